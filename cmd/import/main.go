@@ -0,0 +1,92 @@
+// Command import uploads a CSV or JSON file of subscriptions to the
+// subscription service import endpoint and prints the row-level report.
+//
+//	go run ./cmd/import -file subs.csv -dry-run
+//	go run ./cmd/import -file subs.csv -map "service_name:Сервис,price:Стоимость"
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ItserX/rest/internal/importer"
+	"github.com/ItserX/rest/internal/types"
+)
+
+func main() {
+	serverURL := flag.String("url", "http://localhost:8080", "Base URL of the subscription service")
+	fileName := flag.String("file", "", "CSV or JSON file to import")
+	format := flag.String("format", "", "File format: csv or json (detected from the extension by default)")
+	mapping := flag.String("map", "", "Column mapping as field:column pairs separated by commas")
+	dryRun := flag.Bool("dry-run", false, "Validate the file and report changes without saving them")
+	flag.Parse()
+
+	if *fileName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	detected := importer.DetectFormat(*format, "", *fileName)
+	if detected == "" {
+		log.Fatalf("Cannot detect format of %s, use -format", *fileName)
+	}
+	if _, err := importer.ParseMapping(*mapping); err != nil {
+		log.Fatal("Invalid column mapping: ", err)
+	}
+
+	file, err := os.Open(*fileName)
+	if err != nil {
+		log.Fatal("Failed to open file: ", err)
+	}
+	defer file.Close()
+
+	query := url.Values{}
+	query.Set("format", detected)
+	query.Set("dry_run", strconv.FormatBool(*dryRun))
+	if *mapping != "" {
+		query.Set("map", *mapping)
+	}
+
+	contentType := "text/csv"
+	if detected == importer.FormatJSON {
+		contentType = "application/json"
+	}
+
+	resp, err := http.Post(*serverURL+"/api/subscriptions/import?"+query.Encode(), contentType, file)
+	if err != nil {
+		log.Fatal("Import request failed: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnprocessableEntity {
+		var errResp types.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		log.Fatalf("Import failed with status %d: %s", resp.StatusCode, errResp.Error)
+	}
+
+	var report types.ImportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		log.Fatal("Failed to decode import report: ", err)
+	}
+
+	for _, rowErr := range report.Errors {
+		fmt.Printf("%s: row %d: %s: %s\n", filepath.Base(*fileName), rowErr.Row, rowErr.Field, rowErr.Error)
+	}
+	mode := "imported"
+	if report.DryRun {
+		mode = "dry run"
+	}
+	fmt.Printf("%s: %d rows, %d created, %d updated, %d errors\n",
+		mode, report.Total, report.Created, report.Updated, len(report.Errors))
+
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"net/smtp"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -14,8 +24,13 @@ import (
 
 	_ "github.com/ItserX/rest/docs"
 	"github.com/ItserX/rest/internal/handlers"
+	"github.com/ItserX/rest/internal/importer"
 	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/notify"
+	"github.com/ItserX/rest/internal/outbox"
+	"github.com/ItserX/rest/internal/reminders"
 	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/webhooks"
 )
 
 // @title API сервиса подписок
@@ -54,7 +69,12 @@ func connectDB() *sql.DB {
 	return db
 }
 
-func startServer(db *sql.DB) {
+// shutdownTimeout is how long requests in flight get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+// startServer serves the API and runs the background workers until ctx is
+// done, then waits for both to stop.
+func startServer(ctx context.Context, db *sql.DB) {
 	gin.SetMode(os.Getenv("GIN_MODE"))
 
 	r := gin.New()
@@ -62,8 +82,55 @@ func startServer(db *sql.DB) {
 	r.Use(gin.Recovery())
 	r.Use(loggingMiddleware())
 
+	repo := storage.NewPostgresRepository(db)
+	loadRatesFile(repo, os.Getenv("EXCHANGE_RATES_FILE"))
+
+	notifier := newNotifier(repo)
+
+	scheduler := reminders.Scheduler{
+		Repo:         repo,
+		Window:       intEnv("REMINDER_WINDOW_DAYS", 3),
+		Interval:     positiveDurationEnv("REMINDER_INTERVAL", 24*time.Hour),
+		SendInterval: positiveDurationEnv("REMINDER_SEND_INTERVAL", 5*time.Minute),
+		Notifier:     notifier,
+	}
+
+	relay := webhooks.Relay{
+		Repo:        repo,
+		Client:      &http.Client{Timeout: durationEnv("WEBHOOK_TIMEOUT", 10*time.Second)},
+		Interval:    positiveDurationEnv("WEBHOOK_INTERVAL", 5*time.Second),
+		BatchSize:   positiveIntEnv("WEBHOOK_BATCH_SIZE", 50),
+		MaxAttempts: intEnv("WEBHOOK_MAX_ATTEMPTS", 8),
+		Backoff:     durationEnv("WEBHOOK_BACKOFF", 30*time.Second),
+		MaxBackoff:  durationEnv("WEBHOOK_MAX_BACKOFF", time.Hour),
+	}
+
+	events := outbox.Relay{
+		Repo:           repo,
+		Sinks:          newSinks(repo, notifier),
+		Interval:       positiveDurationEnv("OUTBOX_INTERVAL", time.Second),
+		BatchSize:      positiveIntEnv("OUTBOX_BATCH_SIZE", 100),
+		Lease:          positiveDurationEnv("OUTBOX_LEASE", time.Minute),
+		Backoff:        durationEnv("OUTBOX_BACKOFF", 5*time.Second),
+		MaxBackoff:     durationEnv("OUTBOX_MAX_BACKOFF", 10*time.Minute),
+		ExpiryInterval: positiveDurationEnv("OUTBOX_EXPIRY_INTERVAL", time.Hour),
+		Retention:      durationEnv("OUTBOX_RETENTION", 7*24*time.Hour),
+	}
+
+	var workers sync.WaitGroup
+	for _, run := range []func(context.Context){scheduler.Run, relay.Run, events.Run} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+
 	h := handlers.Handler{
-		Repo: storage.NewPostgresRepository(db),
+		Repo:           repo,
+		IdempotencyTTL: durationEnv("IDEMPOTENCY_TTL", 24*time.Hour),
+		MaxBatchSize:   positiveIntEnv("BATCH_MAX_SIZE", 100),
+		Notifier:       notifier,
 	}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	api := r.Group("/api")
@@ -72,26 +139,260 @@ func startServer(db *sql.DB) {
 		{
 			subscriptions.GET("/:id", h.GetSub)
 			subscriptions.POST("", h.CreateSub)
+			subscriptions.POST("/batch", h.BatchSubs)
+			subscriptions.POST("/import", h.ImportSubs)
 			subscriptions.PUT("/:id", h.UpdateSub)
+			subscriptions.PATCH("/:id", h.PatchSub)
 			subscriptions.DELETE("/:id", h.DeleteSub)
+			subscriptions.POST("/:id/pause", h.PauseSub)
+			subscriptions.POST("/:id/resume", h.ResumeSub)
+			subscriptions.POST("/:id/cancel", h.CancelSub)
+			subscriptions.POST("/:id/reactivate", h.ReactivateSub)
+			subscriptions.GET("/:id/prices", h.GetPriceHistory)
+			subscriptions.POST("/:id/prices", h.SchedulePriceChange)
+			subscriptions.DELETE("/:id/prices/:month", h.DeletePriceChange)
+			subscriptions.POST("/:id/discounts", h.AddDiscount)
+			subscriptions.DELETE("/:id/discounts/:discount_id", h.DeleteDiscount)
+			subscriptions.GET("/:id/members", h.GetSharing)
+			subscriptions.PUT("/:id/members", h.SetSharing)
 			subscriptions.GET("/list", h.ListSubs)
 			subscriptions.GET("/totalCost", h.GetTotalCost)
 		}
+
+		users := api.Group("/users")
+		{
+			users.GET("", h.ListUsers)
+			users.GET("/:id", h.GetUser)
+			users.POST("", h.CreateUser)
+			users.PUT("/:id", h.UpdateUser)
+			users.DELETE("/:id", h.DeleteUser)
+			users.GET("/:id/summary", h.GetUserSummary)
+			users.GET("/:id/reminders", h.ListReminders)
+			users.GET("/:id/reminder-preferences", h.GetReminderPreferences)
+			users.PUT("/:id/reminder-preferences", h.SetReminderPreferences)
+			users.POST("/:id/calendar-token", h.IssueCalendarToken)
+			users.DELETE("/:id/calendar-token", h.RevokeCalendarToken)
+		}
+
+		api.GET("/calendar/:token", h.GetCalendar)
+
+		services := api.Group("/services")
+		{
+			services.GET("", h.ListServices)
+			services.GET("/:id", h.GetService)
+			services.POST("", h.CreateService)
+			services.PUT("/:id", h.UpdateService)
+			services.DELETE("/:id", h.DeleteService)
+		}
+
+		budgets := api.Group("/budgets")
+		{
+			budgets.GET("", h.ListBudgets)
+			budgets.POST("", h.CreateBudget)
+			budgets.POST("/evaluate", h.EvaluateBudgets)
+			budgets.GET("/:id", h.GetBudget)
+			budgets.PUT("/:id", h.UpdateBudget)
+			budgets.DELETE("/:id", h.DeleteBudget)
+			budgets.GET("/:id/status", h.GetBudgetStatus)
+			budgets.GET("/:id/alerts", h.ListBudgetAlerts)
+		}
+
+		api.GET("/notifications/deliveries", h.ListDeliveries)
+
+		hooks := api.Group("/webhooks")
+		{
+			hooks.GET("", h.ListWebhooks)
+			hooks.POST("", h.CreateWebhook)
+			hooks.GET("/dead-letters", h.ListDeadLetters)
+			hooks.POST("/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
+			hooks.GET("/:id", h.GetWebhook)
+			hooks.PUT("/:id", h.UpdateWebhook)
+			hooks.DELETE("/:id", h.DeleteWebhook)
+			hooks.GET("/:id/deliveries", h.ListWebhookDeliveries)
+		}
+
+		rates := api.Group("/exchange-rates")
+		{
+			rates.GET("", h.ListRates)
+			rates.POST("", h.LoadRates)
+		}
 	}
 
 	port := ":" + os.Getenv("SERVER_PORT")
-	logger.Logger.Infow("Starting server", "port", port)
-	err := r.Run(port)
+	server := &http.Server{Addr: port, Handler: r}
+	go func() {
+		logger.Logger.Infow("Starting server", "port", port)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Logger.Fatalw("Server failed to start", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Logger.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Logger.Errorw("Server shutdown failed", "error", err)
+	}
+	workers.Wait()
+	logger.Logger.Info("Server stopped")
+}
+
+// loadRatesFile seeds the exchange rate table from a CSV or JSON file at
+// startup. Rates already stored for the same dates are replaced.
+func loadRatesFile(repo storage.PostRepository, path string) {
+	if path == "" {
+		return
+	}
+
+	rates, err := importer.ParseRatesFile(path)
+	if err != nil {
+		logger.Logger.Fatalw("Failed to read exchange rates file", "path", path, "error", err)
+	}
+	loaded, err := repo.UpsertRates(rates)
+	if err != nil {
+		logger.Logger.Fatalw("Failed to load exchange rates", "path", path, "error", err)
+	}
+	logger.Logger.Infow("Exchange rates loaded from file", "path", path, "count", loaded)
+}
+
+// newNotifier sets up email delivery if SMTP_HOST is set and webhook
+// delivery if NOTIFY_WEBHOOK_URL is. With neither, notifications are not sent.
+func newNotifier(repo storage.PostRepository) *notify.Dispatcher {
+	d := &notify.Dispatcher{
+		Store:    repo,
+		Attempts: intEnv("NOTIFY_ATTEMPTS", 5),
+		Backoff:  durationEnv("NOTIFY_BACKOFF", 2*time.Second),
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		email := &notify.EmailNotifier{
+			Addr: net.JoinHostPort(host, strconv.Itoa(intEnv("SMTP_PORT", 587))),
+			From: os.Getenv("SMTP_FROM"),
+		}
+		if username := os.Getenv("SMTP_USERNAME"); username != "" {
+			email.Auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		d.Notifiers = append(d.Notifiers, email)
+	}
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		d.Notifiers = append(d.Notifiers, &notify.WebhookNotifier{
+			URL:    url,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+
+	logger.Logger.Infow("Notification channels configured",
+		"channels", len(d.Notifiers),
+	)
+	return d
+}
+
+// newSinks returns where subscription events are published: webhooks and
+// owner notifications always, the log if OUTBOX_LOG is true, a file if
+// OUTBOX_FILE is set and NATS if OUTBOX_NATS_ADDR is.
+func newSinks(repo storage.PostRepository, notifier *notify.Dispatcher) []outbox.Sink {
+	sinks := []outbox.Sink{
+		&outbox.WebhookSink{Queue: repo},
+		&outbox.NotifySink{Dispatcher: notifier},
+	}
+
+	if boolEnv("OUTBOX_LOG", false) {
+		sinks = append(sinks, outbox.LogSink{})
+	}
+	if path := os.Getenv("OUTBOX_FILE"); path != "" {
+		file, err := outbox.NewFileSink(path)
+		if err != nil {
+			logger.Logger.Fatalw("Failed to open outbox file", "path", path, "error", err)
+		}
+		sinks = append(sinks, file)
+	}
+	if addr := os.Getenv("OUTBOX_NATS_ADDR"); addr != "" {
+		subject := os.Getenv("OUTBOX_NATS_SUBJECT")
+		if subject == "" {
+			subject = "subscriptions"
+		}
+		sinks = append(sinks, &outbox.BrokerSink{
+			Publisher: &outbox.NATSPublisher{Addr: addr},
+			Topic:     subject,
+		})
+	}
+
+	names := make([]string, len(sinks))
+	for i, sink := range sinks {
+		names[i] = sink.Name()
+	}
+	logger.Logger.Infow("Event sinks configured",
+		"sinks", names,
+	)
+	return sinks
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
 	if err != nil {
-		logger.Logger.Fatalw("Server failed to start", "error", err)
+		logger.Logger.Fatalw("Invalid duration in environment", "key", key, "value", value, "error", err)
+	}
+	return d
+}
+
+func intEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Logger.Fatalw("Invalid integer in environment", "key", key, "value", value, "error", err)
+	}
+	return n
+}
+
+// positiveDurationEnv is durationEnv for settings that must be above zero,
+// such as ticker intervals.
+func positiveDurationEnv(key string, fallback time.Duration) time.Duration {
+	d := durationEnv(key, fallback)
+	if d <= 0 {
+		logger.Logger.Fatalw("Duration in environment must be positive", "key", key, "value", d)
 	}
+	return d
+}
+
+// positiveIntEnv is intEnv for settings that must be above zero, such as
+// batch sizes.
+func positiveIntEnv(key string, fallback int) int {
+	n := intEnv(key, fallback)
+	if n <= 0 {
+		logger.Logger.Fatalw("Integer in environment must be positive", "key", key, "value", n)
+	}
+	return n
+}
+
+func boolEnv(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Logger.Fatalw("Invalid boolean in environment", "key", key, "value", value, "error", err)
+	}
+	return b
 }
 
 func loggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger.Logger.Infow("Incoming request",
 			"method", c.Request.Method,
-			"path", c.Request.URL.Path,
+			"path", handlers.RequestPath(c),
 			"ip", c.ClientIP(),
 		)
 		c.Next()
@@ -110,8 +411,11 @@ func main() {
 	}
 	defer logger.Logger.Sync()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	db := connectDB()
 	defer db.Close()
 
-	startServer(db)
+	startServer(ctx, db)
 }
@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/subscriptions": {
+        "/budgets": {
             "get": {
-                "description": "Получить список всех подписок",
+                "description": "Получить бюджеты, упорядоченные по названию",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,26 +25,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Подписки"
+                    "Бюджеты"
+                ],
+                "summary": "Список бюджетов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "Только бюджеты, в которых учитываются расходы пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Получить список подписок",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.ListSubscriptionsResponse"
+                            "$ref": "#/definitions/types.ListBudgetsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidUserIDErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/types.FailedToListSubsErrorResponse"
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Создать новую подписку с указанными параметрами",
+                "description": "Задать месячный или годовой лимит расходов пользователя или команды: общий, по категории или по сервису. Совместные подписки учитываются в размере доли каждого пользователя",
                 "consumes": [
                     "application/json"
                 ],
@@ -52,17 +67,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Подписки"
+                    "Бюджеты"
                 ],
-                "summary": "Создать новую подписку",
+                "summary": "Добавить бюджет",
                 "parameters": [
                     {
-                        "description": "Данные подписки",
-                        "name": "subscription",
+                        "description": "Параметры бюджета",
+                        "name": "budget",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.Subscription"
+                            "$ref": "#/definitions/types.Budget"
                         }
                     }
                 ],
@@ -70,27 +85,27 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/types.CreatedResponse"
+                            "$ref": "#/definitions/types.IDResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                            "$ref": "#/definitions/types.UnknownUserErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/types.FailedToCreateErrorResponse"
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/subscriptions/totalCost": {
-            "get": {
-                "description": "Рассчитать общую стоимость подписок с возможностью фильтрации",
+        "/budgets/evaluate": {
+            "post": {
+                "description": "Рассчитать исполнение всех бюджетов и вернуть оповещения, созданные этой проверкой: при достижении 80% и 100% лимита, каждое один раз за период. Планировщик напоминаний выполняет проверку сам. Бюджеты без нужного курса валюты пропускаются",
                 "consumes": [
                     "application/json"
                 ],
@@ -98,65 +113,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Подписки"
-                ],
-                "summary": "Рассчитать общую стоимость",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
-                        "description": "ID пользователя для фильтрации",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "example": "\"Yandex\"",
-                        "description": "Название сервиса для фильтрации",
-                        "name": "service_name",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "example": "\"07-2025\"",
-                        "description": "Начальный период в формате ММ-ГГГГ",
-                        "name": "period_start",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "example": "\"12-2025\"",
-                        "description": "Конечный период в формате ММ-ГГГГ",
-                        "name": "period_end",
-                        "in": "query"
-                    }
+                    "Бюджеты"
                 ],
+                "summary": "Проверить все бюджеты",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.TotalCostResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/types.InvalidUserIDErrorResponse"
+                            "$ref": "#/definitions/types.BudgetAlertsResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/types.FailedToCalculateErrorResponse"
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/subscriptions/{id}": {
+        "/budgets/{id}": {
             "get": {
-                "description": "Получить информацию о подписке по её идентификатору",
+                "description": "Получить параметры бюджета",
                 "consumes": [
                     "application/json"
                 ],
@@ -164,14 +142,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Подписки"
+                    "Бюджеты"
                 ],
-                "summary": "Получить подписку по ID",
+                "summary": "Получить бюджет по ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
-                        "description": "ID подписки",
+                        "example": "\"7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20\"",
+                        "description": "ID бюджета",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -181,7 +159,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.Subscription"
+                            "$ref": "#/definitions/types.Budget"
                         }
                     },
                     "400": {
@@ -193,19 +171,19 @@ const docTemplate = `{
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                            "$ref": "#/definitions/types.BudgetNotFoundErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/types.FailedToGetSubErrorResponse"
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
                         }
                     }
                 }
             },
             "put": {
-                "description": "Обновить существующую подписку по ID",
+                "description": "Заменить параметры бюджета. Оповещения текущего периода сохраняются",
                 "consumes": [
                     "application/json"
                 ],
@@ -213,25 +191,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Подписки"
+                    "Бюджеты"
                 ],
-                "summary": "Обновить подписку",
+                "summary": "Обновить бюджет",
                 "parameters": [
                     {
                         "type": "string",
-                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
-                        "description": "ID подписки",
+                        "example": "\"7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20\"",
+                        "description": "ID бюджета",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Обновленные данные подписки",
-                        "name": "subscription",
+                        "description": "Параметры бюджета",
+                        "name": "budget",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.Subscription"
+                            "$ref": "#/definitions/types.Budget"
                         }
                     }
                 ],
@@ -245,25 +223,25 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                            "$ref": "#/definitions/types.UnknownUserErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                            "$ref": "#/definitions/types.BudgetNotFoundErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/types.FailedToUpdateSub"
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Удалить подписку по ID",
+                "description": "Удалить бюджет вместе с его оповещениями",
                 "consumes": [
                     "application/json"
                 ],
@@ -271,14 +249,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Подписки"
+                    "Бюджеты"
                 ],
-                "summary": "Удалить подписку",
+                "summary": "Удалить бюджет",
                 "parameters": [
                     {
                         "type": "string",
-                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
-                        "description": "ID подписки",
+                        "example": "\"7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20\"",
+                        "description": "ID бюджета",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -300,191 +278,4823 @@ const docTemplate = `{
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                            "$ref": "#/definitions/types.BudgetNotFoundErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/types.FailedToDeleteErrorResponse"
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "types.CreatedResponse": {
-            "type": "object",
-            "properties": {
-                "sub_id": {
-                    "type": "string",
-                    "example": "d79c4c83-b0e4-4cc7-a6b1-3f2c5b8c9b76"
-                }
-            }
-        },
-        "types.FailedToCalculateErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Failed to calculate total cost"
-                }
-            }
-        },
-        "types.FailedToCreateErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Failed to create subscription"
-                }
-            }
         },
-        "types.FailedToDeleteErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Failed to delete subscription"
+        "/budgets/{id}/alerts": {
+            "get": {
+                "description": "Получить все оповещения о достижении порогов бюджета, новые первыми",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Бюджеты"
+                ],
+                "summary": "Оповещения бюджета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20\"",
+                        "description": "ID бюджета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.BudgetAlertsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.BudgetNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "types.FailedToGetSubErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Failed to get subscription"
+        "/budgets/{id}/status": {
+            "get": {
+                "description": "Рассчитать расходы с начала текущего периода бюджета и их долю от лимита. Запрос только читает данные: оповещения о достижении 80% и 100% лимита создает проверка бюджетов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Бюджеты"
+                ],
+                "summary": "Исполнение бюджета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20\"",
+                        "description": "ID бюджета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.BudgetStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.BudgetNotFoundErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/types.MissingExchangeRateErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "types.FailedToListSubsErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Failed to list subscriptions"
+        "/calendar/{token}": {
+            "get": {
+                "description": "Календарь с ежемесячными продлениями и датами окончания подписок пользователя. Адрес ленты выдается при выпуске токена",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "Календарь"
+                ],
+                "summary": "Лента iCalendar с продлениями подписок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен календаря, допускается суффикс .ics",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Лента в формате iCalendar",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.CalendarNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "types.FailedToUpdateSub": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
+        "/exchange-rates": {
+            "get": {
+                "description": "Получить загруженные курсы валют к рублю, упорядоченные по валюте и дате начала действия",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Курсы валют"
+                ],
+                "summary": "Список курсов валют",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"USD\"",
+                        "description": "Код валюты для фильтрации",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ExchangeRatesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidCurrencyErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Загрузить курсы валют к рублю из CSV (колонки currency, date, rate) или JSON-массива. Курс действует с указанной даты до следующего курса той же валюты; существующие курсы на ту же дату заменяются",
+                "consumes": [
+                    "text/csv",
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Курсы валют"
+                ],
+                "summary": "Загрузить курсы валют",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"csv\"",
+                        "description": "Формат файла: csv или json (по умолчанию определяется по Content-Type или имени файла)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Курсы валют",
+                        "name": "rates",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.ExchangeRate"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.LoadRatesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRatesFileErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/deliveries": {
+            "get": {
+                "description": "Получить последние попытки доставки напоминаний и оповещений по email и вебхуку, сначала новые",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Журнал доставки уведомлений",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "Только уведомления пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"failed\"",
+                        "description": "Результат доставки: sent, failed или skipped",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidDeliveryStatusErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/services": {
+            "get": {
+                "description": "Получить каталог сервисов с псевдонимами, упорядоченный по названию",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Каталог сервисов"
+                ],
+                "summary": "Список сервисов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"Музыка и кино\"",
+                        "description": "Категория для фильтрации",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListServicesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Добавить сервис в каталог. Подписки, название которых совпадает с названием или псевдонимом сервиса без учета регистра, связываются с ним и получают каноническое название",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Каталог сервисов"
+                ],
+                "summary": "Добавить сервис",
+                "parameters": [
+                    {
+                        "description": "Данные сервиса",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Service"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.ServiceConflictErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/services/{id}": {
+            "get": {
+                "description": "Получить запись каталога сервисов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Каталог сервисов"
+                ],
+                "summary": "Получить сервис по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d\"",
+                        "description": "ID сервиса",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Service"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ServiceNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Заменить запись каталога. Связанные подписки получают новое название, подписки с совпадающими псевдонимами связываются с сервисом",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Каталог сервисов"
+                ],
+                "summary": "Обновить сервис",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d\"",
+                        "description": "ID сервиса",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные сервиса",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Service"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ServiceNotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.ServiceConflictErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удалить сервис из каталога. Подписки сохраняют название и перестают быть связанными с каталогом",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Каталог сервисов"
+                ],
+                "summary": "Удалить сервис",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d\"",
+                        "description": "ID сервиса",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ServiceNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions": {
+            "get": {
+                "description": "Получить список всех подписок с возможностью фильтрации. Формат ответа выбирается параметром format или заголовком Accept: JSON, CSV, XLSX или NDJSON",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Получить список подписок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя для фильтрации",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Yandex\"",
+                        "description": "Название сервиса для фильтрации",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"music\"",
+                        "description": "Категория для фильтрации",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Теги для фильтрации, подписка должна иметь все указанные",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "xlsx",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Формат ответа. CSV и NDJSON передаются потоком по мере чтения из базы",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "example": true,
+                        "description": "Передавать JSON потоком, не накапливая весь список в памяти",
+                        "name": "stream",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListSubscriptionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.UnsupportedFormatErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToListSubsErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создать новую подписку с указанными параметрами. Владелец подписки должен быть зарегистрирован",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Создать новую подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"5b1f8a4e-create-netflix\"",
+                        "description": "Ключ идемпотентности для безопасных повторов запроса",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Данные подписки",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Subscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.CreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.UnknownUserErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.IdempotencyConflictErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToCreateErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/batch": {
+            "post": {
+                "description": "Создать, обновить и удалить несколько подписок за один запрос. В атомарном режиме все операции выполняются в одной транзакции, иначе каждая операция применяется независимо и получает собственный результат",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Пакетная обработка подписок",
+                "parameters": [
+                    {
+                        "description": "Пакет операций",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Multi-Status",
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchTooLargeErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/import": {
+            "post": {
+                "description": "Загрузить подписки из CSV (с заголовком) или JSON-массива. Каждая строка проверяется, в том числе на наличие пользователя, ошибки возвращаются построчно. Подписки сопоставляются по ключу (user_id, service_name, start_date): существующие обновляются, новые создаются. При наличии ошибок ничего не записывается",
+                "consumes": [
+                    "text/csv",
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Импортировать подписки из файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"csv\"",
+                        "description": "Формат файла: csv или json (по умолчанию определяется по Content-Type или имени файла)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "example": true,
+                        "description": "Только проверить файл и посчитать изменения, не сохраняя их",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"service_name:Сервис,price:Стоимость\"",
+                        "description": "Соответствие полей колонкам файла в виде поле:колонка через запятую",
+                        "name": "map",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Файл для импорта (multipart/form-data)",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidColumnMappingErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.CurrencyChangeErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/types.ImportResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/totalCost": {
+            "get": {
+                "description": "Рассчитать общую стоимость подписок с возможностью фильтрации: учитываются месяцы периода, в которых подписка действует и не приостановлена, с учетом расчетного периода подписки. При фильтре по пользователю совместные подписки учитываются в размере его доли. Формат ответа выбирается параметром format или заголовком Accept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Рассчитать общую стоимость",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя для фильтрации",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Yandex\"",
+                        "description": "Название сервиса для фильтрации",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"music\"",
+                        "description": "Категория для фильтрации",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Теги для фильтрации, подписка должна иметь все указанные",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"07-2025\"",
+                        "description": "Начало периода в формате ГГГГ-ММ-ДД или ММ-ГГГГ",
+                        "name": "period_start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"12-2025\"",
+                        "description": "Конец периода включительно в формате ГГГГ-ММ-ДД или ММ-ГГГГ. По умолчанию текущий месяц или period_start, если он позже",
+                        "name": "period_end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "example": true,
+                        "description": "Учитывать неполные месяцы пропорционально числу дней вместо целых месяцев",
+                        "name": "prorate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"USD\"",
+                        "description": "Валюта расчета (по умолчанию RUB). Цены пересчитываются по курсу, действующему на начало каждого месяца",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "accrual",
+                            "cash"
+                        ],
+                        "type": "string",
+                        "default": "accrual",
+                        "description": "Представление: accrual распределяет цену периода оплаты по месяцам, cash относит списание на месяц даты оплаты",
+                        "name": "view",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "category"
+                        ],
+                        "type": "string",
+                        "description": "Добавить к сумме разбивку: category — стоимость и доля каждой категории",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "xlsx",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Формат ответа: json возвращает сумму, csv, xlsx и ndjson — расшифровку по подпискам",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.TotalCostResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.UnsupportedFormatErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/types.MissingExchangeRateErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToCalculateErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}": {
+            "get": {
+                "description": "Получить информацию о подписке по её идентификатору",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Получить подписку по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"\\\"3\\\"\"",
+                        "description": "ETag известной клиенту версии",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Subscription"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия подписки"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Подписка не изменилась"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToGetSubErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Обновить существующую подписку по ID. Новая цена начавшейся подписки действует с текущего месяца, прошлые месяцы сохраняют свои цены. Валюту можно сменить только до первого оплаченного месяца",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Обновить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"\\\"3\\\"\"",
+                        "description": "ETag версии, которую изменяет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Обновленные данные подписки",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Subscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия подписки"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.UnknownServiceErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.CurrencyChangeErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/types.PreconditionFailedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToUpdateSub"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удалить подписку по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Удалить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"\\\"3\\\"\"",
+                        "description": "ETag версии, которую удаляет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/types.PreconditionFailedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToDeleteErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Изменить отдельные поля подписки с помощью JSON Merge Patch (RFC 7396) или JSON Patch (RFC 6902). В merge patch значение null очищает поле, например end_date",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Подписки"
+                ],
+                "summary": "Частично обновить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"\\\"3\\\"\"",
+                        "description": "ETag версии, которую изменяет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Изменяемые поля подписки",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.SubscriptionMergePatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия подписки"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.UnknownServiceErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.CurrencyChangeErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/types.PreconditionFailedErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/types.UnsupportedPatchTypeErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToUpdateSub"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/cancel": {
+            "post": {
+                "description": "Отменить подписку: указанная дата (или последний день указанного месяца) становится последним днем подписки и записывается в end_date. Отмена не продлевает подписку: дата после end_date отклоняется, а без даты сохраняется более ранний end_date",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Статус подписки"
+                ],
+                "summary": "Отменить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Последний день или месяц подписки",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/types.LifecycleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.StatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidDateErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidTransitionErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToUpdateSub"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/discounts": {
+            "post": {
+                "description": "Добавить подписке скидку в процентах или фиксированной суммой на N месяцев, до даты или бессрочно. Скидки, действующие в один день, применяются по очереди",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Скидки"
+                ],
+                "summary": "Добавить скидку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры скидки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.DiscountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.Discount"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.CurrencyMismatchErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/discounts/{discount_id}": {
+            "delete": {
+                "description": "Удалить скидку подписки",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Скидки"
+                ],
+                "summary": "Удалить скидку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"0b8f3a52-4c1e-4a8e-9d6f-2f7b5c1e9a30\"",
+                        "description": "ID скидки",
+                        "name": "discount_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.DiscountNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/members": {
+            "get": {
+                "description": "Получить участников совместной подписки и правило разделения ее стоимости",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Совместные подписки"
+                ],
+                "summary": "Участники подписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Sharing"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Заменить участников совместной подписки и правило разделения стоимости. Владелец платит остаток после долей участников; фиксированные доли указываются за расчетный период в валюте подписки и учитываются по порядку. Пустой список участников отменяет совместное использование",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Совместные подписки"
+                ],
+                "summary": "Задать участников подписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Участники и правило разделения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Sharing"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Sharing"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.CurrencyMismatchErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/pause": {
+            "post": {
+                "description": "Приостановить подписку начиная с указанного месяца. Приостановленные месяцы не учитываются в общей стоимости. Месяц не может быть позже текущего",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Статус подписки"
+                ],
+                "summary": "Приостановить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Первый приостановленный месяц",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/types.LifecycleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.StatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidDateErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidTransitionErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToUpdateSub"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/prices": {
+            "get": {
+                "description": "Получить цены подписки с первого месяца, включая запланированные изменения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Цены"
+                ],
+                "summary": "История цен подписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.PriceHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Задать новую цену подписки начиная с указанного месяца, не меняя стоимость прошлых месяцев. Изменение на тот же месяц заменяется. Начальную цену меняет обновление подписки",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Цены"
+                ],
+                "summary": "Запланировать изменение цены",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новая цена и месяц начала ее действия",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.PriceChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.PriceHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.CurrencyMismatchErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/prices/{month}": {
+            "delete": {
+                "description": "Отменить изменение цены, начинающееся в указанном месяце",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Цены"
+                ],
+                "summary": "Удалить изменение цены",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"01-2026\"",
+                        "description": "Первый месяц действия цены в формате ММ-ГГГГ",
+                        "name": "month",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.PriceHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidDateErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.PriceChangeNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/reactivate": {
+            "post": {
+                "description": "Снова сделать отмененную или истекшую подписку активной, дата окончания снимается",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Статус подписки"
+                ],
+                "summary": "Возобновить отмененную подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.StatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidTransitionErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToUpdateSub"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/resume": {
+            "post": {
+                "description": "Возобновить приостановленную подписку с указанного месяца, не позже текущего",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Статус подписки"
+                ],
+                "summary": "Возобновить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0\"",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Первый оплачиваемый месяц после паузы",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/types.LifecycleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.StatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidDateErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.NotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidTransitionErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedToUpdateSub"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "description": "Получить список пользователей, упорядоченный по имени. Параметры email и external_id позволяют найти пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Список пользователей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"ivan@example.com\"",
+                        "description": "Адрес электронной почты, без учета регистра",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"crm-10452\"",
+                        "description": "Идентификатор во внешней системе",
+                        "name": "external_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListUsersResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Зарегистрировать пользователя. Подписки можно создавать только для зарегистрированных пользователей; чтобы зарегистрировать владельца существующих подписок, передайте его ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Добавить пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserConflictErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "description": "Получить данные пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Получить пользователя по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Заменить имя, адрес электронной почты и внешний идентификатор пользователя. Поле id в теле игнорируется",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Обновить пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные пользователя",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserConflictErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удалить пользователя. Участие в чужих подписках и токен календаря удаляются всегда. Собственные подписки по умолчанию мешают удалению (restrict); их можно удалить вместе с пользователем (delete) или передать другому пользователю (transfer)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Удалить пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "restrict",
+                            "delete",
+                            "transfer"
+                        ],
+                        "type": "string",
+                        "default": "restrict",
+                        "description": "Что сделать с подписками пользователя: restrict, delete или transfer",
+                        "name": "subscriptions",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2c1e4a6b-8d0f-4a2c-9e1b-3d5f7a9c1e2b\"",
+                        "description": "ID пользователя, которому передаются подписки, для transfer",
+                        "name": "transfer_to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.DeleteUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidUserDeleteErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserHasSubscriptionsErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/calendar-token": {
+            "post": {
+                "description": "Выпустить новый секретный токен ленты iCalendar для пользователя. Предыдущий токен перестает действовать",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Календарь"
+                ],
+                "summary": "Выпустить токен календаря",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.CalendarTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Отключить ленту iCalendar пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Календарь"
+                ],
+                "summary": "Отозвать токен календаря",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.CalendarNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/reminder-preferences": {
+            "get": {
+                "description": "Получить настройки напоминаний пользователя. Если они не менялись, включены оба типа напоминаний с окном по умолчанию",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Напоминания"
+                ],
+                "summary": "Настройки напоминаний",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ReminderPreferences"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Включить или отключить напоминания о продлении и окончании подписок и задать, за сколько дней до даты напоминать",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Напоминания"
+                ],
+                "summary": "Изменить настройки напоминаний",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Настройки напоминаний",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.ReminderPreferences"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ReminderPreferences"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/reminders": {
+            "get": {
+                "description": "Получить последние напоминания о продлении и окончании подписок пользователя, сначала новые",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Напоминания"
+                ],
+                "summary": "Напоминания пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListRemindersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/summary": {
+            "get": {
+                "description": "Получить для экрана личных финансов число действующих подписок, расходы за текущий месяц и с начала года, изменение к предыдущему месяцу, ближайшие списания и самые дорогие сервисы. Совместные подписки учитываются в размере доли пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Сводка расходов пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"60601fee-2bf1-4721-ae6f-7636e79a0cba\"",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"USD\"",
+                        "description": "Валюта расчета (по умолчанию RUB)",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidCurrencyErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserNotFoundErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/types.MissingExchangeRateErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Получить зарегистрированные адреса для событий об изменении подписок. Секреты не возвращаются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Список вебхуков",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListWebhooksResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Зарегистрировать адрес, на который POST-запросом отправляются события о создании, изменении, удалении и истечении подписок. Каждый запрос подписан: заголовок X-Webhook-Signature содержит sha256= и HMAC-SHA256 от строки \"\u003cX-Webhook-Timestamp\u003e.\u003cтело запроса\u003e\" с секретом вебхука. Секрет возвращается только в ответе на регистрацию",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Зарегистрировать вебхук",
+                "parameters": [
+                    {
+                        "description": "Адрес и события вебхука",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookEndpoint"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookEndpoint"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/dead-letters": {
+            "get": {
+                "description": "Получить доставки всех вебхуков, от которых отказались после всех попыток, сначала новые",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Недоставленные события",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListWebhookDeliveriesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{delivery_id}/redeliver": {
+            "post": {
+                "description": "Снова поставить событие в очередь на доставку с полным набором попыток",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Повторить доставку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"7a9c1e3f-5b7d-4f9a-8c1e-3f5b7d9f1a3c\"",
+                        "description": "ID доставки",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookDeliveryNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "description": "Получить адрес, события и состояние вебхука",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Получить вебхук по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f\"",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookEndpoint"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Заменить адрес, события и состояние вебхука. Секрет не меняется",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Обновить вебхук",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f\"",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Адрес и события вебхука",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookEndpoint"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidRequestBodyErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удалить вебхук вместе с историей его доставок",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Удалить вебхук",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f\"",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.IDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidIDErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Получить последние доставки событий на вебхук, сначала новые",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Доставки вебхука",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f\"",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"dead\"",
+                        "description": "Состояние доставки: pending, delivered или dead",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListWebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.InvalidDeliveryStatusErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookNotFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.InternalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "money.Money": {
+            "description": "Денежная сумма в минимальных единицах валюты (копейках, центах)",
+            "type": "object",
+            "required": [
+                "currency"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Сумма в минимальных единицах валюты",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 39900
+                },
+                "currency": {
+                    "description": "Код валюты ISO 4217",
+                    "type": "string",
+                    "example": "RUB"
+                }
+            }
+        },
+        "types.BatchOperation": {
+            "description": "Операция пакетной обработки подписок",
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID подписки, обязателен для update и delete",
+                    "type": "string",
+                    "example": "8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"
+                },
+                "if_match": {
+                    "description": "Опциональный ETag версии для update и delete",
+                    "type": "string",
+                    "example": "\"3\""
+                },
+                "op": {
+                    "description": "Тип операции: create, update или delete",
+                    "type": "string",
+                    "example": "create"
+                },
+                "subscription": {
+                    "description": "Данные подписки, обязательны для create и update",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.Subscription"
+                        }
+                    ]
+                }
+            }
+        },
+        "types.BatchRequest": {
+            "description": "Пакет операций над подписками",
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "atomic": {
+                    "description": "Выполнить все операции в одной транзакции: при первой ошибке изменения откатываются",
+                    "type": "boolean",
+                    "example": true
+                },
+                "operations": {
+                    "description": "Операции в порядке выполнения",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/types.BatchOperation"
+                    }
+                }
+            }
+        },
+        "types.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.BatchResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "types.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Subscription not found"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "d79c4c83-b0e4-4cc7-a6b1-3f2c5b8c9b76"
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "op": {
+                    "type": "string",
+                    "example": "create"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 201
+                }
+            }
+        },
+        "types.BatchTooLargeErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Batch exceeds maximum size of 100 operations"
+                }
+            }
+        },
+        "types.Budget": {
+            "description": "Бюджет на подписки пользователя или команды: общий, по категории или по сервису",
+            "type": "object",
+            "required": [
+                "name",
+                "period",
+                "user_ids"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Лимит расходов за период",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "category": {
+                    "description": "Категория подписок, пусто для всех категорий",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "music"
+                },
+                "id": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20"
+                },
+                "name": {
+                    "description": "Название бюджета",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Развлечения семьи"
+                },
+                "period": {
+                    "description": "Период бюджета: month или year",
+                    "type": "string",
+                    "enum": [
+                        "month",
+                        "year"
+                    ],
+                    "example": "month"
+                },
+                "service_name": {
+                    "description": "Название сервиса, пусто для всех сервисов. Указывается не вместе с category",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Yandex Plus"
+                },
+                "user_ids": {
+                    "description": "Пользователи, расходы которых учитываются; несколько пользователей образуют командный бюджет",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "types.BudgetAlert": {
+            "description": "Оповещение о достижении порога бюджета, создается один раз за период для каждого порога",
+            "type": "object",
+            "properties": {
+                "budget_id": {
+                    "type": "string",
+                    "example": "7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20"
+                },
+                "created_at": {
+                    "description": "Время создания оповещения в формате RFC 3339",
+                    "type": "string",
+                    "example": "2026-03-14T09:30:00Z"
+                },
+                "period_start": {
+                    "description": "Первый месяц периода в формате ММ-ГГГГ",
+                    "type": "string",
+                    "example": "03-2026"
+                },
+                "spent": {
+                    "description": "Расходы на момент достижения порога",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "threshold": {
+                    "description": "Достигнутый порог в процентах: 80 или 100",
+                    "type": "integer",
+                    "example": 80
+                }
+            }
+        },
+        "types.BudgetAlertsResponse": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.BudgetAlert"
+                    }
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "types.BudgetNotFoundErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Budget not found"
+                }
+            }
+        },
+        "types.BudgetStatus": {
+            "description": "Исполнение бюджета в текущем периоде",
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "description": "Оповещения текущего периода",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.BudgetAlert"
+                    }
+                },
+                "budget": {
+                    "$ref": "#/definitions/types.Budget"
+                },
+                "percent": {
+                    "description": "Израсходованная доля лимита в процентах",
+                    "type": "number",
+                    "example": 84.5
+                },
+                "period_end": {
+                    "description": "Последний месяц текущего периода в формате ММ-ГГГГ",
+                    "type": "string",
+                    "example": "03-2026"
+                },
+                "period_start": {
+                    "description": "Первый месяц текущего периода в формате ММ-ГГГГ",
+                    "type": "string",
+                    "example": "03-2026"
+                },
+                "remaining": {
+                    "description": "Остаток лимита, отрицательный при перерасходе",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "spent": {
+                    "description": "Расходы с начала периода по текущий месяц включительно",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "state": {
+                    "description": "Состояние: ok, warning (от 80%) или exceeded (от 100%)",
+                    "type": "string",
+                    "example": "warning"
+                }
+            }
+        },
+        "types.CalendarNotFoundErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Calendar not found"
+                }
+            }
+        },
+        "types.CalendarTokenResponse": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Секретный токен ленты, показывается только при выпуске",
+                    "type": "string",
+                    "example": "3f1c0d9e5b7a4c2e8f6a1b3d5c7e9f0a2b4c6d8e0f1a3b5c7d9e1f3a5b7c9d1e"
+                },
+                "url": {
+                    "description": "Адрес ленты для подписки в календаре",
+                    "type": "string",
+                    "example": "http://localhost:8080/api/calendar/3f1c0d9e5b7a4c2e8f6a1b3d5c7e9f0a2b4c6d8e0f1a3b5c7d9e1f3a5b7c9d1e.ics"
+                }
+            }
+        },
+        "types.CategoryCost": {
+            "description": "Стоимость подписок одной категории и ее доля в общей стоимости",
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Категория, пусто для подписок без категории",
+                    "type": "string",
+                    "example": "dev tools"
+                },
+                "cost": {
+                    "description": "Стоимость в валюте расчета",
+                    "type": "number",
+                    "example": 1263.5
+                },
+                "share": {
+                    "description": "Доля в общей стоимости от 0 до 1",
+                    "type": "number",
+                    "example": 0.4215
+                }
+            }
+        },
+        "types.CreatedResponse": {
+            "type": "object",
+            "properties": {
+                "sub_id": {
+                    "type": "string",
+                    "example": "d79c4c83-b0e4-4cc7-a6b1-3f2c5b8c9b76"
+                }
+            }
+        },
+        "types.CurrencyChangeErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Currency of a subscription with billing history cannot change"
+                }
+            }
+        },
+        "types.CurrencyMismatchErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Price currency must match subscription currency"
+                }
+            }
+        },
+        "types.DeleteUserResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+                },
+                "subscriptions": {
+                    "description": "Количество удаленных или переданных подписок пользователя",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "types.Delivery": {
+            "description": "Запись журнала доставки уведомления",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Число попыток отправки",
+                    "type": "integer",
+                    "example": 1
+                },
+                "channel": {
+                    "description": "Канал доставки: email или webhook",
+                    "type": "string",
+                    "example": "email"
+                },
+                "created_at": {
+                    "description": "Время записи в формате RFC 3339",
+                    "type": "string",
+                    "example": "2026-03-12T09:00:00Z"
+                },
+                "error": {
+                    "description": "Ошибка последней попытки",
+                    "type": "string",
+                    "example": ""
+                },
+                "event": {
+                    "description": "Событие: reminder.renewal, reminder.expiry, budget.alert, subscription.created, subscription.updated, subscription.deleted",
+                    "type": "string",
+                    "example": "reminder.renewal"
+                },
+                "event_id": {
+                    "description": "ID события подписки, о котором уведомление, для событий subscription.*",
+                    "type": "string",
+                    "example": "5d7f9b1c-3e5a-4c7e-9f1b-3d5f7a9c1e3a"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "recipient": {
+                    "description": "Адрес получателя в канале",
+                    "type": "string",
+                    "example": "ivan@example.com"
+                },
+                "status": {
+                    "description": "Результат: sent — доставлено, failed — не доставлено после всех попыток, skipped — у пользователя нет адреса в канале",
+                    "type": "string",
+                    "example": "sent"
+                },
+                "subject": {
+                    "description": "Тема уведомления",
+                    "type": "string",
+                    "example": "Скоро продление подписки Yandex Plus"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+                }
+            }
+        },
+        "types.Discount": {
+            "description": "Скидка на подписку: процент или фиксированная сумма за расчетный период",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Фиксированная скидка за расчетный период в валюте подписки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "end_date": {
+                    "description": "Последний день действия скидки включительно, пусто для бессрочной скидки",
+                    "type": "string",
+                    "example": "06-2026"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0b8f3a52-4c1e-4a8e-9d6f-2f7b5c1e9a30"
+                },
+                "percent": {
+                    "description": "Скидка в процентах от цены",
+                    "type": "integer",
+                    "example": 50
+                },
+                "start_date": {
+                    "description": "Первый день действия скидки",
+                    "type": "string",
+                    "example": "07-2025"
+                }
+            }
+        },
+        "types.DiscountNotFoundErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Discount not found"
+                }
+            }
+        },
+        "types.DiscountRequest": {
+            "description": "Новая скидка на подписку. Указывается либо percent, либо amount; срок задается months или end_date, без них скидка действует до окончания подписки",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Фиксированная скидка за расчетный период в валюте подписки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "end_date": {
+                    "description": "Последний день действия скидки включительно в формате ГГГГ-ММ-ДД или ММ-ГГГГ",
+                    "type": "string",
+                    "example": "06-2026"
+                },
+                "months": {
+                    "description": "Срок действия скидки в месяцах",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 12
+                },
+                "percent": {
+                    "description": "Скидка в процентах от цены, от 1 до 100",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1,
+                    "example": 50
+                },
+                "start_date": {
+                    "description": "Первый день действия скидки в формате ГГГГ-ММ-ДД или ММ-ГГГГ, по умолчанию дата начала подписки",
+                    "type": "string",
+                    "example": "07-2025"
+                }
+            }
+        },
+        "types.ExchangeRate": {
+            "description": "Курс валюты к рублю, действующий с указанной даты до следующего курса",
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Код валюты ISO 4217",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "date": {
+                    "description": "Дата начала действия курса в формате ГГГГ-ММ-ДД или ММ-ГГГГ",
+                    "type": "string",
+                    "example": "2025-07-01"
+                },
+                "rate": {
+                    "description": "Стоимость единицы валюты в рублях",
+                    "type": "number",
+                    "example": 78.45
+                }
+            }
+        },
+        "types.ExchangeRatesResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ExchangeRate"
+                    }
+                }
+            }
+        },
+        "types.FailedToCalculateErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Failed to calculate total cost"
+                }
+            }
+        },
+        "types.FailedToCreateErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Failed to create subscription"
+                }
+            }
+        },
+        "types.FailedToDeleteErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Failed to delete subscription"
+                }
+            }
+        },
+        "types.FailedToGetSubErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Failed to get subscription"
+                }
+            }
+        },
+        "types.FailedToListSubsErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Failed to list subscriptions"
+                }
+            }
+        },
+        "types.FailedToUpdateSub": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
                     "example": "Failed to update subscription"
                 }
             }
         },
-        "types.IDResponse": {
+        "types.IDResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"
+                }
+            }
+        },
+        "types.IdempotencyConflictErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Idempotency-Key already used with a different request"
+                }
+            }
+        },
+        "types.ImportResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "dry_run": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ImportRowError"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "updated": {
+                    "type": "integer",
+                    "example": 20
+                }
+            }
+        },
+        "types.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "price must be a positive amount in major units, e.g. 399.90"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "price"
+                },
+                "row": {
+                    "description": "Номер строки файла (для CSV с учетом заголовка) или позиция в JSON-массиве",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "types.InternalServerErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Failed to process request"
+                }
+            }
+        },
+        "types.InvalidBudgetErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid budget"
+                }
+            }
+        },
+        "types.InvalidColumnMappingErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid column mapping"
+                }
+            }
+        },
+        "types.InvalidCurrencyErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid currency"
+                }
+            }
+        },
+        "types.InvalidDateErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid date"
+                }
+            }
+        },
+        "types.InvalidDeliveryStatusErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid status"
+                }
+            }
+        },
+        "types.InvalidDiscountErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid discount"
+                }
+            }
+        },
+        "types.InvalidGroupByErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid group_by"
+                }
+            }
+        },
+        "types.InvalidIDErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid ID format"
+                }
+            }
+        },
+        "types.InvalidIdempotencyKeyErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid Idempotency-Key header"
+                }
+            }
+        },
+        "types.InvalidImportFileErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid import file"
+                }
+            }
+        },
+        "types.InvalidPatchErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid patch document"
+                }
+            }
+        },
+        "types.InvalidRatesFileErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid exchange rates"
+                }
+            }
+        },
+        "types.InvalidRequestBodyErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid request body"
+                }
+            }
+        },
+        "types.InvalidSharingErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid sharing"
+                }
+            }
+        },
+        "types.InvalidTransitionErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Transition not allowed from status cancelled"
+                }
+            }
+        },
+        "types.InvalidUserDeleteErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid subscriptions or transfer_to parameter"
+                }
+            }
+        },
+        "types.InvalidUserIDErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid user_id format"
+                }
+            }
+        },
+        "types.InvalidViewErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid view"
+                }
+            }
+        },
+        "types.LifecycleRequest": {
+            "description": "Параметры смены статуса подписки",
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Дата вступления в силу в формате ГГГГ-ММ-ДД или ММ-ГГГГ, по умолчанию текущий месяц. Приостановки учитываются по месяцам",
+                    "type": "string",
+                    "example": "03-2026"
+                }
+            }
+        },
+        "types.ListBudgetsResponse": {
+            "type": "object",
+            "properties": {
+                "budgets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Budget"
+                    }
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "types.ListDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Delivery"
+                    }
+                }
+            }
+        },
+        "types.ListRemindersResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "reminders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Reminder"
+                    }
+                }
+            }
+        },
+        "types.ListServicesResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "services": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Service"
+                    }
+                }
+            }
+        },
+        "types.ListSubscriptionsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Subscription"
+                    }
+                }
+            }
+        },
+        "types.ListUsersResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.User"
+                    }
+                }
+            }
+        },
+        "types.ListWebhookDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.WebhookDelivery"
+                    }
+                }
+            }
+        },
+        "types.ListWebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.WebhookEndpoint"
+                    }
+                }
+            }
+        },
+        "types.LoadRatesResponse": {
+            "type": "object",
+            "properties": {
+                "loaded": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "types.Member": {
+            "description": "Участник совместной подписки, кроме владельца",
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Фиксированная сумма участника за расчетный период для правила fixed, в валюте подписки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "percent": {
+                    "description": "Доля участника в процентах для правила percent",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1,
+                    "example": 25
+                },
+                "user_id": {
+                    "description": "ID пользователя-участника",
+                    "type": "string",
+                    "example": "2c1e4a6b-8d0f-4a2c-9e1b-3d5f7a9c1e2b"
+                }
+            }
+        },
+        "types.MissingExchangeRateErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Exchange rate not found"
+                }
+            }
+        },
+        "types.NotFoundErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Subscription not found"
+                }
+            }
+        },
+        "types.PatchTestFailedErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Patch test operation failed"
+                }
+            }
+        },
+        "types.Pause": {
+            "description": "Период приостановки подписки, месяцы включительно",
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "Первый приостановленный месяц в формате ММ-ГГГГ",
+                    "type": "string",
+                    "example": "11-2025"
+                },
+                "to": {
+                    "description": "Последний приостановленный месяц в формате ММ-ГГГГ, пусто для текущей приостановки",
+                    "type": "string",
+                    "example": "02-2026"
+                }
+            }
+        },
+        "types.PeriodStartRequiredErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "period_start are required"
+                }
+            }
+        },
+        "types.PreconditionFailedErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Subscription was modified by another request"
+                }
+            }
+        },
+        "types.PriceChangeNotFoundErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Price change not found"
+                }
+            }
+        },
+        "types.PriceChangeRequest": {
+            "description": "Запланированное изменение цены подписки",
+            "type": "object",
+            "required": [
+                "effective_from"
+            ],
+            "properties": {
+                "effective_from": {
+                    "description": "Первый месяц действия новой цены в формате ММ-ГГГГ или ГГГГ-ММ-ДД (учитывается месяц)",
+                    "type": "string",
+                    "example": "01-2026"
+                },
+                "price": {
+                    "description": "Новая цена за расчетный период в валюте подписки, больше нуля",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                }
+            }
+        },
+        "types.PriceHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"
+                },
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.PricePeriod"
+                    }
+                }
+            }
+        },
+        "types.PricePeriod": {
+            "description": "Цена подписки, действовавшая или действующая в диапазоне месяцев",
+            "type": "object",
+            "properties": {
+                "effective_from": {
+                    "description": "Первый месяц действия цены в формате ММ-ГГГГ",
+                    "type": "string",
+                    "example": "07-2025"
+                },
+                "effective_to": {
+                    "description": "Последний месяц действия цены в формате ММ-ГГГГ, пусто для последней цены",
+                    "type": "string",
+                    "example": "12-2025"
+                },
+                "price": {
+                    "description": "Цена за расчетный период",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "scheduled": {
+                    "description": "Цена еще не вступила в силу",
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "types.Reminder": {
+            "description": "Напоминание о предстоящем продлении или окончании подписки",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Время создания напоминания в формате RFC 3339",
+                    "type": "string",
+                    "example": "2026-03-12T06:00:00Z"
+                },
+                "date": {
+                    "description": "Дата списания или последний день подписки в формате ГГГГ-ММ-ДД",
+                    "type": "string",
+                    "example": "2026-03-15"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f5a7c9e-1b3d-4f5a-8c9e-1b3d5f7a9c0e"
+                },
+                "kind": {
+                    "description": "Тип напоминания: renewal — продление, expiry — окончание",
+                    "type": "string",
+                    "example": "renewal"
+                },
+                "price": {
+                    "description": "Сумма списания для напоминания о продлении",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "service_name": {
+                    "description": "Название сервиса",
+                    "type": "string",
+                    "example": "Yandex Plus"
+                },
+                "sub_id": {
+                    "type": "string",
+                    "example": "8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+                }
+            }
+        },
+        "types.ReminderPreferences": {
+            "description": "Настройки напоминаний пользователя",
+            "type": "object",
+            "properties": {
+                "days_before": {
+                    "description": "За сколько дней напоминать, 0 — значение по умолчанию сервиса",
+                    "type": "integer",
+                    "maximum": 60,
+                    "minimum": 0,
+                    "example": 3
+                },
+                "expiries": {
+                    "description": "Напоминать об окончании подписок",
+                    "type": "boolean",
+                    "example": true
+                },
+                "renewals": {
+                    "description": "Напоминать о продлении подписок",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "types.Renewal": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Дата списания в формате ГГГГ-ММ-ДД",
+                    "type": "string",
+                    "example": "2026-03-15"
+                },
+                "price": {
+                    "description": "Сумма списания с учетом пробного периода, скидок и доли пользователя",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "service_name": {
+                    "type": "string",
+                    "example": "Yandex Plus"
+                }
+            }
+        },
+        "types.Service": {
+            "description": "Сервис из каталога",
+            "type": "object",
+            "required": [
+                "aliases",
+                "name"
+            ],
+            "properties": {
+                "aliases": {
+                    "description": "Другие написания названия, по которым подписки связываются с сервисом без учета регистра",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "yandex plus",
+                        "Яндекс Плюс"
+                    ]
+                },
+                "category": {
+                    "description": "Категория сервиса, используется для подписок без своей категории",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "music"
+                },
+                "default_price": {
+                    "description": "Цена по умолчанию за расчетный период",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d"
+                },
+                "name": {
+                    "description": "Каноническое название сервиса",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Yandex Plus"
+                },
+                "vendor": {
+                    "description": "Компания-поставщик",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Яндекс"
+                },
+                "website": {
+                    "description": "Сайт сервиса",
+                    "type": "string",
+                    "maxLength": 2048,
+                    "example": "https://plus.yandex.ru"
+                }
+            }
+        },
+        "types.ServiceConflictErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Service name or alias is already used by another service"
+                }
+            }
+        },
+        "types.ServiceCost": {
+            "type": "object",
+            "properties": {
+                "cost": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "service_name": {
+                    "type": "string",
+                    "example": "Yandex Plus"
+                }
+            }
+        },
+        "types.ServiceNotFoundErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Service not found"
+                }
+            }
+        },
+        "types.Sharing": {
+            "description": "Разделение стоимости подписки между владельцем и участниками. Владелец платит то, что остается после долей участников",
+            "type": "object",
+            "required": [
+                "split_rule"
+            ],
+            "properties": {
+                "members": {
+                    "description": "Участники подписки без владельца; пустой список отменяет совместное использование",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Member"
+                    }
+                },
+                "split_rule": {
+                    "description": "Правило разделения: equal — поровну, percent — в процентах, fixed — фиксированными суммами",
+                    "type": "string",
+                    "enum": [
+                        "equal",
+                        "percent",
+                        "fixed"
+                    ],
+                    "example": "equal"
+                }
+            }
+        },
+        "types.StatusResponse": {
             "type": "object",
             "properties": {
                 "id": {
                     "type": "string",
                     "example": "8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "paused"
                 }
             }
         },
-        "types.InvalidIDErrorResponse": {
+        "types.Subscription": {
+            "description": "Информация о подписке",
+            "type": "object",
+            "required": [
+                "price",
+                "start_date",
+                "user_id"
+            ],
+            "properties": {
+                "billing_interval": {
+                    "description": "Единица расчетного периода: week, month, quarter или year (по умолчанию month)",
+                    "type": "string",
+                    "enum": [
+                        "week",
+                        "month",
+                        "quarter",
+                        "year"
+                    ],
+                    "example": "month"
+                },
+                "billing_interval_count": {
+                    "description": "Количество единиц в расчетном периоде (по умолчанию 1)",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "category": {
+                    "description": "Собственная категория подписки, например music, video, cloud или dev tools",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "music"
+                },
+                "currency": {
+                    "description": "Код валюты ISO 4217 (по умолчанию RUB)",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "discounts": {
+                    "description": "Скидки подписки, меняются отдельными действиями",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Discount"
+                    },
+                    "readOnly": true
+                },
+                "effective_category": {
+                    "description": "Действующая категория: собственная или, если она не задана, категория сервиса из каталога. Только для чтения",
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "music"
+                },
+                "end_date": {
+                    "description": "Опциональная дата окончания подписки включительно в формате ГГГГ-ММ-ДД или ММ-ГГГГ (последнее число месяца)",
+                    "type": "string"
+                },
+                "pauses": {
+                    "description": "История приостановок подписки",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Pause"
+                    },
+                    "readOnly": true
+                },
+                "price": {
+                    "description": "Стоимость за один расчетный период в основных единицах валюты, больше нуля. В ответе — цена текущего месяца",
+                    "type": "number",
+                    "example": 399.9
+                },
+                "service_id": {
+                    "description": "ID сервиса из каталога. Если указан, название берется из каталога",
+                    "type": "string",
+                    "example": "4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d"
+                },
+                "service_name": {
+                    "description": "Название сервиса. Название или псевдоним из каталога заменяется каноническим названием",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "sharing": {
+                    "description": "Участники совместной подписки и правило разделения стоимости, меняются отдельным действием",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.Sharing"
+                        }
+                    ],
+                    "readOnly": true
+                },
+                "start_date": {
+                    "description": "Дата начала подписки в формате ГГГГ-ММ-ДД или ММ-ГГГГ (первое число месяца)",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Статус подписки: active, paused, cancelled или expired. Только для чтения, меняется отдельными действиями",
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "active"
+                },
+                "tags": {
+                    "description": "Произвольные теги подписки, хранятся в нижнем регистре",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "family",
+                        "work"
+                    ]
+                },
+                "trial_end": {
+                    "description": "Опциональный последний день бесплатного пробного периода в формате ГГГГ-ММ-ДД или ММ-ГГГГ (последнее число месяца)",
+                    "type": "string",
+                    "example": "09-2025"
+                },
+                "user_id": {
+                    "description": "ID пользователя-владельца подписки",
+                    "type": "string"
+                }
+            }
+        },
+        "types.SubscriptionEvent": {
+            "description": "Событие об изменении подписки, тело запроса к вебхуку и сообщения в остальные получатели событий",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Время события в формате RFC 3339",
+                    "type": "string",
+                    "example": "2026-03-01T10:00:00Z"
+                },
+                "data": {
+                    "description": "Подписка после изменения; для удаления — до него",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.Subscription"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5d7f9b1c-3e5a-4c7e-9f1b-3d5f7a9c1e3a"
+                },
+                "sub_id": {
+                    "type": "string",
+                    "example": "8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"
+                },
+                "type": {
+                    "description": "Тип события",
+                    "type": "string",
+                    "example": "subscription.created"
+                }
+            }
+        },
+        "types.SubscriptionMergePatch": {
+            "description": "Частичное обновление подписки (JSON Merge Patch): отсутствующие поля не меняются, null очищает поле",
+            "type": "object",
+            "properties": {
+                "billing_interval": {
+                    "description": "Единица расчетного периода: week, month, quarter или year",
+                    "type": "string",
+                    "example": "month"
+                },
+                "billing_interval_count": {
+                    "description": "Количество единиц в расчетном периоде",
+                    "type": "integer",
+                    "example": 1
+                },
+                "category": {
+                    "description": "Категория подписки, null возвращает категорию сервиса из каталога",
+                    "type": "string",
+                    "example": "music"
+                },
+                "currency": {
+                    "description": "Код валюты ISO 4217",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "end_date": {
+                    "description": "Дата окончания подписки в формате ГГГГ-ММ-ДД или ММ-ГГГГ, null снимает дату окончания",
+                    "type": "string",
+                    "example": "12-2025"
+                },
+                "price": {
+                    "description": "Стоимость за один расчетный период в основных единицах валюты",
+                    "type": "number",
+                    "example": 399.9
+                },
+                "service_id": {
+                    "description": "ID сервиса из каталога, null отвязывает подписку от каталога",
+                    "type": "string",
+                    "example": "4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d"
+                },
+                "service_name": {
+                    "description": "Название сервиса. Чтобы сменить сервис по названию, передайте также service_id: null",
+                    "type": "string",
+                    "example": "Yandex Plus"
+                },
+                "start_date": {
+                    "description": "Дата начала подписки в формате ГГГГ-ММ-ДД или ММ-ГГГГ",
+                    "type": "string",
+                    "example": "07-2025"
+                },
+                "tags": {
+                    "description": "Теги подписки, заменяют текущие целиком",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "family",
+                        "work"
+                    ]
+                },
+                "trial_end": {
+                    "description": "Последний день пробного периода в формате ГГГГ-ММ-ДД или ММ-ГГГГ, null снимает пробный период",
+                    "type": "string",
+                    "example": "09-2025"
+                }
+            }
+        },
+        "types.TotalCostResponse": {
+            "type": "object",
+            "properties": {
+                "by_category": {
+                    "description": "Стоимость по категориям, если указан group_by=category",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.CategoryCost"
+                    }
+                },
+                "currency": {
+                    "description": "Валюта расчета, код ISO 4217",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "total_cost": {
+                    "description": "Общая стоимость в основных единицах валюты",
+                    "type": "number",
+                    "example": 2997.5
+                }
+            }
+        },
+        "types.UnknownServiceErrorResponse": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "Invalid ID format"
+                    "example": "Unknown service_id"
                 }
             }
         },
-        "types.InvalidRequestBodyErrorResponse": {
+        "types.UnknownUserErrorResponse": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "Invalid request body"
+                    "example": "Unknown user_id"
                 }
             }
         },
-        "types.InvalidUserIDErrorResponse": {
+        "types.UnsupportedFormatErrorResponse": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "Invalid user_id format"
+                    "example": "Unsupported format"
                 }
             }
         },
-        "types.ListSubscriptionsResponse": {
+        "types.UnsupportedPatchTypeErrorResponse": {
             "type": "object",
             "properties": {
-                "count": {
+                "error": {
+                    "type": "string",
+                    "example": "Unsupported patch content type"
+                }
+            }
+        },
+        "types.User": {
+            "description": "Пользователь",
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "email": {
+                    "description": "Адрес электронной почты, уникален без учета регистра",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "ivan@example.com"
+                },
+                "external_id": {
+                    "description": "Идентификатор пользователя во внешней системе, уникален",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "crm-10452"
+                },
+                "id": {
+                    "description": "ID пользователя; при создании можно указать user_id уже существующих подписок",
+                    "type": "string",
+                    "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+                },
+                "language": {
+                    "description": "Язык уведомлений: ru (по умолчанию) или en",
+                    "type": "string",
+                    "enum": [
+                        "ru",
+                        "en"
+                    ],
+                    "example": "ru"
+                },
+                "name": {
+                    "description": "Отображаемое имя",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Иван Петров"
+                }
+            }
+        },
+        "types.UserConflictErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "User ID, email or external_id is already used by another user"
+                }
+            }
+        },
+        "types.UserHasSubscriptionsErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "User still owns subscriptions"
+                }
+            }
+        },
+        "types.UserNotFoundErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "User not found"
+                }
+            }
+        },
+        "types.UserSummary": {
+            "description": "Сводка расходов пользователя. Суммы учитывают только долю пользователя в совместных подписках",
+            "type": "object",
+            "properties": {
+                "active_subscriptions": {
+                    "description": "Количество действующих подписок, включая совместные",
                     "type": "integer",
-                    "example": 1
+                    "example": 4
                 },
-                "subscriptions": {
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "month_over_month_change": {
+                    "description": "Изменение расходов текущего месяца по сравнению с предыдущим",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "month_over_month_percent": {
+                    "description": "Изменение в процентах, отсутствует, если в предыдущем месяце расходов не было",
+                    "type": "number",
+                    "example": 12.5
+                },
+                "monthly_spend": {
+                    "description": "Расходы за текущий месяц",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "next_renewals": {
+                    "description": "Ближайшие списания по действующим подпискам в течение года",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/types.Subscription"
+                        "$ref": "#/definitions/types.Renewal"
+                    }
+                },
+                "previous_month_spend": {
+                    "description": "Расходы за предыдущий месяц",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
+                "top_services": {
+                    "description": "Самые дорогие сервисы по расходам текущего месяца",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ServiceCost"
                     }
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+                },
+                "year_to_date_spend": {
+                    "description": "Расходы с начала года по текущий месяц включительно",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
                 }
             }
         },
-        "types.NotFoundErrorResponse": {
+        "types.WebhookDelivery": {
+            "description": "Доставка события на вебхук",
             "type": "object",
             "properties": {
-                "error": {
+                "attempts": {
+                    "description": "Число выполненных попыток",
+                    "type": "integer",
+                    "example": 8
+                },
+                "created_at": {
+                    "description": "Время создания в формате RFC 3339",
                     "type": "string",
-                    "example": "Subscription not found"
+                    "example": "2026-03-01T10:00:00Z"
+                },
+                "delivered_at": {
+                    "description": "Время доставки в формате RFC 3339",
+                    "type": "string",
+                    "example": ""
+                },
+                "endpoint_id": {
+                    "type": "string",
+                    "example": "2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f"
+                },
+                "event": {
+                    "description": "Отправляемое событие",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.SubscriptionEvent"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "7a9c1e3f-5b7d-4f9a-8c1e-3f5b7d9f1a3c"
+                },
+                "last_error": {
+                    "description": "Ошибка последней попытки",
+                    "type": "string",
+                    "example": "webhook responded with status 503"
+                },
+                "last_status_code": {
+                    "description": "HTTP-статус последнего ответа",
+                    "type": "integer",
+                    "example": 503
+                },
+                "next_attempt_at": {
+                    "description": "Время следующей попытки для ожидающих доставок в формате RFC 3339",
+                    "type": "string",
+                    "example": "2026-03-01T10:05:00Z"
+                },
+                "status": {
+                    "description": "Состояние: pending — ожидает отправки, delivered — доставлено, dead — не доставлено после всех попыток",
+                    "type": "string",
+                    "example": "dead"
                 }
             }
         },
-        "types.PeriodStartRequiredErrorResponse": {
+        "types.WebhookDeliveryNotFoundErrorResponse": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "period_start are required"
+                    "example": "Webhook delivery not found"
                 }
             }
         },
-        "types.Subscription": {
-            "description": "Информация о подписке",
+        "types.WebhookEndpoint": {
+            "description": "Адрес, на который отправляются события об изменении подписок",
             "type": "object",
             "required": [
-                "price",
-                "service_name",
-                "start_date",
-                "user_id"
+                "url"
             ],
             "properties": {
-                "end_date": {
-                    "description": "Опциональная дата окончания подписки в формате ММ-ГГГГ",
-                    "type": "string"
+                "created_at": {
+                    "description": "Время регистрации в формате RFC 3339",
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "2026-03-01T10:00:00Z"
                 },
-                "price": {
-                    "description": "Месячная стоимость в рублях",
-                    "type": "integer",
-                    "minimum": 0
+                "disabled": {
+                    "description": "Временно не отправлять события",
+                    "type": "boolean",
+                    "example": false
                 },
-                "service_name": {
-                    "description": "Название сервиса",
-                    "type": "string"
+                "events": {
+                    "description": "Типы событий: subscription.created, subscription.updated, subscription.deleted, subscription.expired. Пусто — все события",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "subscription.created",
+                        "subscription.deleted"
+                    ]
                 },
-                "start_date": {
-                    "description": "Дата начала подписки в формате ММ-ГГГГ",
-                    "type": "string"
+                "id": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f"
                 },
-                "user_id": {
-                    "description": "ID пользователя-владельца подписки",
-                    "type": "string"
+                "secret": {
+                    "description": "Секрет для проверки подписи HMAC-SHA256, возвращается только при регистрации",
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "url": {
+                    "description": "HTTP(S) URL, принимающий POST-запросы с событиями",
+                    "type": "string",
+                    "maxLength": 2048,
+                    "example": "https://crm.example.com/hooks/subscriptions"
                 }
             }
         },
-        "types.TotalCostResponse": {
+        "types.WebhookNotFoundErrorResponse": {
             "type": "object",
             "properties": {
-                "total_cost": {
-                    "type": "integer",
-                    "example": 2997
+                "error": {
+                    "type": "string",
+                    "example": "Webhook not found"
                 }
             }
         }
@@ -1,8 +1,232 @@
+CREATE TABLE users (
+    user_id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE,
+    external_id VARCHAR(255) UNIQUE,
+    language VARCHAR(2) NOT NULL DEFAULT 'ru' CHECK (language IN ('ru', 'en'))
+);
+
+CREATE TABLE services (
+    service_id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(255),
+    vendor VARCHAR(255),
+    website VARCHAR(2048),
+    default_price_minor BIGINT CHECK (default_price_minor >= 0),
+    default_currency CHAR(3),
+    CHECK ((default_price_minor IS NULL) = (default_currency IS NULL))
+);
+
+CREATE TABLE service_aliases (
+    alias_key VARCHAR(255) PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services (service_id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX service_aliases_service_id_idx ON service_aliases (service_id);
+
 CREATE TABLE subscriptions (
     sub_id UUID PRIMARY KEY,
-    user_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (user_id),
     service_name VARCHAR(255) NOT NULL,
-    price INTEGER NOT NULL,
+    service_id UUID REFERENCES services (service_id) ON DELETE SET NULL,
+    price_minor BIGINT NOT NULL CHECK (price_minor >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    billing_interval VARCHAR(16) NOT NULL DEFAULT 'month' CHECK (billing_interval IN ('week', 'month', 'quarter', 'year')),
+    billing_interval_count INTEGER NOT NULL DEFAULT 1 CHECK (billing_interval_count > 0),
     start_date TIMESTAMP NOT NULL,
-    end_date TIMESTAMP
-);
\ No newline at end of file
+    end_date TIMESTAMP,
+    trial_end TIMESTAMP,
+    category VARCHAR(255),
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    split_rule VARCHAR(16) NOT NULL DEFAULT 'equal' CHECK (split_rule IN ('equal', 'percent', 'fixed')),
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled')),
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE idempotency_keys (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    fingerprint CHAR(64) NOT NULL,
+    sub_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX subscriptions_natural_key_idx ON subscriptions (user_id, service_name, start_date);
+
+CREATE INDEX subscriptions_tags_idx ON subscriptions USING GIN (tags);
+
+CREATE TABLE calendar_tokens (
+    user_id UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE subscription_pauses (
+    pause_id BIGSERIAL PRIMARY KEY,
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    start_month TIMESTAMP NOT NULL,
+    end_month TIMESTAMP CHECK (end_month >= start_month)
+);
+
+CREATE INDEX subscription_pauses_sub_id_idx ON subscription_pauses (sub_id);
+
+CREATE TABLE exchange_rates (
+    currency CHAR(3) NOT NULL,
+    effective_date DATE NOT NULL,
+    rate NUMERIC(18, 6) NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (currency, effective_date)
+);
+
+CREATE TABLE subscription_prices (
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    effective_month TIMESTAMP NOT NULL,
+    price_minor BIGINT NOT NULL CHECK (price_minor >= 0),
+    PRIMARY KEY (sub_id, effective_month)
+);
+
+CREATE TABLE subscription_discounts (
+    discount_id UUID PRIMARY KEY,
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    percent SMALLINT CHECK (percent BETWEEN 1 AND 100),
+    amount_minor BIGINT CHECK (amount_minor > 0),
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP,
+    CHECK ((percent IS NULL) <> (amount_minor IS NULL))
+);
+
+CREATE INDEX subscription_discounts_sub_id_idx ON subscription_discounts (sub_id);
+
+CREATE TABLE subscription_members (
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    share BIGINT NOT NULL DEFAULT 0 CHECK (share >= 0),
+    PRIMARY KEY (sub_id, user_id)
+);
+
+CREATE INDEX subscription_members_user_id_idx ON subscription_members (user_id);
+
+CREATE TABLE budgets (
+    budget_id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    period VARCHAR(16) NOT NULL CHECK (period IN ('month', 'year')),
+    amount_minor BIGINT NOT NULL CHECK (amount_minor > 0),
+    currency CHAR(3) NOT NULL,
+    category VARCHAR(255),
+    service_name VARCHAR(255),
+    CHECK (category IS NULL OR service_name IS NULL)
+);
+
+CREATE TABLE budget_users (
+    budget_id UUID NOT NULL REFERENCES budgets (budget_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    PRIMARY KEY (budget_id, user_id)
+);
+
+CREATE INDEX budget_users_user_id_idx ON budget_users (user_id);
+
+CREATE TABLE budget_alerts (
+    alert_id BIGSERIAL PRIMARY KEY,
+    budget_id UUID NOT NULL REFERENCES budgets (budget_id) ON DELETE CASCADE,
+    period_start TIMESTAMP NOT NULL,
+    threshold SMALLINT NOT NULL,
+    spent_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    notify_after TIMESTAMP NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMP,
+    UNIQUE (budget_id, period_start, threshold)
+);
+
+CREATE INDEX budget_alerts_pending_idx ON budget_alerts (notify_after) WHERE notified_at IS NULL;
+
+CREATE TABLE reminder_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    renewals BOOLEAN NOT NULL DEFAULT TRUE,
+    expiries BOOLEAN NOT NULL DEFAULT TRUE,
+    days_before SMALLINT CHECK (days_before > 0)
+);
+
+CREATE TABLE reminders (
+    reminder_id UUID PRIMARY KEY,
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('renewal', 'expiry')),
+    due_date TIMESTAMP NOT NULL,
+    price_minor BIGINT,
+    currency CHAR(3),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    notify_after TIMESTAMP NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMP,
+    UNIQUE (sub_id, kind, due_date)
+);
+
+CREATE INDEX reminders_user_id_idx ON reminders (user_id, created_at);
+CREATE INDEX reminders_pending_idx ON reminders (notify_after) WHERE notified_at IS NULL;
+
+CREATE TABLE notification_deliveries (
+    delivery_id BIGSERIAL PRIMARY KEY,
+    channel VARCHAR(32) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    event_id UUID,
+    user_id UUID,
+    recipient TEXT,
+    subject TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('sent', 'failed', 'skipped')),
+    attempts INTEGER NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX notification_deliveries_user_id_idx ON notification_deliveries (user_id, created_at);
+CREATE INDEX notification_deliveries_event_id_idx ON notification_deliveries (event_id, channel) WHERE event_id IS NOT NULL;
+
+CREATE TABLE webhook_endpoints (
+    endpoint_id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_events (
+    event_id UUID PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    sub_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_deliveries (
+    delivery_id UUID PRIMARY KEY,
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints (endpoint_id) ON DELETE CASCADE,
+    event_id UUID NOT NULL REFERENCES webhook_events (event_id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at);
+
+CREATE TABLE outbox_events (
+    event_id UUID PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    sub_id UUID NOT NULL,
+    dedup_key TEXT UNIQUE,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT clock_timestamp(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX outbox_events_pending_idx ON outbox_events (created_at) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_pending_sub_id_idx ON outbox_events (sub_id, created_at) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_published_at_idx ON outbox_events (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,425 @@
+package billing
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/money"
+)
+
+const (
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalQuarter = "quarter"
+	IntervalYear    = "year"
+)
+
+const (
+	// ViewAccrual spreads every charge evenly over the months it pays for.
+	ViewAccrual = "accrual"
+	// ViewCash attributes the whole charge to the month of the billing date.
+	ViewCash = "cash"
+)
+
+// cyclesPerYear is how many one-unit billing cycles of each interval fit into
+// a year; accrual amortizes a cycle price over 12 months using it.
+var cyclesPerYear = map[string]int{
+	IntervalWeek:    52,
+	IntervalMonth:   12,
+	IntervalQuarter: 4,
+	IntervalYear:    1,
+}
+
+const (
+	// SplitEqual divides the price evenly among the owner and the members.
+	SplitEqual = "equal"
+	// SplitPercent gives every member a percentage of the price.
+	SplitPercent = "percent"
+	// SplitFixed gives every member a fixed amount of each cycle's price.
+	SplitFixed = "fixed"
+)
+
+// ValidInterval reports whether interval is a supported billing interval.
+func ValidInterval(interval string) bool {
+	_, ok := cyclesPerYear[interval]
+	return ok
+}
+
+// ValidView reports whether view is a supported cost attribution view.
+func ValidView(view string) bool {
+	return view == ViewAccrual || view == ViewCash
+}
+
+// Range is an inclusive range of months. A nil To means the range is open.
+type Range struct {
+	From time.Time
+	To   *time.Time
+}
+
+func (r Range) Contains(month time.Time) bool {
+	return !month.Before(r.From) && (r.To == nil || !month.After(*r.To))
+}
+
+// PriceChange replaces the item's price from the month starting on From.
+type PriceChange struct {
+	From  time.Time
+	Price int64
+}
+
+// Discount lowers the price on the days From through To, both inclusive; a
+// nil To means until the subscription ends. A non-zero Percent takes that
+// share off the price, otherwise Amount minor units are taken off, never
+// going below zero.
+type Discount struct {
+	From    time.Time
+	To      *time.Time
+	Percent int
+	Amount  int64
+}
+
+func (d Discount) covers(day time.Time) bool {
+	return !day.Before(d.From) && (d.To == nil || !day.After(*d.To))
+}
+
+// Item is everything the calculation needs to know about one subscription.
+// Price, in minor units, is charged once per Interval × IntervalCount starting
+// on Start until the first of PriceChanges, which must be sorted by From; End
+// is the last day of the subscription, inclusive. Nothing is due through
+// TrialEnd, and Discounts in effect on the same day are applied in order.
+type Item struct {
+	Price         int64
+	PriceChanges  []PriceChange
+	Interval      string
+	IntervalCount int
+	Start         time.Time
+	End           *time.Time
+	TrialEnd      *time.Time
+	Discounts     []Discount
+	Pauses        []Range
+}
+
+// PriceAt returns the price in effect in month.
+func (item Item) PriceAt(month time.Time) int64 {
+	price := item.Price
+	for _, change := range item.PriceChanges {
+		if change.From.After(month) {
+			break
+		}
+		price = change.Price
+	}
+	return price
+}
+
+// PriceOn returns the price due for a cycle billed on day: the price in effect
+// in its month less the trial and the discounts covering the day.
+func (item Item) PriceOn(day time.Time) *big.Rat {
+	if item.TrialEnd != nil && !day.After(*item.TrialEnd) {
+		return new(big.Rat)
+	}
+
+	price := big.NewRat(item.PriceAt(dates.MonthStart(day)), 1)
+	for _, discount := range item.Discounts {
+		if !discount.covers(day) {
+			continue
+		}
+		if discount.Percent != 0 {
+			price.Mul(price, big.NewRat(int64(100-discount.Percent), 100))
+			continue
+		}
+		price.Sub(price, big.NewRat(discount.Amount, 1))
+		if price.Sign() < 0 {
+			price.SetInt64(0)
+		}
+	}
+	return price
+}
+
+// Split is how the price of a shared item is divided among the users paying
+// for it. Shares holds one entry per member other than the owner: ignored for
+// equal splits, a percentage for percent splits and minor units per cycle for
+// fixed ones. The owner covers whatever the members leave.
+type Split struct {
+	Rule   string
+	Shares []int64
+}
+
+// Portion returns the part of price paid by payer: 0 is the owner, i > 0 the
+// member with Shares[i-1]. Fixed shares are taken in order and never exceed
+// what is left of the price. Any other payer, such as a user who is no longer
+// a member, pays nothing.
+func (s Split) Portion(price *big.Rat, payer int) *big.Rat {
+	if payer < 0 || payer > len(s.Shares) {
+		return new(big.Rat)
+	}
+	switch s.Rule {
+	case SplitPercent:
+		percent := int64(100)
+		if payer > 0 {
+			percent = s.Shares[payer-1]
+		} else {
+			for _, share := range s.Shares {
+				percent -= share
+			}
+		}
+		return new(big.Rat).Mul(price, big.NewRat(percent, 100))
+	case SplitFixed:
+		left := new(big.Rat).Set(price)
+		for i, share := range s.Shares {
+			part := big.NewRat(share, 1)
+			if part.Cmp(left) > 0 {
+				part.Set(left)
+			}
+			if i+1 == payer {
+				return part
+			}
+			left.Sub(left, part)
+		}
+		return left
+	default:
+		return new(big.Rat).Quo(price, big.NewRat(int64(len(s.Shares)+1), 1))
+	}
+}
+
+// Options control how a cost is attributed to a period.
+type Options struct {
+	// View is ViewAccrual or ViewCash; empty means accrual.
+	View string
+	// Prorate counts partial months by day, and trials and discounts apply
+	// on exactly the days they cover. Otherwise every month the subscription
+	// touches counts in full, the period is widened to whole months and a
+	// month is priced as on its last day.
+	Prorate bool
+	// Factor converts minor units of the item's price into minor units of the
+	// reporting currency for the month starting on its argument. Nil means no
+	// conversion.
+	Factor func(month time.Time) (*big.Rat, error)
+	// Portion picks the part of a price the report is about, such as one
+	// user's share of a shared item. Nil means the whole price.
+	Portion func(price *big.Rat) *big.Rat
+}
+
+// Cost is the amount an item contributes to a period in minor units, how many
+// months of the period it was running and not paused, and how many billing
+// dates fell into them.
+type Cost struct {
+	Amount  int64
+	Months  int
+	Charges int
+}
+
+// Calculate attributes the item's charges to the days periodStart through
+// periodEnd. Paused months are neither accrued nor charged.
+//
+// Proration and conversion are exact: accrual adds up price × cycles per year
+// / 12 / IntervalCount × days used / days in month × factor for every month,
+// cash adds up price × factor for every billing date, and the sum is rounded
+// to a whole minor unit once, halves away from zero. The price is the one
+// PriceOn gives for the day being accrued or charged, narrowed by Portion.
+func Calculate(item Item, periodStart, periodEnd time.Time, opts Options) (Cost, error) {
+	interval, count := item.Interval, item.IntervalCount
+	if interval == "" {
+		interval = IntervalMonth
+	}
+	if count <= 0 {
+		count = 1
+	}
+	perYear, ok := cyclesPerYear[interval]
+	if !ok {
+		return Cost{}, fmt.Errorf("unknown billing interval %q", interval)
+	}
+
+	from, to := dates.Day(periodStart), dates.Day(periodEnd)
+	start, end := dates.Day(item.Start), to
+	if item.End != nil && item.End.Before(end) {
+		end = dates.Day(*item.End)
+	}
+	if !opts.Prorate {
+		from, to = dates.MonthStart(from), dates.MonthEnd(to)
+		start, end = dates.MonthStart(start), dates.MonthEnd(end)
+		if end.After(to) {
+			end = to
+		}
+	}
+	// Billing dates after the last day are never charged, even when whole
+	// months are counted.
+	lastCharge := end
+	if item.End != nil && item.End.Before(lastCharge) {
+		lastCharge = dates.Day(*item.End)
+	}
+	first := maxTime(from, start)
+
+	if !ValidView(opts.View) && opts.View != "" {
+		return Cost{}, fmt.Errorf("unknown cost view %q", opts.View)
+	}
+	factor := opts.Factor
+	if factor == nil {
+		factor = func(time.Time) (*big.Rat, error) { return big.NewRat(1, 1), nil }
+	}
+	priceOn := item.PriceOn
+	if opts.Portion != nil {
+		priceOn = func(day time.Time) *big.Rat { return opts.Portion(item.PriceOn(day)) }
+	}
+	var (
+		cost   Cost
+		amount = new(big.Rat)
+	)
+	for month := dates.MonthStart(first); !month.After(end); month = month.AddDate(0, 1, 0) {
+		if paused(item.Pauses, month) {
+			continue
+		}
+		cost.Months++
+		if opts.View == ViewCash {
+			continue
+		}
+
+		a, b := maxTime(month, first), minTime(dates.MonthEnd(month), end)
+		var used *big.Rat
+		if opts.Prorate {
+			used = accrue(item, priceOn, a, b)
+		} else {
+			used = new(big.Rat).Mul(priceOn(dates.MonthEnd(month)), big.NewRat(int64(daysBetween(a, b)), 1))
+		}
+		days := daysBetween(month, dates.MonthEnd(month))
+		f, err := factor(month)
+		if err != nil {
+			return Cost{}, err
+		}
+		used.Mul(used, big.NewRat(int64(perYear), int64(12*count*days)))
+		amount.Add(amount, used.Mul(used, f))
+	}
+
+	for n := 0; ; n++ {
+		charge := chargeDate(dates.Day(item.Start), interval, count*n)
+		if charge.After(lastCharge) {
+			break
+		}
+		if charge.Before(first) || paused(item.Pauses, dates.MonthStart(charge)) {
+			continue
+		}
+		cost.Charges++
+		if opts.View != ViewCash {
+			continue
+		}
+
+		f, err := factor(dates.MonthStart(charge))
+		if err != nil {
+			return Cost{}, err
+		}
+		amount.Add(amount, new(big.Rat).Mul(priceOn(charge), f))
+	}
+
+	rounded, err := money.Round(amount)
+	if err != nil {
+		return Cost{}, err
+	}
+	cost.Amount = rounded
+	return cost, nil
+}
+
+// accrue adds up the prices due on the days a through b. The price only
+// changes on the days a trial or a discount starts or ends, so it is taken
+// once for every stretch of days between them.
+func accrue(item Item, priceOn func(time.Time) *big.Rat, a, b time.Time) *big.Rat {
+	cuts := []time.Time{b.AddDate(0, 0, 1)}
+	cut := func(day time.Time) {
+		if day = dates.Day(day); day.After(a) && !day.After(b) {
+			cuts = append(cuts, day)
+		}
+	}
+	if item.TrialEnd != nil {
+		cut(item.TrialEnd.AddDate(0, 0, 1))
+	}
+	for _, discount := range item.Discounts {
+		cut(discount.From)
+		if discount.To != nil {
+			cut(discount.To.AddDate(0, 0, 1))
+		}
+	}
+	slices.SortFunc(cuts, time.Time.Compare)
+
+	sum := new(big.Rat)
+	from := a
+	for _, next := range cuts {
+		if !next.After(from) {
+			continue
+		}
+		days := big.NewRat(int64(daysBetween(from, next.AddDate(0, 0, -1))), 1)
+		sum.Add(sum, days.Mul(days, priceOn(from)))
+		from = next
+	}
+	return sum
+}
+
+// NextCharge returns the first billing date of the item from day through
+// until, inclusive, that falls before the item ends and outside its pauses.
+// It reports false if there is none.
+func NextCharge(item Item, day, until time.Time) (time.Time, bool) {
+	interval, count := item.Interval, item.IntervalCount
+	if interval == "" {
+		interval = IntervalMonth
+	}
+	if count <= 0 {
+		count = 1
+	}
+
+	day, until = dates.Day(day), dates.Day(until)
+	if item.End != nil && item.End.Before(until) {
+		until = dates.Day(*item.End)
+	}
+	for n := 0; ; n++ {
+		charge := chargeDate(dates.Day(item.Start), interval, count*n)
+		if charge.After(until) {
+			return time.Time{}, false
+		}
+		if !charge.Before(day) && !paused(item.Pauses, dates.MonthStart(charge)) {
+			return charge, true
+		}
+	}
+}
+
+// chargeDate returns the billing date n interval units after start. Monthly
+// dates that do not exist, such as the 31st in a shorter month, fall on the
+// last day of the month.
+func chargeDate(start time.Time, interval string, n int) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7*n)
+	case IntervalQuarter:
+		return dates.AddMonths(start, 3*n)
+	case IntervalYear:
+		return dates.AddMonths(start, 12*n)
+	default:
+		return dates.AddMonths(start, n)
+	}
+}
+
+func paused(pauses []Range, month time.Time) bool {
+	for _, pause := range pauses {
+		if pause.Contains(month) {
+			return true
+		}
+	}
+	return false
+}
+
+// daysBetween counts the days from a through b, both inclusive.
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours()/24) + 1
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
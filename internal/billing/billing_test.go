@@ -0,0 +1,247 @@
+package billing
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestCalculate(t *testing.T) {
+	monthly := Item{Price: 1000, Interval: IntervalMonth, IntervalCount: 1, Start: day(2025, 1, 1)}
+
+	tests := []struct {
+		name     string
+		item     Item
+		from, to time.Time
+		opts     Options
+		want     Cost
+	}{
+		{
+			name: "monthly",
+			item: monthly,
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			want: Cost{Amount: 3000, Months: 3, Charges: 3},
+		},
+		{
+			name: "monthly counts partial months in full",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 15), End: ptr(day(2025, 3, 10))},
+			from: day(2025, 1, 1), to: day(2025, 12, 31),
+			want: Cost{Amount: 3000, Months: 3, Charges: 2},
+		},
+		{
+			name: "monthly prorated by day",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 15), End: ptr(day(2025, 2, 14))},
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			opts: Options{Prorate: true},
+			// 17/31 of January and 14/28 of February.
+			want: Cost{Amount: 1048, Months: 2, Charges: 1},
+		},
+		{
+			name: "yearly accrual",
+			item: Item{Price: 12000, Interval: IntervalYear, IntervalCount: 1, Start: day(2025, 1, 1)},
+			from: day(2025, 1, 1), to: day(2025, 6, 30),
+			want: Cost{Amount: 6000, Months: 6, Charges: 1},
+		},
+		{
+			name: "yearly cash",
+			item: Item{Price: 12000, Interval: IntervalYear, IntervalCount: 1, Start: day(2025, 1, 1)},
+			from: day(2025, 1, 1), to: day(2025, 6, 30),
+			opts: Options{View: ViewCash},
+			want: Cost{Amount: 12000, Months: 6, Charges: 1},
+		},
+		{
+			name: "yearly cash without a billing date",
+			item: Item{Price: 12000, Interval: IntervalYear, IntervalCount: 1, Start: day(2025, 1, 1)},
+			from: day(2025, 2, 1), to: day(2025, 6, 30),
+			opts: Options{View: ViewCash},
+			want: Cost{Amount: 0, Months: 5, Charges: 0},
+		},
+		{
+			name: "every three months",
+			item: Item{Price: 3000, Interval: IntervalMonth, IntervalCount: 3, Start: day(2025, 1, 1)},
+			from: day(2025, 1, 1), to: day(2025, 6, 30),
+			want: Cost{Amount: 6000, Months: 6, Charges: 2},
+		},
+		{
+			name: "quarterly cash",
+			item: Item{Price: 3000, Interval: IntervalQuarter, IntervalCount: 1, Start: day(2025, 1, 1)},
+			from: day(2025, 1, 1), to: day(2025, 6, 30),
+			opts: Options{View: ViewCash},
+			want: Cost{Amount: 6000, Months: 6, Charges: 2},
+		},
+		{
+			name: "weekly",
+			item: Item{Price: 700, Interval: IntervalWeek, IntervalCount: 1, Start: day(2025, 1, 1)},
+			from: day(2025, 1, 1), to: day(2025, 1, 31),
+			// 700 × 52 / 12 = 3033.33.
+			want: Cost{Amount: 3033, Months: 1, Charges: 5},
+		},
+		{
+			name: "billing dates on the 31st fall on the last day of shorter months",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 31)},
+			from: day(2025, 2, 1), to: day(2025, 4, 30),
+			opts: Options{View: ViewCash},
+			want: Cost{Amount: 3000, Months: 3, Charges: 3},
+		},
+		{
+			name: "trial is free",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 1), TrialEnd: ptr(day(2025, 1, 31))},
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			want: Cost{Amount: 2000, Months: 3, Charges: 3},
+		},
+		{
+			name: "trial cash",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 1), TrialEnd: ptr(day(2025, 1, 31))},
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			opts: Options{View: ViewCash},
+			want: Cost{Amount: 2000, Months: 3, Charges: 3},
+		},
+		{
+			name: "trial ending mid-month is prorated",
+			item: Item{Price: 3100, Interval: IntervalMonth, Start: day(2025, 1, 1), TrialEnd: ptr(day(2025, 1, 10))},
+			from: day(2025, 1, 1), to: day(2025, 1, 31),
+			opts: Options{Prorate: true},
+			want: Cost{Amount: 2100, Months: 1, Charges: 1},
+		},
+		{
+			name: "paused month",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 1), Pauses: []Range{{From: day(2025, 2, 1), To: ptr(day(2025, 2, 1))}}},
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			want: Cost{Amount: 2000, Months: 2, Charges: 2},
+		},
+		{
+			name: "open pause",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 1), Pauses: []Range{{From: day(2025, 3, 1)}}},
+			from: day(2025, 1, 1), to: day(2025, 6, 30),
+			opts: Options{View: ViewCash},
+			want: Cost{Amount: 2000, Months: 2, Charges: 2},
+		},
+		{
+			name: "price change",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 1), PriceChanges: []PriceChange{{From: day(2025, 2, 1), Price: 2000}}},
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			want: Cost{Amount: 5000, Months: 3, Charges: 3},
+		},
+		{
+			name: "percent discount",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 1), Discounts: []Discount{{From: day(2025, 1, 1), To: ptr(day(2025, 1, 31)), Percent: 50}}},
+			from: day(2025, 1, 1), to: day(2025, 2, 28),
+			want: Cost{Amount: 1500, Months: 2, Charges: 2},
+		},
+		{
+			name: "discount within a prorated month",
+			item: Item{Price: 3100, Interval: IntervalMonth, Start: day(2025, 1, 1), TrialEnd: ptr(day(2025, 1, 5)), Discounts: []Discount{{From: day(2025, 1, 11), To: ptr(day(2025, 1, 20)), Percent: 50}}},
+			from: day(2025, 1, 1), to: day(2025, 1, 31),
+			opts: Options{Prorate: true},
+			// 5 free days, 5 days at 100, 10 days at 50 and 11 days at 100.
+			want: Cost{Amount: 2100, Months: 1, Charges: 1},
+		},
+		{
+			name: "amount discount never goes below zero",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 1, 1), Discounts: []Discount{{From: day(2025, 1, 1), Amount: 1500}}},
+			from: day(2025, 1, 1), to: day(2025, 2, 28),
+			opts: Options{View: ViewCash},
+			want: Cost{Amount: 0, Months: 2, Charges: 2},
+		},
+		{
+			name: "factor and portion",
+			item: monthly,
+			from: day(2025, 1, 1), to: day(2025, 2, 28),
+			opts: Options{
+				Factor:  func(time.Time) (*big.Rat, error) { return big.NewRat(3, 2), nil },
+				Portion: func(price *big.Rat) *big.Rat { return new(big.Rat).Quo(price, big.NewRat(3, 1)) },
+			},
+			want: Cost{Amount: 1000, Months: 2, Charges: 2},
+		},
+		{
+			name: "half a minor unit rounds up",
+			item: Item{Price: 1, Interval: IntervalMonth, Start: day(2025, 4, 1)},
+			from: day(2025, 4, 1), to: day(2025, 4, 15),
+			opts: Options{Prorate: true},
+			want: Cost{Amount: 1, Months: 1, Charges: 1},
+		},
+		{
+			name: "less than half a minor unit rounds down",
+			item: Item{Price: 1, Interval: IntervalMonth, Start: day(2025, 4, 1)},
+			from: day(2025, 4, 1), to: day(2025, 4, 14),
+			opts: Options{Prorate: true},
+			want: Cost{Amount: 0, Months: 1, Charges: 1},
+		},
+		{
+			name: "rounded once for the whole period",
+			item: Item{Price: 100, Interval: IntervalMonth, IntervalCount: 3, Start: day(2025, 1, 1)},
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			// 33.33 three times is 100, not 99.
+			want: Cost{Amount: 100, Months: 3, Charges: 1},
+		},
+		{
+			name: "outside the period",
+			item: Item{Price: 1000, Interval: IntervalMonth, Start: day(2025, 5, 1)},
+			from: day(2025, 1, 1), to: day(2025, 3, 31),
+			want: Cost{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Calculate(tt.item, tt.from, tt.to, tt.opts)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Calculate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateRejectsUnknownSettings(t *testing.T) {
+	if _, err := Calculate(Item{Price: 1000, Interval: "day", Start: day(2025, 1, 1)}, day(2025, 1, 1), day(2025, 1, 31), Options{}); err == nil {
+		t.Error("Calculate() with an unknown interval succeeded")
+	}
+	if _, err := Calculate(Item{Price: 1000, Start: day(2025, 1, 1)}, day(2025, 1, 1), day(2025, 1, 31), Options{View: "forecast"}); err == nil {
+		t.Error("Calculate() with an unknown view succeeded")
+	}
+}
+
+func TestSplitPortion(t *testing.T) {
+	price := big.NewRat(1000, 1)
+
+	tests := []struct {
+		name  string
+		split Split
+		payer int
+		want  *big.Rat
+	}{
+		{name: "equal owner", split: Split{Rule: SplitEqual, Shares: []int64{0, 0}}, payer: 0, want: big.NewRat(1000, 3)},
+		{name: "equal member", split: Split{Rule: SplitEqual, Shares: []int64{0, 0}}, payer: 2, want: big.NewRat(1000, 3)},
+		{name: "equal without members", split: Split{Rule: SplitEqual}, payer: 0, want: big.NewRat(1000, 1)},
+		{name: "percent member", split: Split{Rule: SplitPercent, Shares: []int64{25, 30}}, payer: 1, want: big.NewRat(250, 1)},
+		{name: "percent owner takes the remainder", split: Split{Rule: SplitPercent, Shares: []int64{25, 30}}, payer: 0, want: big.NewRat(450, 1)},
+		{name: "fixed member", split: Split{Rule: SplitFixed, Shares: []int64{300, 200}}, payer: 2, want: big.NewRat(200, 1)},
+		{name: "fixed owner takes the remainder", split: Split{Rule: SplitFixed, Shares: []int64{300, 200}}, payer: 0, want: big.NewRat(500, 1)},
+		{name: "fixed share capped at the price", split: Split{Rule: SplitFixed, Shares: []int64{1500, 200}}, payer: 1, want: big.NewRat(1000, 1)},
+		{name: "fixed share after the price is used up", split: Split{Rule: SplitFixed, Shares: []int64{1500, 200}}, payer: 2, want: new(big.Rat)},
+		{name: "fixed owner of a price used up", split: Split{Rule: SplitFixed, Shares: []int64{1500, 200}}, payer: 0, want: new(big.Rat)},
+		{name: "equal non-member", split: Split{Rule: SplitEqual, Shares: []int64{0}}, payer: -1, want: new(big.Rat)},
+		{name: "percent non-member", split: Split{Rule: SplitPercent, Shares: []int64{25}}, payer: -1, want: new(big.Rat)},
+		{name: "fixed non-member", split: Split{Rule: SplitFixed, Shares: []int64{300}}, payer: -1, want: new(big.Rat)},
+		{name: "payer past the members", split: Split{Rule: SplitPercent, Shares: []int64{25}}, payer: 2, want: new(big.Rat)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.split.Portion(price, tt.payer); got.Cmp(tt.want) != 0 {
+				t.Errorf("Portion() = %s, want %s", got.RatString(), tt.want.RatString())
+			}
+		})
+	}
+}
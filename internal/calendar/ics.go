@@ -0,0 +1,206 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/types"
+)
+
+const (
+	dateLayout      = "20060102"
+	timestampLayout = "20060102T150405Z"
+	uidDomain       = "subcontroller"
+	maxLineOctets   = 75
+)
+
+// WriteICS renders an iCalendar (RFC 5545) feed with a monthly recurring
+// renewal event for every subscription and a separate event on the end date
+// of subscriptions that expire. Renewals that fall in paused months are left
+// out, and an ongoing pause ends the renewals.
+func WriteICS(w io.Writer, name string, entries []types.CalendarEntry, now time.Time) error {
+	cw := &writer{w: w}
+	stamp := now.UTC().Format(timestampLayout)
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//ItserX//SubController//RU")
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	cw.line("X-WR-CALNAME:" + escape(name))
+
+	for _, entry := range entries {
+		until, skipped := renewals(entry)
+		if until == nil || !until.Before(entry.StartDate) {
+			cw.line("BEGIN:VEVENT")
+			cw.line(fmt.Sprintf("UID:%s-renewal@%s", entry.SubID, uidDomain))
+			cw.line("DTSTAMP:" + stamp)
+			cw.line("DTSTART;VALUE=DATE:" + entry.StartDate.Format(dateLayout))
+			cw.line("DTEND;VALUE=DATE:" + entry.StartDate.AddDate(0, 0, 1).Format(dateLayout))
+			rule := "RRULE:" + recurrence(entry.BillingInterval, entry.BillingIntervalCount, entry.StartDate)
+			if until != nil {
+				rule += ";UNTIL=" + until.Format(dateLayout)
+			}
+			cw.line(rule)
+			if len(skipped) > 0 {
+				exdates := make([]string, len(skipped))
+				for i, day := range skipped {
+					exdates[i] = day.Format(dateLayout)
+				}
+				cw.line("EXDATE;VALUE=DATE:" + strings.Join(exdates, ","))
+			}
+			cw.line("SUMMARY:" + escape(fmt.Sprintf("Продление %s — %s %s", entry.ServiceName, entry.Price.Decimal(), symbol(entry.Price.Currency))))
+			cw.line("TRANSP:TRANSPARENT")
+			cw.line("END:VEVENT")
+		}
+
+		if entry.EndDate == nil {
+			continue
+		}
+		cw.line("BEGIN:VEVENT")
+		cw.line(fmt.Sprintf("UID:%s-expiration@%s", entry.SubID, uidDomain))
+		cw.line("DTSTAMP:" + stamp)
+		cw.line("DTSTART;VALUE=DATE:" + entry.EndDate.Format(dateLayout))
+		cw.line("DTEND;VALUE=DATE:" + entry.EndDate.AddDate(0, 0, 1).Format(dateLayout))
+		cw.line("SUMMARY:" + escape("Окончание подписки "+entry.ServiceName))
+		cw.line("TRANSP:TRANSPARENT")
+		cw.line("END:VEVENT")
+	}
+
+	cw.line("END:VCALENDAR")
+	return cw.err
+}
+
+// renewals returns the last day renewals may fall on, nil if they go on, and
+// the renewal dates that fall in finished pauses. An ongoing pause stops the
+// renewals the day before it starts.
+func renewals(entry types.CalendarEntry) (*time.Time, []time.Time) {
+	until := entry.EndDate
+	for _, pause := range entry.Pauses {
+		if pause.To != nil {
+			continue
+		}
+		last := pause.From.AddDate(0, 0, -1)
+		if until == nil || last.Before(*until) {
+			until = &last
+		}
+	}
+
+	item := billing.Item{
+		Interval:      entry.BillingInterval,
+		IntervalCount: entry.BillingIntervalCount,
+		Start:         entry.StartDate,
+		End:           until,
+	}
+	var skipped []time.Time
+	for _, pause := range entry.Pauses {
+		if pause.To == nil {
+			continue
+		}
+		day, last := pause.From, dates.MonthEnd(*pause.To)
+		for {
+			charge, ok := billing.NextCharge(item, day, last)
+			if !ok {
+				break
+			}
+			skipped = append(skipped, charge)
+			day = charge.AddDate(0, 0, 1)
+		}
+	}
+	return until, skipped
+}
+
+type writer struct {
+	w   io.Writer
+	err error
+}
+
+// line writes a content line terminated by CRLF, folding it so that no
+// physical line exceeds 75 octets without splitting a UTF-8 character.
+func (cw *writer) line(s string) {
+	if cw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+
+	_, cw.err = io.WriteString(cw.w, b.String())
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// recurrence maps a billing cycle onto an RRULE frequency and interval.
+// RFC 5545 skips monthly and yearly occurrences on days a month lacks, while
+// billing moves them to the last day of the month, so cycles starting after
+// the 28th pick the start day or, failing that, the month's last day.
+func recurrence(interval string, count int, start time.Time) string {
+	if count <= 0 {
+		count = 1
+	}
+	freq := "MONTHLY"
+	switch interval {
+	case billing.IntervalWeek:
+		freq = "WEEKLY"
+	case billing.IntervalQuarter:
+		count *= 3
+	case billing.IntervalYear:
+		freq = "YEARLY"
+	}
+
+	rule := "FREQ=" + freq
+	if count > 1 {
+		rule += fmt.Sprintf(";INTERVAL=%d", count)
+	}
+	if freq == "WEEKLY" || start.Day() < 29 {
+		return rule
+	}
+	if freq == "YEARLY" {
+		rule += fmt.Sprintf(";BYMONTH=%d", start.Month())
+	}
+	if start.Day() == 31 {
+		return rule + ";BYMONTHDAY=-1"
+	}
+	return rule + fmt.Sprintf(";BYMONTHDAY=%d,-1;BYSETPOS=1", start.Day())
+}
+
+var currencySymbols = map[string]string{
+	"RUB": "₽",
+	"USD": "$",
+	"EUR": "€",
+}
+
+// symbol returns the sign of common currencies and the code of the rest.
+func symbol(currency string) string {
+	if sign, ok := currencySymbols[currency]; ok {
+		return sign
+	}
+	return currency
+}
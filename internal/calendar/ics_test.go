@@ -0,0 +1,127 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+// render writes entries as a feed and returns its unfolded content lines.
+func render(t *testing.T, entries ...types.CalendarEntry) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, "Подписки", entries, day(2025, 1, 1)); err != nil {
+		t.Fatalf("WriteICS() error = %v", err)
+	}
+	return strings.Split(strings.ReplaceAll(buf.String(), "\r\n ", ""), "\r\n")
+}
+
+// property returns the values of every line of the named property.
+func property(lines []string, name string) []string {
+	var values []string
+	for _, line := range lines {
+		if value, ok := strings.CutPrefix(line, name); ok && (value[0] == ':' || value[0] == ';') {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func entry(interval string, count int, start time.Time) types.CalendarEntry {
+	return types.CalendarEntry{
+		SubID:                uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		ServiceName:          "Yandex Plus",
+		Price:                money.New(39990, "RUB"),
+		BillingInterval:      interval,
+		BillingIntervalCount: count,
+		StartDate:            start,
+	}
+}
+
+func TestRecurrence(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval string
+		count    int
+		start    time.Time
+		want     string
+	}{
+		{name: "monthly", interval: billing.IntervalMonth, count: 1, start: day(2025, 1, 15), want: "FREQ=MONTHLY"},
+		{name: "every two months", interval: billing.IntervalMonth, count: 2, start: day(2025, 1, 28), want: "FREQ=MONTHLY;INTERVAL=2"},
+		{name: "quarterly", interval: billing.IntervalQuarter, count: 1, start: day(2025, 1, 15), want: "FREQ=MONTHLY;INTERVAL=3"},
+		{name: "weekly on the 31st", interval: billing.IntervalWeek, count: 2, start: day(2025, 1, 31), want: "FREQ=WEEKLY;INTERVAL=2"},
+		{name: "monthly on the 31st", interval: billing.IntervalMonth, count: 1, start: day(2025, 1, 31), want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{name: "monthly on the 30th", interval: billing.IntervalMonth, count: 1, start: day(2025, 1, 30), want: "FREQ=MONTHLY;BYMONTHDAY=30,-1;BYSETPOS=1"},
+		{name: "quarterly on the 29th", interval: billing.IntervalQuarter, count: 1, start: day(2024, 11, 29), want: "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=29,-1;BYSETPOS=1"},
+		{name: "yearly on Feb 29", interval: billing.IntervalYear, count: 1, start: day(2024, 2, 29), want: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29,-1;BYSETPOS=1"},
+		{name: "default interval", count: 0, start: day(2025, 1, 1), want: "FREQ=MONTHLY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recurrence(tt.interval, tt.count, tt.start); got != tt.want {
+				t.Errorf("recurrence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteICSEndOfMonthStart(t *testing.T) {
+	lines := render(t, entry(billing.IntervalMonth, 1, day(2025, 1, 31)))
+
+	if got := property(lines, "DTSTART"); len(got) != 1 || got[0] != ";VALUE=DATE:20250131" {
+		t.Errorf("DTSTART = %v, want the start date", got)
+	}
+	if got := property(lines, "RRULE"); len(got) != 1 || got[0] != ":FREQ=MONTHLY;BYMONTHDAY=-1" {
+		t.Errorf("RRULE = %v, want renewals on the last day of every month", got)
+	}
+	if got := property(lines, "UID"); len(got) != 1 {
+		t.Errorf("UID = %v, want only the renewal event", got)
+	}
+}
+
+func TestWriteICSPauses(t *testing.T) {
+	e := entry(billing.IntervalMonth, 1, day(2025, 1, 31))
+	e.EndDate = ptr(day(2025, 12, 31))
+	e.Pauses = []billing.Range{
+		{From: day(2025, 2, 1), To: ptr(day(2025, 2, 1))},
+		{From: day(2025, 4, 1), To: ptr(day(2025, 5, 1))},
+		{From: day(2025, 9, 1)},
+	}
+	lines := render(t, e)
+
+	if got := property(lines, "RRULE"); len(got) != 1 || got[0] != ":FREQ=MONTHLY;BYMONTHDAY=-1;UNTIL=20250831" {
+		t.Errorf("RRULE = %v, want renewals until the day before the ongoing pause", got)
+	}
+	if got := property(lines, "EXDATE"); len(got) != 1 || got[0] != ";VALUE=DATE:20250228,20250430,20250531" {
+		t.Errorf("EXDATE = %v, want the renewals in finished pauses on their clamped days", got)
+	}
+	if got := property(lines, "DTSTART"); len(got) != 2 || got[1] != ";VALUE=DATE:20251231" {
+		t.Errorf("DTSTART = %v, want an expiration event on the end date", got)
+	}
+}
+
+func TestWriteICSPausedFromStart(t *testing.T) {
+	e := entry(billing.IntervalMonth, 1, day(2025, 3, 10))
+	e.Pauses = []billing.Range{{From: day(2025, 3, 1)}}
+	lines := render(t, e)
+
+	if got := property(lines, "RRULE"); len(got) != 0 {
+		t.Errorf("RRULE = %v, want no renewal event", got)
+	}
+}
@@ -0,0 +1,99 @@
+package dates
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// MonthLayout is the legacy month-precision format.
+	MonthLayout = "01-2006"
+	// DayLayout is the ISO-8601 calendar date format.
+	DayLayout = "2006-01-02"
+)
+
+// MonthStart truncates t to the first day of its month.
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthEnd returns the last day of t's month.
+func MonthEnd(t time.Time) time.Time {
+	return MonthStart(t).AddDate(0, 1, -1)
+}
+
+// AddMonths returns the same day of the month n months after t. Days that do
+// not exist in the target month, such as the 31st in a shorter month, fall on
+// its last day.
+func AddMonths(t time.Time, n int) time.Time {
+	month := MonthStart(t).AddDate(0, n, 0)
+	last := MonthEnd(month)
+	if t.Day() > last.Day() {
+		return last
+	}
+	return month.AddDate(0, 0, t.Day()-1)
+}
+
+// Day truncates t to midnight UTC of its calendar day.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ParseStart parses the first day of a range. A month without a day
+// (MM-YYYY) starts on its first day.
+func ParseStart(s string) (time.Time, error) {
+	t, monthOnly, err := parse(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if monthOnly {
+		return MonthStart(t), nil
+	}
+	return t, nil
+}
+
+// ParseEnd parses the last day of a range, inclusive. A month without a day
+// (MM-YYYY) lasts until its last day.
+func ParseEnd(s string) (time.Time, error) {
+	t, monthOnly, err := parse(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if monthOnly {
+		return MonthEnd(t), nil
+	}
+	return t, nil
+}
+
+// FormatStart renders the first day of a range, using MM-YYYY when it falls
+// on the first of a month so month-precision values round-trip unchanged.
+func FormatStart(t time.Time) string {
+	if t.Day() == 1 {
+		return t.Format(MonthLayout)
+	}
+	return t.Format(DayLayout)
+}
+
+// FormatEnd renders the last day of a range, using MM-YYYY when it falls on
+// the last day of a month.
+func FormatEnd(t time.Time) string {
+	if t.Equal(MonthEnd(t)) {
+		return t.Format(MonthLayout)
+	}
+	return t.Format(DayLayout)
+}
+
+// parse accepts YYYY-MM-DD, an RFC 3339 timestamp (only its date is kept) or
+// MM-YYYY, and reports whether the value had month precision.
+func parse(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(MonthLayout, s); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(DayLayout, s); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return Day(t), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or MM-YYYY", s)
+}
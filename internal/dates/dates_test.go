@@ -0,0 +1,120 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseStartAndEnd(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{in: "07-2025", wantStart: day(2025, 7, 1), wantEnd: day(2025, 7, 31)},
+		{in: "02-2024", wantStart: day(2024, 2, 1), wantEnd: day(2024, 2, 29)},
+		{in: "02-2025", wantStart: day(2025, 2, 1), wantEnd: day(2025, 2, 28)},
+		{in: "2025-07-15", wantStart: day(2025, 7, 15), wantEnd: day(2025, 7, 15)},
+		{in: "2025-07-01", wantStart: day(2025, 7, 1), wantEnd: day(2025, 7, 1)},
+		{in: "2025-07-15T23:30:00+03:00", wantStart: day(2025, 7, 15), wantEnd: day(2025, 7, 15)},
+		{in: "", wantErr: true},
+		{in: "7-2025", wantErr: true},
+		{in: "13-2025", wantErr: true},
+		{in: "2025-02-30", wantErr: true},
+		{in: "15.07.2025", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			start, err := ParseStart(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseStart(%q) = %v, want an error", tt.in, start)
+				}
+				if end, err := ParseEnd(tt.in); err == nil {
+					t.Errorf("ParseEnd(%q) = %v, want an error", tt.in, end)
+				}
+				return
+			}
+			if err != nil || !start.Equal(tt.wantStart) {
+				t.Errorf("ParseStart(%q) = %v, %v; want %v", tt.in, start, err, tt.wantStart)
+			}
+			if end, err := ParseEnd(tt.in); err != nil || !end.Equal(tt.wantEnd) {
+				t.Errorf("ParseEnd(%q) = %v, %v; want %v", tt.in, end, err, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		t         time.Time
+		wantStart string
+		wantEnd   string
+	}{
+		{t: day(2025, 7, 1), wantStart: "07-2025", wantEnd: "2025-07-01"},
+		{t: day(2025, 7, 31), wantStart: "2025-07-31", wantEnd: "07-2025"},
+		{t: day(2025, 7, 15), wantStart: "2025-07-15", wantEnd: "2025-07-15"},
+		{t: day(2024, 2, 29), wantStart: "2024-02-29", wantEnd: "02-2024"},
+		{t: day(2025, 2, 28), wantStart: "2025-02-28", wantEnd: "02-2025"},
+		{t: day(2024, 2, 28), wantStart: "2024-02-28", wantEnd: "2024-02-28"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatStart(tt.t); got != tt.wantStart {
+			t.Errorf("FormatStart(%v) = %q, want %q", tt.t, got, tt.wantStart)
+		}
+		if got := FormatEnd(tt.t); got != tt.wantEnd {
+			t.Errorf("FormatEnd(%v) = %q, want %q", tt.t, got, tt.wantEnd)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, in := range []string{"07-2025", "2025-07-15", "02-2024"} {
+		start, err := ParseStart(in)
+		if err != nil {
+			t.Fatalf("ParseStart(%q) error = %v", in, err)
+		}
+		if got := FormatStart(start); got != in {
+			t.Errorf("FormatStart(ParseStart(%q)) = %q", in, got)
+		}
+		end, err := ParseEnd(in)
+		if err != nil {
+			t.Fatalf("ParseEnd(%q) error = %v", in, err)
+		}
+		if got := FormatEnd(end); got != in {
+			t.Errorf("FormatEnd(ParseEnd(%q)) = %q", in, got)
+		}
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		n    int
+		want time.Time
+	}{
+		{t: day(2025, 1, 15), n: 1, want: day(2025, 2, 15)},
+		{t: day(2025, 1, 31), n: 1, want: day(2025, 2, 28)},
+		{t: day(2024, 1, 31), n: 1, want: day(2024, 2, 29)},
+		{t: day(2025, 1, 31), n: 2, want: day(2025, 3, 31)},
+		{t: day(2025, 1, 31), n: 3, want: day(2025, 4, 30)},
+		{t: day(2025, 3, 30), n: -1, want: day(2025, 2, 28)},
+		{t: day(2024, 2, 29), n: 12, want: day(2025, 2, 28)},
+		{t: day(2024, 2, 29), n: 48, want: day(2028, 2, 29)},
+		{t: day(2025, 12, 31), n: 2, want: day(2026, 2, 28)},
+		{t: day(2025, 5, 31), n: 0, want: day(2025, 5, 31)},
+	}
+
+	for _, tt := range tests {
+		if got := AddMonths(tt.t, tt.n); !got.Equal(tt.want) {
+			t.Errorf("AddMonths(%v, %d) = %v, want %v", tt.t.Format(DayLayout), tt.n, got.Format(DayLayout), tt.want.Format(DayLayout))
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatXLSX   = "xlsx"
+	FormatNDJSON = "ndjson"
+)
+
+const (
+	MIMEJSON   = "application/json"
+	MIMECSV    = "text/csv"
+	MIMEXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MIMENDJSON = "application/x-ndjson"
+)
+
+// ContentTypes maps every export format to the Content-Type it is served with.
+var ContentTypes = map[string]string{
+	FormatJSON:   MIMEJSON,
+	FormatCSV:    MIMECSV + "; charset=utf-8",
+	FormatXLSX:   MIMEXLSX,
+	FormatNDJSON: MIMENDJSON,
+}
+
+// Table is a tabular view of exported data used by the CSV and XLSX writers.
+type Table struct {
+	Sheet  string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// Number is an exact decimal amount in a table, such as a price. CSV shows it
+// as it is, XLSX stores it as a number cell so that spreadsheets can sum and
+// sort it.
+type Number string
+
+func WriteCSV(w io.Writer, t Table) error {
+	cw, err := NewCSVWriter(w, t.Header)
+	if err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return cw.Flush()
+}
+
+// CSVWriter writes CSV rows one at a time for streaming exports.
+type CSVWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func NewCSVWriter(w io.Writer, header []string) (*CSVWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	return &CSVWriter{writer: writer, record: make([]string, len(header))}, nil
+}
+
+func (cw *CSVWriter) Write(row []interface{}) error {
+	record := cw.record[:0]
+	for _, value := range row {
+		if text, ok := value.(string); ok {
+			record = append(record, escapeFormula(text))
+		} else {
+			record = append(record, fmt.Sprint(value))
+		}
+	}
+	return cw.writer.Write(record)
+}
+
+// escapeFormula prefixes text that spreadsheets would run as a formula with a
+// quote, so that user input such as a service name is shown as it is. Leading
+// tabs and carriage returns are escaped too, as spreadsheets may skip them
+// before looking for a formula.
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func (cw *CSVWriter) Flush() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+func WriteXLSX(w io.Writer, t Table) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := t.Sheet
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	stream, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(t.Header))
+	for i, column := range t.Header {
+		header[i] = column
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	for i, row := range t.Rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := stream.SetRow(cell, xlsxRow(row)); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// xlsxRow converts the numbers of a row to float64, which excelize writes as
+// number cells. An empty number is left as an empty cell.
+func xlsxRow(row []interface{}) []interface{} {
+	cells := make([]interface{}, len(row))
+	for i, value := range row {
+		cells[i] = value
+		number, ok := value.(Number)
+		if !ok {
+			continue
+		}
+		if f, err := strconv.ParseFloat(string(number), 64); err == nil {
+			cells[i] = f
+		} else {
+			cells[i] = string(number)
+		}
+	}
+	return cells
+}
+
+// WriteNDJSON writes every item as a JSON document on its own line.
+func WriteNDJSON[T any](w io.Writer, items []T) error {
+	encoder := json.NewEncoder(w)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
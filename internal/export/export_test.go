@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: ""},
+		{text: "Netflix", want: "Netflix"},
+		{text: "=HYPERLINK(\"http://x\")", want: "'=HYPERLINK(\"http://x\")"},
+		{text: "+1", want: "'+1"},
+		{text: "-1", want: "'-1"},
+		{text: "@SUM(A1)", want: "'@SUM(A1)"},
+		{text: "\t=1+1", want: "'\t=1+1"},
+		{text: "\r=1+1", want: "'\r=1+1"},
+		{text: "a=b", want: "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeFormula(tt.text); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, Table{
+		Header: []string{"service_name", "price", "billing_interval_count"},
+		Rows: [][]interface{}{
+			{"=cmd|' /C calc'!A0", "-5.00", 1},
+			{"Yandex Plus, family", "399.90", 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "service_name,price,billing_interval_count\n" +
+		"'=cmd|' /C calc'!A0,'-5.00,1\n" +
+		"\"Yandex Plus, family\",399.90,3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteCSVNumbers(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, Table{
+		Header: []string{"price"},
+		Rows:   [][]interface{}{{Number("399.90")}},
+	})
+	if err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	if got, want := buf.String(), "price\n399.90\n"; got != want {
+		t.Errorf("WriteCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteXLSXNumbers(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXLSX(&buf, Table{
+		Sheet:  "Subscriptions",
+		Header: []string{"service_name", "price", "billing_interval_count"},
+		Rows: [][]interface{}{
+			{"Yandex Plus", Number("399.90"), 1},
+			{"Netflix", Number("9.99"), 12},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	defer f.Close()
+
+	for _, cell := range []string{"B2", "B3", "C2"} {
+		cellType, err := f.GetCellType("Subscriptions", cell)
+		if err != nil {
+			t.Fatalf("GetCellType(%s) error = %v", cell, err)
+		}
+		if cellType != excelize.CellTypeNumber && cellType != excelize.CellTypeUnset {
+			t.Errorf("cell %s has type %v, want a number", cell, cellType)
+		}
+	}
+	sum, err := f.CalcCellValue("Subscriptions", "B2")
+	if err != nil {
+		t.Fatalf("CalcCellValue() error = %v", err)
+	}
+	if sum != "399.9" {
+		t.Errorf("B2 = %q, want 399.9", sum)
+	}
+	if name, _ := f.GetCellType("Subscriptions", "A2"); name != excelize.CellTypeSharedString && name != excelize.CellTypeInlineString {
+		t.Errorf("A2 has type %v, want text", name)
+	}
+}
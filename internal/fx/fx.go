@@ -0,0 +1,84 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/money"
+)
+
+// Base is the currency rates are quoted in. It always converts at 1.
+const Base = "RUB"
+
+var ErrNoRate = errors.New("exchange rate not found")
+
+var codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidCode reports whether code looks like an ISO 4217 currency code.
+func ValidCode(code string) bool {
+	return codePattern.MatchString(code)
+}
+
+// Rate is the price of one major unit of Currency in major units of the base
+// currency, in effect from Date until the next rate for the same currency.
+type Rate struct {
+	Currency string
+	Date     time.Time
+	Rate     *big.Rat
+}
+
+// Table looks up the rate in effect on a given day.
+type Table struct {
+	rates map[string][]Rate
+}
+
+func NewTable(rates []Rate) *Table {
+	t := &Table{rates: make(map[string][]Rate)}
+	for _, rate := range rates {
+		t.rates[rate.Currency] = append(t.rates[rate.Currency], rate)
+	}
+	for _, list := range t.rates {
+		sort.Slice(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+	}
+	return t
+}
+
+// Rate returns the rate of currency in effect on day: the latest one whose
+// effective date is not after it.
+func (t *Table) Rate(currency string, day time.Time) (*big.Rat, error) {
+	if currency == Base {
+		return big.NewRat(1, 1), nil
+	}
+
+	list := t.rates[currency]
+	i := sort.Search(len(list), func(i int) bool { return list[i].Date.After(day) })
+	if i == 0 {
+		return nil, fmt.Errorf("%w: %s on %s", ErrNoRate, currency, day.Format(dates.DayLayout))
+	}
+	return list[i-1].Rate, nil
+}
+
+// Factor returns what one minor unit of from is worth in minor units of to on
+// day. The result is exact; callers round once the amount is final.
+func (t *Table) Factor(from, to string, day time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	fromRate, err := t.Rate(from, day)
+	if err != nil {
+		return nil, err
+	}
+	toRate, err := t.Rate(to, day)
+	if err != nil {
+		return nil, err
+	}
+
+	factor := new(big.Rat).Quo(fromRate, toRate)
+	factor.Mul(factor, money.Scale(money.Exponent(to)))
+	return factor.Quo(factor, money.Scale(money.Exponent(from))), nil
+}
@@ -1,26 +1,61 @@
 package handlers
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/export"
+	"github.com/ItserX/rest/internal/fx"
 	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/notify"
 	"github.com/ItserX/rest/internal/storage"
 	"github.com/ItserX/rest/internal/types"
 )
 
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
 type Handler struct {
-	Repo storage.PostRepository
+	Repo           storage.PostRepository
+	IdempotencyTTL time.Duration
+	MaxBatchSize   int
+	// Notifier sends budget alerts to users; nil sends nothing.
+	Notifier *notify.Dispatcher
+}
+
+// RequestPath is the path of the request as it is logged. Calendar feed
+// paths carry a secret token, so they are logged as their route.
+func RequestPath(c *gin.Context) string {
+	if c.Param("token") != "" {
+		return c.FullPath()
+	}
+	return c.Request.URL.Path
 }
 
 func (h *Handler) logStart(c *gin.Context) {
 	logger.Logger.Infow("Request started",
 		"method", c.Request.Method,
-		"path", c.Request.URL.Path,
+		"path", RequestPath(c),
 	)
 }
 
@@ -28,7 +63,7 @@ func (h *Handler) logSuccess(c *gin.Context, msg string, code int, keysAndValues
 	logger.Logger.Infow(msg,
 		append([]interface{}{
 			"method", c.Request.Method,
-			"path", c.Request.URL.Path,
+			"path", RequestPath(c),
 			"code", code,
 		}, keysAndValues...)...,
 	)
@@ -38,7 +73,7 @@ func (h *Handler) logError(c *gin.Context, err error, code int, keysAndValues ..
 	logger.Logger.Errorw(err.Error(),
 		append([]interface{}{
 			"method", c.Request.Method,
-			"path", c.Request.URL.Path,
+			"path", RequestPath(c),
 			"code", code,
 			"error", err,
 		}, keysAndValues...)...,
@@ -51,7 +86,10 @@ func (h *Handler) logError(c *gin.Context, err error, code int, keysAndValues ..
 // @Accept json
 // @Produce json
 // @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param If-None-Match header string false "ETag известной клиенту версии" example("\"3\"")
 // @Success 200 {object} types.Subscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Success 304 "Подписка не изменилась"
 // @Failure 400 {object} types.InvalidIDErrorResponse
 // @Failure 404 {object} types.NotFoundErrorResponse
 // @Failure 500 {object} types.FailedToGetSubErrorResponse
@@ -78,23 +116,44 @@ func (h *Handler) GetSub(c *gin.Context) {
 		return
 	}
 
+	etag := formatETag(sub.Version)
+	c.Header(etagHeader, etag)
+	if ifNoneMatch := c.GetHeader(ifNoneMatchHeader); ifNoneMatch != "" && etagMatches(ifNoneMatch, sub.Version) {
+		h.logSuccess(c, "Subscription not modified", http.StatusNotModified, "id", id, "etag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	h.logSuccess(c, "Subscription retrieved", http.StatusOK, "id", id)
 	c.JSON(http.StatusOK, sub)
 }
 
 // @Summary Создать новую подписку
-// @Description Создать новую подписку с указанными параметрами
+// @Description Создать новую подписку с указанными параметрами. Владелец подписки должен быть зарегистрирован
 // @Tags Подписки
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Ключ идемпотентности для безопасных повторов запроса" example("5b1f8a4e-create-netflix")
 // @Param subscription body types.Subscription true "Данные подписки"
 // @Success 201 {object} types.CreatedResponse
 // @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 400 {object} types.InvalidIdempotencyKeyErrorResponse
+// @Failure 400 {object} types.UnknownServiceErrorResponse
+// @Failure 400 {object} types.UnknownUserErrorResponse
+// @Failure 409 {object} types.IdempotencyConflictErrorResponse
 // @Failure 500 {object} types.FailedToCreateErrorResponse
 // @Router /subscriptions [post]
 func (h *Handler) CreateSub(c *gin.Context) {
 	h.logStart(c)
 
+	idempotencyKey := c.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("idempotency key exceeds %d characters", maxIdempotencyKeyLength)
+		h.logError(c, err, http.StatusBadRequest, "operation", "idempotency key validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid Idempotency-Key header"})
+		return
+	}
+
 	var sub types.Subscription
 	err := c.ShouldBindJSON(&sub)
 	if err != nil {
@@ -103,7 +162,30 @@ func (h *Handler) CreateSub(c *gin.Context) {
 		return
 	}
 
-	subID, err := h.Repo.Create(sub)
+	var (
+		subID    uuid.UUID
+		replayed bool
+	)
+	if idempotencyKey == "" {
+		subID, err = h.Repo.Create(sub)
+	} else {
+		subID, replayed, err = h.Repo.CreateIdempotent(sub, idempotencyKey, fingerprint(sub), h.IdempotencyTTL)
+	}
+	if errors.Is(err, storage.ErrIdempotencyMismatch) {
+		h.logError(c, err, http.StatusConflict, "operation", "CreateIdempotent", "idempotency_key", idempotencyKey)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Idempotency-Key already used with a different request"})
+		return
+	}
+	if errors.Is(err, storage.ErrServiceNotFound) {
+		h.logError(c, err, http.StatusBadRequest, "operation", "Create", "subscription", sub)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unknown service_id"})
+		return
+	}
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusBadRequest, "operation", "Create", "subscription", sub)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unknown user_id"})
+		return
+	}
 	if err != nil {
 		h.logError(c, err, http.StatusInternalServerError, "operation", "Create", "subscription", sub)
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create subscription"})
@@ -111,21 +193,32 @@ func (h *Handler) CreateSub(c *gin.Context) {
 	}
 
 	subIDStr := fmt.Sprintf("%v", subID)
-	h.logSuccess(c, "Subscription created", http.StatusCreated, "sub_id", subIDStr, "subscription", sub)
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+		h.logSuccess(c, "Subscription creation replayed", http.StatusCreated, "sub_id", subIDStr, "idempotency_key", idempotencyKey)
+	} else {
+		h.logSuccess(c, "Subscription created", http.StatusCreated, "sub_id", subIDStr, "subscription", sub)
+	}
 	c.JSON(http.StatusCreated, types.CreatedResponse{SubID: subIDStr})
 }
 
 // @Summary Обновить подписку
-// @Description Обновить существующую подписку по ID
+// @Description Обновить существующую подписку по ID. Новая цена начавшейся подписки действует с текущего месяца, прошлые месяцы сохраняют свои цены. Валюту можно сменить только до первого оплаченного месяца
 // @Tags Подписки
 // @Accept json
 // @Produce json
 // @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param If-Match header string false "ETag версии, которую изменяет клиент" example("\"3\"")
 // @Param subscription body types.Subscription true "Обновленные данные подписки"
 // @Success 200 {object} types.IDResponse
+// @Header 200 {string} ETag "Новая версия подписки"
 // @Failure 400 {object} types.InvalidIDErrorResponse
 // @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 400 {object} types.UnknownServiceErrorResponse
 // @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 409 {object} types.CancelledEndDateErrorResponse
+// @Failure 409 {object} types.CurrencyChangeErrorResponse
+// @Failure 412 {object} types.PreconditionFailedErrorResponse
 // @Failure 500 {object} types.FailedToUpdateSub
 // @Router /subscriptions/{id} [put]
 func (h *Handler) UpdateSub(c *gin.Context) {
@@ -146,19 +239,171 @@ func (h *Handler) UpdateSub(c *gin.Context) {
 		return
 	}
 
-	err = h.Repo.Update(id, sub)
+	ifMatch := c.GetHeader(ifMatchHeader)
+	version, err := h.Repo.Update(id, sub, parseIfMatch(ifMatch))
 	if errors.Is(err, storage.ErrNotFound) {
 		h.logError(c, err, http.StatusNotFound, "operation", "Update", "id", id, "subscription", sub)
 		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
 		return
 	}
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		h.logError(c, err, http.StatusPreconditionFailed, "operation", "Update", "id", id, "if_match", ifMatch)
+		c.JSON(http.StatusPreconditionFailed, types.ErrorResponse{Error: "Subscription was modified by another request"})
+		return
+	}
+	if errors.Is(err, storage.ErrServiceNotFound) {
+		h.logError(c, err, http.StatusBadRequest, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unknown service_id"})
+		return
+	}
+	if errors.Is(err, storage.ErrCurrencyChange) {
+		h.logError(c, err, http.StatusConflict, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Currency of a subscription with billing history cannot change"})
+		return
+	}
+	if errors.Is(err, storage.ErrEndDateRequired) {
+		h.logError(c, err, http.StatusConflict, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Cancelled subscription must keep an end date, reactivate it instead"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update subscription"})
+		return
+	}
+
+	c.Header(etagHeader, formatETag(version))
+	h.logSuccess(c, "Subscription updated", http.StatusOK, "id", id, "version", version)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Частично обновить подписку
+// @Description Изменить отдельные поля подписки с помощью JSON Merge Patch (RFC 7396) или JSON Patch (RFC 6902). В merge patch значение null очищает поле, например end_date
+// @Tags Подписки
+// @Accept application/merge-patch+json,application/json-patch+json,json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param If-Match header string false "ETag версии, которую изменяет клиент" example("\"3\"")
+// @Param patch body types.SubscriptionMergePatch true "Изменяемые поля подписки"
+// @Success 200 {object} types.IDResponse
+// @Header 200 {string} ETag "Новая версия подписки"
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidPatchErrorResponse
+// @Failure 400 {object} types.UnknownServiceErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 409 {object} types.PatchTestFailedErrorResponse
+// @Failure 409 {object} types.CancelledEndDateErrorResponse
+// @Failure 409 {object} types.CurrencyChangeErrorResponse
+// @Failure 412 {object} types.PreconditionFailedErrorResponse
+// @Failure 415 {object} types.UnsupportedPatchTypeErrorResponse
+// @Failure 500 {object} types.FailedToUpdateSub
+// @Router /subscriptions/{id} [patch]
+func (h *Handler) PatchSub(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "GetRawData")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	current, err := h.Repo.Get(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "GetByID", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "GetByID", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update subscription"})
+		return
+	}
+
+	ifMatch := c.GetHeader(ifMatchHeader)
+	if versions := parseIfMatch(ifMatch); versions != nil && !slices.Contains(versions, int64(current.Version)) {
+		err := storage.ErrVersionMismatch
+		h.logError(c, err, http.StatusPreconditionFailed, "operation", "If-Match", "id", id, "if_match", ifMatch)
+		c.JSON(http.StatusPreconditionFailed, types.ErrorResponse{Error: "Subscription was modified by another request"})
+		return
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "Marshal", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update subscription"})
+		return
+	}
+
+	patched, err := applyPatch(c.ContentType(), original, patch)
+	if errors.Is(err, errUnsupportedPatchType) {
+		h.logError(c, err, http.StatusUnsupportedMediaType, "operation", "applyPatch", "content_type", c.ContentType())
+		c.JSON(http.StatusUnsupportedMediaType, types.ErrorResponse{Error: "Unsupported patch content type"})
+		return
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		h.logError(c, err, http.StatusConflict, "operation", "applyPatch", "id", id)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Patch test operation failed"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "applyPatch", "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid patch document"})
+		return
+	}
+
+	var sub types.Subscription
+	err = json.Unmarshal(patched, &sub)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(&sub)
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "validate patched subscription", "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid patch document"})
+		return
+	}
+
+	version, err := h.Repo.Update(id, sub, []int64{int64(current.Version)})
+	if errors.Is(err, storage.ErrNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
+		return
+	}
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		h.logError(c, err, http.StatusPreconditionFailed, "operation", "Update", "id", id, "version", current.Version)
+		c.JSON(http.StatusPreconditionFailed, types.ErrorResponse{Error: "Subscription was modified by another request"})
+		return
+	}
+	if errors.Is(err, storage.ErrServiceNotFound) {
+		h.logError(c, err, http.StatusBadRequest, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unknown service_id"})
+		return
+	}
+	if errors.Is(err, storage.ErrCurrencyChange) {
+		h.logError(c, err, http.StatusConflict, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Currency of a subscription with billing history cannot change"})
+		return
+	}
+	if errors.Is(err, storage.ErrEndDateRequired) {
+		h.logError(c, err, http.StatusConflict, "operation", "Update", "id", id, "subscription", sub)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Cancelled subscription must keep an end date, reactivate it instead"})
+		return
+	}
 	if err != nil {
 		h.logError(c, err, http.StatusInternalServerError, "operation", "Update", "id", id, "subscription", sub)
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update subscription"})
 		return
 	}
 
-	h.logSuccess(c, "Subscription updated", http.StatusOK, "id", id)
+	c.Header(etagHeader, formatETag(version))
+	h.logSuccess(c, "Subscription patched", http.StatusOK, "id", id, "version", version)
 	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
 }
 
@@ -168,9 +413,11 @@ func (h *Handler) UpdateSub(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param If-Match header string false "ETag версии, которую удаляет клиент" example("\"3\"")
 // @Success 200 {object} types.IDResponse
 // @Failure 400 {object} types.InvalidIDErrorResponse
 // @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 412 {object} types.PreconditionFailedErrorResponse
 // @Failure 500 {object} types.FailedToDeleteErrorResponse
 // @Router /subscriptions/{id} [delete]
 func (h *Handler) DeleteSub(c *gin.Context) {
@@ -183,12 +430,18 @@ func (h *Handler) DeleteSub(c *gin.Context) {
 		return
 	}
 
-	err = h.Repo.Delete(id)
+	ifMatch := c.GetHeader(ifMatchHeader)
+	err = h.Repo.Delete(id, parseIfMatch(ifMatch))
 	if errors.Is(err, storage.ErrNotFound) {
 		h.logError(c, err, http.StatusNotFound, "operation", "Delete", "id", id)
 		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
 		return
 	}
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		h.logError(c, err, http.StatusPreconditionFailed, "operation", "Delete", "id", id, "if_match", ifMatch)
+		c.JSON(http.StatusPreconditionFailed, types.ErrorResponse{Error: "Subscription was modified by another request"})
+		return
+	}
 	if err != nil {
 		h.logError(c, err, http.StatusInternalServerError, "operation", "Delete", "id", id)
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to delete subscription"})
@@ -200,23 +453,68 @@ func (h *Handler) DeleteSub(c *gin.Context) {
 }
 
 // @Summary Получить список подписок
-// @Description Получить список всех подписок
+// @Description Получить список всех подписок с возможностью фильтрации. Формат ответа выбирается параметром format или заголовком Accept: JSON, CSV, XLSX или NDJSON
 // @Tags Подписки
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Param user_id query string false "ID пользователя для фильтрации" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param service_name query string false "Название сервиса для фильтрации" example("Yandex")
+// @Param category query string false "Категория для фильтрации" example("music")
+// @Param tag query []string false "Теги для фильтрации, подписка должна иметь все указанные" collectionFormat(multi)
+// @Param format query string false "Формат ответа. CSV и NDJSON передаются потоком по мере чтения из базы" Enums(json, csv, xlsx, ndjson)
+// @Param stream query bool false "Передавать JSON потоком, не накапливая весь список в памяти" example(true)
 // @Success 200 {object} types.ListSubscriptionsResponse
+// @Failure 400 {object} types.InvalidUserIDErrorResponse
+// @Failure 400 {object} types.UnsupportedFormatErrorResponse
 // @Failure 500 {object} types.FailedToListSubsErrorResponse
 // @Router /subscriptions [get]
 func (h *Handler) ListSubs(c *gin.Context) {
 	h.logStart(c)
 
-	subs, err := h.Repo.List()
+	format, err := negotiateFormat(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "negotiateFormat")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unsupported format"})
+		return
+	}
+
+	filter := storage.SubscriptionFilter{
+		ServiceName: c.Query("service_name"),
+		Category:    c.Query("category"),
+		Tags:        c.QueryArray("tag"),
+	}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		filter.UserID, err = uuid.Parse(userIDStr)
+		if err != nil {
+			h.logError(c, err, http.StatusBadRequest, "operation", "parse user_id")
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user_id format"})
+			return
+		}
+	}
+
+	stream, _ := strconv.ParseBool(c.Query("stream"))
+	if format == export.FormatCSV || format == export.FormatNDJSON || (format == export.FormatJSON && stream) {
+		h.streamSubs(c, format, filter)
+		return
+	}
+
+	subs, err := h.Repo.List(filter)
 	if err != nil {
 		h.logError(c, err, http.StatusInternalServerError, "operation", "List")
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list subscriptions"})
 		return
 	}
 
+	if format != export.FormatJSON {
+		err = writeExport(c, format, "subscriptions", subscriptionsTable(subs), subs)
+		if err != nil {
+			h.logError(c, err, http.StatusInternalServerError, "operation", "writeExport", "format", format)
+			return
+		}
+		h.logSuccess(c, "Subscriptions exported", http.StatusOK, "count", len(subs), "format", format)
+		return
+	}
+
 	h.logSuccess(c, "All subscriptions listed", http.StatusOK, "count", len(subs))
 	c.JSON(http.StatusOK, types.ListSubscriptionsResponse{
 		Subscriptions: subs,
@@ -225,17 +523,29 @@ func (h *Handler) ListSubs(c *gin.Context) {
 }
 
 // @Summary Рассчитать общую стоимость
-// @Description Рассчитать общую стоимость подписок с возможностью фильтрации
+// @Description Рассчитать общую стоимость подписок с возможностью фильтрации: учитываются месяцы периода, в которых подписка действует и не приостановлена, с учетом расчетного периода подписки. При фильтре по пользователю совместные подписки учитываются в размере его доли. Формат ответа выбирается параметром format или заголовком Accept
 // @Tags Подписки
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
 // @Param user_id query string false "ID пользователя для фильтрации" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
 // @Param service_name query string false "Название сервиса для фильтрации" example("Yandex")
-// @Param period_start query string true "Начальный период в формате ММ-ГГГГ" example("07-2025")
-// @Param period_end query string false "Конечный период в формате ММ-ГГГГ" example("12-2025")
+// @Param category query string false "Категория для фильтрации" example("music")
+// @Param tag query []string false "Теги для фильтрации, подписка должна иметь все указанные" collectionFormat(multi)
+// @Param period_start query string true "Начало периода в формате ГГГГ-ММ-ДД или ММ-ГГГГ" example("07-2025")
+// @Param period_end query string false "Конец периода включительно в формате ГГГГ-ММ-ДД или ММ-ГГГГ. По умолчанию текущий месяц или period_start, если он позже" example("12-2025")
+// @Param prorate query bool false "Учитывать неполные месяцы пропорционально числу дней вместо целых месяцев" example(true)
+// @Param currency query string false "Валюта расчета (по умолчанию RUB). Цены пересчитываются по курсу, действующему на начало каждого месяца" example("USD")
+// @Param view query string false "Представление: accrual распределяет цену периода оплаты по месяцам, cash относит списание на месяц даты оплаты" Enums(accrual, cash) default(accrual)
+// @Param group_by query string false "Добавить к сумме разбивку: category — стоимость и доля каждой категории" Enums(category)
+// @Param format query string false "Формат ответа: json возвращает сумму, csv, xlsx и ndjson — расшифровку по подпискам" Enums(json, csv, xlsx, ndjson)
 // @Success 200 {object} types.TotalCostResponse
 // @Failure 400 {object} types.PeriodStartRequiredErrorResponse
 // @Failure 400 {object} types.InvalidUserIDErrorResponse
+// @Failure 400 {object} types.InvalidViewErrorResponse
+// @Failure 400 {object} types.InvalidCurrencyErrorResponse
+// @Failure 400 {object} types.InvalidGroupByErrorResponse
+// @Failure 400 {object} types.UnsupportedFormatErrorResponse
+// @Failure 422 {object} types.MissingExchangeRateErrorResponse
 // @Failure 500 {object} types.FailedToCalculateErrorResponse
 // @Router /subscriptions/totalCost [get]
 func (h *Handler) GetTotalCost(c *gin.Context) {
@@ -245,15 +555,15 @@ func (h *Handler) GetTotalCost(c *gin.Context) {
 	serviceName := c.Query("service_name")
 	periodStart := c.Query("period_start")
 	periodEnd := c.Query("period_end")
-	if periodEnd == "" {
-		periodEnd = "12-2100"
-	}
 	if periodStart == "" {
 		err := fmt.Errorf("period_start are required")
 		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
 		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "period_start are required"})
 		return
 	}
+	if periodEnd == "" {
+		periodEnd = defaultPeriodEnd(periodStart, time.Now())
+	}
 
 	var userID uuid.UUID
 	var err error
@@ -266,7 +576,99 @@ func (h *Handler) GetTotalCost(c *gin.Context) {
 		}
 	}
 
-	total, err := h.Repo.GetTotalCost(userID, serviceName, periodStart, periodEnd)
+	prorate, _ := strconv.ParseBool(c.Query("prorate"))
+
+	view := c.DefaultQuery("view", billing.ViewAccrual)
+	if !billing.ValidView(view) {
+		err := fmt.Errorf("unknown view %q", view)
+		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid view"})
+		return
+	}
+
+	currency := strings.ToUpper(c.DefaultQuery("currency", fx.Base))
+	if !fx.ValidCode(currency) {
+		err := fmt.Errorf("invalid currency %q", currency)
+		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid currency"})
+		return
+	}
+
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != groupByCategory {
+		err := fmt.Errorf("unknown group_by %q", groupBy)
+		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid group_by"})
+		return
+	}
+
+	format, err := negotiateFormat(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "negotiateFormat")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unsupported format"})
+		return
+	}
+
+	query := storage.CostQuery{
+		Filter: storage.SubscriptionFilter{
+			UserID:      userID,
+			ServiceName: serviceName,
+			Category:    c.Query("category"),
+			Tags:        c.QueryArray("tag"),
+		},
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		View:        view,
+		Prorate:     prorate,
+		Currency:    currency,
+	}
+
+	if format != export.FormatJSON {
+		items, err := h.Repo.CostBreakdown(query)
+		if errors.Is(err, fx.ErrNoRate) {
+			h.logError(c, err, http.StatusUnprocessableEntity, "operation", "CostBreakdown")
+			c.JSON(http.StatusUnprocessableEntity, types.ErrorResponse{Error: "Exchange rate not found"})
+			return
+		}
+		if err != nil {
+			h.logError(c, err, http.StatusInternalServerError, "operation", "CostBreakdown")
+			c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to calculate total cost"})
+			return
+		}
+
+		table, err := costTable(items, currency)
+		if err != nil {
+			h.logError(c, err, http.StatusInternalServerError, "operation", "costTable")
+			c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to calculate total cost"})
+			return
+		}
+		err = writeExport(c, format, "total-cost", table, items)
+		if err != nil {
+			h.logError(c, err, http.StatusInternalServerError, "operation", "writeExport", "format", format)
+			return
+		}
+		h.logSuccess(c, "Total cost exported", http.StatusOK, "count", len(items), "format", format)
+		return
+	}
+
+	var (
+		total      money.Money
+		byCategory []types.CategoryCost
+	)
+	if groupBy == groupByCategory {
+		var items []types.CostItem
+		items, err = h.Repo.CostBreakdown(query)
+		if err == nil {
+			total, byCategory, err = categoryCosts(items, currency)
+		}
+	} else {
+		total, err = h.Repo.GetTotalCost(query)
+	}
+	if errors.Is(err, fx.ErrNoRate) {
+		h.logError(c, err, http.StatusUnprocessableEntity, "operation", "CalculateTotalCost")
+		c.JSON(http.StatusUnprocessableEntity, types.ErrorResponse{Error: "Exchange rate not found"})
+		return
+	}
 	if err != nil {
 		h.logError(c, err, http.StatusInternalServerError, "operation", "CalculateTotalCost")
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to calculate total cost"})
@@ -278,9 +680,17 @@ func (h *Handler) GetTotalCost(c *gin.Context) {
 		"serviceName", serviceName,
 		"periodStart", periodStart,
 		"periodEnd", periodEnd,
-		"total", total,
+		"view", view,
+		"prorate", prorate,
+		"currency", currency,
+		"groupBy", groupBy,
+		"total", total.String(),
 	)
-	c.JSON(http.StatusOK, types.TotalCostResponse{TotalCost: total})
+	c.JSON(http.StatusOK, types.TotalCostResponse{
+		TotalCost:  json.Number(total.Decimal()),
+		Currency:   total.Currency,
+		ByCategory: byCategory,
+	})
 }
 
 func getID(c *gin.Context) (uuid.UUID, error) {
@@ -291,3 +701,59 @@ func getID(c *gin.Context) (uuid.UUID, error) {
 	}
 	return id, nil
 }
+
+func fingerprint(sub types.Subscription) string {
+	payload, _ := json.Marshal(sub)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// defaultPeriodEnd bounds open cost periods by the current month, so that
+// open-ended subscriptions are not charged for months that have not come yet.
+func defaultPeriodEnd(periodStart string, now time.Time) string {
+	start, err := dates.ParseStart(periodStart)
+	if err != nil || start.After(now) {
+		return periodStart
+	}
+	return now.Format(dates.MonthLayout)
+}
+
+const groupByCategory = "category"
+
+// categoryCosts adds up the breakdown per category, most expensive first, and
+// returns the total along with it.
+func categoryCosts(items []types.CostItem, currency string) (money.Money, []types.CategoryCost, error) {
+	type category struct {
+		name string
+		cost money.Money
+	}
+
+	total := money.New(0, currency)
+	var categories []category
+	for _, item := range items {
+		var err error
+		if total, err = total.Add(item.Cost); err != nil {
+			return money.Money{}, nil, err
+		}
+		i := slices.IndexFunc(categories, func(c category) bool { return c.name == item.Category })
+		if i < 0 {
+			categories = append(categories, category{name: item.Category, cost: money.New(0, currency)})
+			i = len(categories) - 1
+		}
+		if categories[i].cost, err = categories[i].cost.Add(item.Cost); err != nil {
+			return money.Money{}, nil, err
+		}
+	}
+
+	slices.SortStableFunc(categories, func(a, b category) int {
+		return cmp.Compare(b.cost.Amount, a.cost.Amount)
+	})
+	costs := make([]types.CategoryCost, len(categories))
+	for i, c := range categories {
+		costs[i] = types.CategoryCost{Category: c.name, Cost: json.Number(c.cost.Decimal())}
+		if total.Amount != 0 {
+			costs[i].Share = math.Round(float64(c.cost.Amount)/float64(total.Amount)*10000) / 10000
+		}
+	}
+	return total, costs, nil
+}
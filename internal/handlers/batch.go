@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Пакетная обработка подписок
+// @Description Создать, обновить и удалить несколько подписок за один запрос. В атомарном режиме все операции выполняются в одной транзакции, иначе каждая операция применяется независимо и получает собственный результат
+// @Tags Подписки
+// @Accept json
+// @Produce json
+// @Param batch body types.BatchRequest true "Пакет операций"
+// @Success 200 {object} types.BatchResponse
+// @Success 207 {object} types.BatchResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 413 {object} types.BatchTooLargeErrorResponse
+// @Failure 422 {object} types.BatchResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/batch [post]
+func (h *Handler) BatchSubs(c *gin.Context) {
+	h.logStart(c)
+
+	var req types.BatchRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	if len(req.Operations) > h.MaxBatchSize {
+		msg := fmt.Sprintf("Batch exceeds maximum size of %d operations", h.MaxBatchSize)
+		h.logError(c, errors.New(msg), http.StatusRequestEntityTooLarge, "operation", "batch size validation", "size", len(req.Operations))
+		c.JSON(http.StatusRequestEntityTooLarge, types.ErrorResponse{Error: msg})
+		return
+	}
+
+	results := make([]types.BatchResult, len(req.Operations))
+	ops := make([]storage.BatchOp, 0, len(req.Operations))
+	indexes := make([]int, 0, len(req.Operations))
+	invalid := 0
+	for i, operation := range req.Operations {
+		results[i] = types.BatchResult{Index: i, Op: operation.Op, ID: operation.ID}
+
+		op, err := toBatchOp(operation)
+		if err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			invalid++
+			continue
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	if invalid > 0 && req.Atomic {
+		for i := range results {
+			if results[i].Status == 0 {
+				results[i].Status, results[i].Error = batchStatus("", storage.ErrBatchAborted)
+			}
+		}
+		err := fmt.Errorf("%d invalid operations in atomic batch", invalid)
+		h.logError(c, err, http.StatusUnprocessableEntity, "operation", "batch validation")
+		c.JSON(http.StatusUnprocessableEntity, batchResponse(results))
+		return
+	}
+
+	outcomes, err := h.Repo.ApplyBatch(ops, req.Atomic)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ApplyBatch")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to process batch"})
+		return
+	}
+
+	for k, outcome := range outcomes {
+		i := indexes[k]
+		results[i].Status, results[i].Error = batchStatus(ops[k].Kind, outcome.Err)
+		if outcome.ID != uuid.Nil {
+			results[i].ID = outcome.ID.String()
+		}
+	}
+
+	resp := batchResponse(results)
+	code := http.StatusOK
+	if resp.Failed > 0 {
+		code = http.StatusMultiStatus
+		if req.Atomic {
+			code = http.StatusUnprocessableEntity
+		}
+	}
+
+	h.logSuccess(c, "Subscription batch processed", code,
+		"atomic", req.Atomic,
+		"succeeded", resp.Succeeded,
+		"failed", resp.Failed,
+	)
+	c.JSON(code, resp)
+}
+
+func toBatchOp(operation types.BatchOperation) (storage.BatchOp, error) {
+	op := storage.BatchOp{Kind: operation.Op}
+
+	switch operation.Op {
+	case storage.OpCreate, storage.OpUpdate, storage.OpDelete:
+	default:
+		return op, errors.New("Unknown operation")
+	}
+
+	if operation.Op != storage.OpCreate {
+		id, err := uuid.Parse(operation.ID)
+		if err != nil {
+			return op, errors.New("Invalid ID format")
+		}
+		op.ID = id
+		op.IfMatch = parseIfMatch(operation.IfMatch)
+	}
+
+	if operation.Op != storage.OpDelete {
+		if operation.Subscription == nil {
+			return op, errors.New("Subscription is required")
+		}
+		if err := binding.Validator.ValidateStruct(operation.Subscription); err != nil {
+			return op, errors.New("Invalid subscription")
+		}
+		op.Sub = *operation.Subscription
+	}
+
+	return op, nil
+}
+
+func batchStatus(kind string, err error) (int, string) {
+	switch {
+	case err == nil && kind == storage.OpCreate:
+		return http.StatusCreated, ""
+	case err == nil:
+		return http.StatusOK, ""
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound, "Subscription not found"
+	case errors.Is(err, storage.ErrVersionMismatch):
+		return http.StatusPreconditionFailed, "Subscription was modified by another request"
+	case errors.Is(err, storage.ErrServiceNotFound):
+		return http.StatusBadRequest, "Unknown service_id"
+	case errors.Is(err, storage.ErrUserNotFound):
+		return http.StatusBadRequest, "Unknown user_id"
+	case errors.Is(err, storage.ErrCurrencyChange):
+		return http.StatusConflict, "Currency of a subscription with billing history cannot change"
+	case errors.Is(err, storage.ErrEndDateRequired):
+		return http.StatusConflict, "Cancelled subscription must keep an end date, reactivate it instead"
+	case errors.Is(err, storage.ErrBatchAborted):
+		return http.StatusFailedDependency, "Batch aborted"
+	default:
+		return http.StatusInternalServerError, fmt.Sprintf("Failed to %s subscription", kind)
+	}
+}
+
+func batchResponse(results []types.BatchResult) types.BatchResponse {
+	resp := types.BatchResponse{Results: results}
+	for _, result := range results {
+		if result.Error == "" {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+func TestToBatchOp(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name      string
+		operation types.BatchOperation
+		wantErr   string
+	}{
+		{name: "unknown op", operation: types.BatchOperation{Op: "upsert"}, wantErr: "Unknown operation"},
+		{name: "update without id", operation: types.BatchOperation{Op: storage.OpUpdate}, wantErr: "Invalid ID format"},
+		{name: "delete with bad id", operation: types.BatchOperation{Op: storage.OpDelete, ID: "42"}, wantErr: "Invalid ID format"},
+		{name: "create without body", operation: types.BatchOperation{Op: storage.OpCreate}, wantErr: "Subscription is required"},
+		{name: "update without body", operation: types.BatchOperation{Op: storage.OpUpdate, ID: id.String()}, wantErr: "Subscription is required"},
+		{name: "create with bad start date", operation: types.BatchOperation{Op: storage.OpCreate, Subscription: testSub("2025-13")}, wantErr: "Invalid subscription"},
+		{name: "update with bad end date", operation: types.BatchOperation{Op: storage.OpUpdate, ID: id.String(), Subscription: withEnd(testSub("07-2025"), "31-2025")}, wantErr: "Invalid subscription"},
+		{name: "delete", operation: types.BatchOperation{Op: storage.OpDelete, ID: id.String(), IfMatch: `"3"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := toBatchOp(tt.operation)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("toBatchOp() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toBatchOp() error = %v", err)
+			}
+			if op.Kind != storage.OpDelete || op.ID != id || len(op.IfMatch) != 1 || op.IfMatch[0] != 3 {
+				t.Errorf("toBatchOp() = %+v", op)
+			}
+		})
+	}
+}
+
+func TestToBatchOpAcceptsDates(t *testing.T) {
+	for _, start := range []string{"07-2025", "2025-07-15"} {
+		op, err := toBatchOp(types.BatchOperation{Op: storage.OpCreate, Subscription: withEnd(testSub(start), "2026-06-30")})
+		if err != nil {
+			t.Errorf("toBatchOp() with start %q error = %v", start, err)
+		} else if op.Sub.StartDate != start {
+			t.Errorf("toBatchOp() start = %q, want %q", op.Sub.StartDate, start)
+		}
+	}
+}
+
+func testSub(start string) *types.Subscription {
+	return &types.Subscription{ServiceName: "Yandex Plus", Price: "399.90", UserID: uuid.New(), StartDate: start}
+}
+
+func withEnd(sub *types.Subscription, end string) *types.Subscription {
+	sub.EndDate = end
+	return sub
+}
+
+func TestBatchStatus(t *testing.T) {
+	tests := []struct {
+		kind string
+		err  error
+		want int
+	}{
+		{kind: storage.OpCreate, want: http.StatusCreated},
+		{kind: storage.OpUpdate, want: http.StatusOK},
+		{kind: storage.OpDelete, want: http.StatusOK},
+		{kind: storage.OpUpdate, err: fmt.Errorf("update: %w", storage.ErrNotFound), want: http.StatusNotFound},
+		{kind: storage.OpDelete, err: storage.ErrVersionMismatch, want: http.StatusPreconditionFailed},
+		{kind: storage.OpCreate, err: storage.ErrUserNotFound, want: http.StatusBadRequest},
+		{kind: storage.OpUpdate, err: storage.ErrCurrencyChange, want: http.StatusConflict},
+		{kind: storage.OpCreate, err: storage.ErrBatchAborted, want: http.StatusFailedDependency},
+		{kind: storage.OpCreate, err: errors.New("connection reset"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		status, msg := batchStatus(tt.kind, tt.err)
+		if status != tt.want {
+			t.Errorf("batchStatus(%q, %v) status = %d, want %d", tt.kind, tt.err, status, tt.want)
+		}
+		if (msg == "") != (tt.err == nil) {
+			t.Errorf("batchStatus(%q, %v) message = %q", tt.kind, tt.err, msg)
+		}
+	}
+}
+
+func TestBatchResponseCounts(t *testing.T) {
+	resp := batchResponse([]types.BatchResult{
+		{Index: 0, Status: http.StatusCreated},
+		{Index: 1, Status: http.StatusNotFound, Error: "Subscription not found"},
+		{Index: 2, Status: http.StatusOK},
+	})
+	if resp.Succeeded != 2 || resp.Failed != 1 || len(resp.Results) != 3 {
+		t.Errorf("batchResponse() = %+v, want 2 succeeded and 1 failed", resp)
+	}
+}
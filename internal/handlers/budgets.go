@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Список бюджетов
+// @Description Получить бюджеты, упорядоченные по названию
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Param user_id query string false "Только бюджеты, в которых учитываются расходы пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success 200 {object} types.ListBudgetsResponse
+// @Failure 400 {object} types.InvalidUserIDErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets [get]
+func (h *Handler) ListBudgets(c *gin.Context) {
+	h.logStart(c)
+
+	var userID uuid.UUID
+	if raw := c.Query("user_id"); raw != "" {
+		var err error
+		userID, err = uuid.Parse(raw)
+		if err != nil {
+			h.logError(c, err, http.StatusBadRequest, "operation", "parse user_id")
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user_id format"})
+			return
+		}
+	}
+
+	budgets, err := h.Repo.ListBudgets(userID)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListBudgets")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list budgets"})
+		return
+	}
+
+	h.logSuccess(c, "Budgets listed", http.StatusOK, "count", len(budgets))
+	c.JSON(http.StatusOK, types.ListBudgetsResponse{
+		Budgets: budgets,
+		Count:   len(budgets),
+	})
+}
+
+// @Summary Получить бюджет по ID
+// @Description Получить параметры бюджета
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Param id path string true "ID бюджета" example("7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20")
+// @Success 200 {object} types.Budget
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.BudgetNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets/{id} [get]
+func (h *Handler) GetBudget(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	budget, err := h.Repo.GetBudget(id)
+	if !h.budgetError(c, err, "GetBudget", id) {
+		return
+	}
+
+	h.logSuccess(c, "Budget retrieved", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, budget)
+}
+
+// @Summary Добавить бюджет
+// @Description Задать месячный или годовой лимит расходов пользователя или команды: общий, по категории или по сервису. Совместные подписки учитываются в размере доли каждого пользователя
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Param budget body types.Budget true "Параметры бюджета"
+// @Success 201 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 400 {object} types.InvalidBudgetErrorResponse
+// @Failure 400 {object} types.UnknownUserErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets [post]
+func (h *Handler) CreateBudget(c *gin.Context) {
+	h.logStart(c)
+
+	var budget types.Budget
+	err := c.ShouldBindJSON(&budget)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	id, err := h.Repo.CreateBudget(budget)
+	if !h.budgetError(c, err, "CreateBudget", uuid.Nil) {
+		return
+	}
+
+	h.logSuccess(c, "Budget created", http.StatusCreated, "id", id)
+	c.JSON(http.StatusCreated, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Обновить бюджет
+// @Description Заменить параметры бюджета. Оповещения текущего периода сохраняются
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Param id path string true "ID бюджета" example("7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20")
+// @Param budget body types.Budget true "Параметры бюджета"
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 400 {object} types.InvalidBudgetErrorResponse
+// @Failure 400 {object} types.UnknownUserErrorResponse
+// @Failure 404 {object} types.BudgetNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets/{id} [put]
+func (h *Handler) UpdateBudget(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var budget types.Budget
+	err = c.ShouldBindJSON(&budget)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	err = h.Repo.UpdateBudget(id, budget)
+	if !h.budgetError(c, err, "UpdateBudget", id) {
+		return
+	}
+
+	h.logSuccess(c, "Budget updated", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Удалить бюджет
+// @Description Удалить бюджет вместе с его оповещениями
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Param id path string true "ID бюджета" example("7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20")
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.BudgetNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets/{id} [delete]
+func (h *Handler) DeleteBudget(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	err = h.Repo.DeleteBudget(id)
+	if !h.budgetError(c, err, "DeleteBudget", id) {
+		return
+	}
+
+	h.logSuccess(c, "Budget deleted", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Исполнение бюджета
+// @Description Рассчитать расходы с начала текущего периода бюджета и их долю от лимита. Запрос только читает данные: оповещения о достижении 80% и 100% лимита создает проверка бюджетов
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Param id path string true "ID бюджета" example("7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20")
+// @Success 200 {object} types.BudgetStatus
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.BudgetNotFoundErrorResponse
+// @Failure 422 {object} types.MissingExchangeRateErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets/{id}/status [get]
+func (h *Handler) GetBudgetStatus(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	status, err := h.Repo.BudgetStatus(id, time.Now())
+	if !h.budgetError(c, err, "BudgetStatus", id) {
+		return
+	}
+
+	h.logSuccess(c, "Budget status calculated", http.StatusOK, "id", id, "state", status.State)
+	c.JSON(http.StatusOK, status)
+}
+
+// @Summary Оповещения бюджета
+// @Description Получить все оповещения о достижении порогов бюджета, новые первыми
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Param id path string true "ID бюджета" example("7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20")
+// @Success 200 {object} types.BudgetAlertsResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.BudgetNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets/{id}/alerts [get]
+func (h *Handler) ListBudgetAlerts(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	alerts, err := h.Repo.BudgetAlerts(id)
+	if !h.budgetError(c, err, "BudgetAlerts", id) {
+		return
+	}
+
+	h.logSuccess(c, "Budget alerts listed", http.StatusOK, "id", id, "count", len(alerts))
+	c.JSON(http.StatusOK, types.BudgetAlertsResponse{
+		Alerts: alerts,
+		Count:  len(alerts),
+	})
+}
+
+// @Summary Проверить все бюджеты
+// @Description Рассчитать исполнение всех бюджетов и вернуть оповещения, созданные этой проверкой: при достижении 80% и 100% лимита, каждое один раз за период. Планировщик напоминаний выполняет проверку сам. Бюджеты без нужного курса валюты пропускаются
+// @Tags Бюджеты
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.BudgetAlertsResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /budgets/evaluate [post]
+func (h *Handler) EvaluateBudgets(c *gin.Context) {
+	h.logStart(c)
+
+	alerts, err := h.Repo.EvaluateBudgets(time.Now())
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "EvaluateBudgets")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to evaluate budgets"})
+		return
+	}
+	if alerts == nil {
+		alerts = []types.BudgetAlert{}
+	}
+	if h.Notifier != nil && len(alerts) > 0 {
+		// Alerts stay pending until sent, so the scheduler picks up any
+		// this send does not finish.
+		go h.Notifier.PendingBudgetAlerts(context.Background())
+	}
+
+	h.logSuccess(c, "Budgets evaluated", http.StatusOK, "alerts", len(alerts))
+	c.JSON(http.StatusOK, types.BudgetAlertsResponse{
+		Alerts: alerts,
+		Count:  len(alerts),
+	})
+}
+
+// budgetError writes the response for a failed budget request and reports
+// whether the request may go on.
+func (h *Handler) budgetError(c *gin.Context, err error, operation string, id uuid.UUID) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrBudgetNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", operation, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Budget not found"})
+	case errors.Is(err, storage.ErrInvalidBudget):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid budget"})
+	case errors.Is(err, storage.ErrUserNotFound):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unknown user_id"})
+	case errors.Is(err, fx.ErrNoRate):
+		h.logError(c, err, http.StatusUnprocessableEntity, "operation", operation, "id", id)
+		c.JSON(http.StatusUnprocessableEntity, types.ErrorResponse{Error: "Exchange rate not found"})
+	default:
+		h.logError(c, err, http.StatusInternalServerError, "operation", operation, "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to process budget"})
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ItserX/rest/internal/calendar"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Выпустить токен календаря
+// @Description Выпустить новый секретный токен ленты iCalendar для пользователя. Предыдущий токен перестает действовать
+// @Tags Календарь
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success 201 {object} types.CalendarTokenResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id}/calendar-token [post]
+func (h *Handler) IssueCalendarToken(c *gin.Context) {
+	h.logStart(c)
+
+	userID, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "generate token")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to issue calendar token"})
+		return
+	}
+	token := hex.EncodeToString(raw)
+
+	err = h.Repo.SetCalendarToken(userID, hashToken(token))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "SetCalendarToken", "user_id", userID)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "SetCalendarToken", "user_id", userID)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to issue calendar token"})
+		return
+	}
+
+	h.logSuccess(c, "Calendar token issued", http.StatusCreated, "user_id", userID)
+	c.JSON(http.StatusCreated, types.CalendarTokenResponse{
+		Token: token,
+		URL:   fmt.Sprintf("%s://%s/api/calendar/%s.ics", requestScheme(c), c.Request.Host, token),
+	})
+}
+
+// @Summary Отозвать токен календаря
+// @Description Отключить ленту iCalendar пользователя
+// @Tags Календарь
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.CalendarNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id}/calendar-token [delete]
+func (h *Handler) RevokeCalendarToken(c *gin.Context) {
+	h.logStart(c)
+
+	userID, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	err = h.Repo.DeleteCalendarToken(userID)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "DeleteCalendarToken", "user_id", userID)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Calendar not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "DeleteCalendarToken", "user_id", userID)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to revoke calendar token"})
+		return
+	}
+
+	h.logSuccess(c, "Calendar token revoked", http.StatusOK, "user_id", userID)
+	c.JSON(http.StatusOK, types.IDResponse{ID: userID.String()})
+}
+
+// @Summary Лента iCalendar с продлениями подписок
+// @Description Календарь с ежемесячными продлениями и датами окончания подписок пользователя. Адрес ленты выдается при выпуске токена
+// @Tags Календарь
+// @Produce text/calendar
+// @Param token path string true "Токен календаря, допускается суффикс .ics"
+// @Success 200 {string} string "Лента в формате iCalendar"
+// @Failure 404 {object} types.CalendarNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /calendar/{token} [get]
+func (h *Handler) GetCalendar(c *gin.Context) {
+	h.logStart(c)
+
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	userID, entries, err := h.Repo.CalendarEntries(hashToken(token))
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "CalendarEntries")
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Calendar not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "CalendarEntries")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to build calendar"})
+		return
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="subscriptions.ics"`)
+	c.Status(http.StatusOK)
+	err = calendar.WriteICS(c.Writer, "Подписки", entries, time.Now())
+	if err != nil {
+		h.logError(c, err, http.StatusOK, "operation", "WriteICS", "user_id", userID)
+		return
+	}
+
+	h.logSuccess(c, "Calendar served", http.StatusOK, "user_id", userID, "count", len(entries))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func requestScheme(c *gin.Context) string {
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		return proto
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
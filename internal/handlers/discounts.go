@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Добавить скидку
+// @Description Добавить подписке скидку в процентах или фиксированной суммой на N месяцев, до даты или бессрочно. Скидки, действующие в один день, применяются по очереди
+// @Tags Скидки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param request body types.DiscountRequest true "Параметры скидки"
+// @Success 201 {object} types.Discount
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 400 {object} types.InvalidDiscountErrorResponse
+// @Failure 400 {object} types.InvalidDateErrorResponse
+// @Failure 400 {object} types.CurrencyMismatchErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/{id}/discounts [post]
+func (h *Handler) AddDiscount(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var req types.DiscountRequest
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	discount, err := h.Repo.AddDiscount(id, req)
+	if !h.discountError(c, err, "AddDiscount", id) {
+		return
+	}
+
+	h.logSuccess(c, "Discount added", http.StatusCreated, "id", id, "discount_id", discount.ID)
+	c.JSON(http.StatusCreated, discount)
+}
+
+// @Summary Удалить скидку
+// @Description Удалить скидку подписки
+// @Tags Скидки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param discount_id path string true "ID скидки" example("0b8f3a52-4c1e-4a8e-9d6f-2f7b5c1e9a30")
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 404 {object} types.DiscountNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/{id}/discounts/{discount_id} [delete]
+func (h *Handler) DeleteDiscount(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	discountID, err := uuid.Parse(c.Param("discount_id"))
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "uuid.Parse", "discount_id", c.Param("discount_id"))
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	err = h.Repo.DeleteDiscount(id, discountID)
+	if !h.discountError(c, err, "DeleteDiscount", id) {
+		return
+	}
+
+	h.logSuccess(c, "Discount deleted", http.StatusOK, "id", id, "discount_id", discountID)
+	c.JSON(http.StatusOK, types.IDResponse{ID: discountID.String()})
+}
+
+// discountError writes the response for a failed discount change and reports
+// whether the request may go on.
+func (h *Handler) discountError(c *gin.Context, err error, operation string, id uuid.UUID) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", operation, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
+	case errors.Is(err, storage.ErrDiscountNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", operation, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Discount not found"})
+	case errors.Is(err, storage.ErrInvalidDiscount):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid discount"})
+	case errors.Is(err, storage.ErrInvalidDate):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid date"})
+	case errors.Is(err, storage.ErrCurrencyMismatch):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Discount currency must match subscription currency"})
+	default:
+		h.logError(c, err, http.StatusInternalServerError, "operation", operation, "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update discount"})
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	etagHeader        = "ETag"
+	ifMatchHeader     = "If-Match"
+	ifNoneMatchHeader = "If-None-Match"
+)
+
+func formatETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseIfMatch converts an If-Match header into the versions a write may
+// apply to. nil means the write is unconditional (no header or "*"). Weak and
+// malformed tags never match, so they are dropped, which may leave an empty
+// non-nil slice that rejects every version.
+func parseIfMatch(header string) []int64 {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 32)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// etagMatches reports whether an If-None-Match header matches the current
+// version using weak comparison.
+func etagMatches(header string, version int) bool {
+	current := formatETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int64
+	}{
+		{header: "", want: nil},
+		{header: "*", want: nil},
+		{header: "  *  ", want: nil},
+		{header: `"3"`, want: []int64{3}},
+		{header: `"3", "5"`, want: []int64{3, 5}},
+		{header: `"3",W/"5"`, want: []int64{3}},
+		{header: `W/"3"`, want: []int64{}},
+		{header: `3`, want: []int64{}},
+		{header: `"abc"`, want: []int64{}},
+		{header: `"`, want: []int64{}},
+		{header: `"99999999999"`, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got := parseIfMatch(tt.header)
+			if (got == nil) != (tt.want == nil) || !slices.Equal(got, tt.want) {
+				t.Errorf("parseIfMatch(%q) = %#v, want %#v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: `"3"`, want: true},
+		{header: `W/"3"`, want: true},
+		{header: `"1", "3"`, want: true},
+		{header: `*`, want: true},
+		{header: `"4"`, want: false},
+		{header: `3`, want: false},
+		{header: ``, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := etagMatches(tt.header, 3); got != tt.want {
+				t.Errorf("etagMatches(%q, 3) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ItserX/rest/internal/export"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var exportMIMEFormats = map[string]string{
+	export.MIMEJSON:      export.FormatJSON,
+	export.MIMECSV:       export.FormatCSV,
+	export.MIMEXLSX:      export.FormatXLSX,
+	export.MIMENDJSON:    export.FormatNDJSON,
+	"application/ndjson": export.FormatNDJSON,
+	"application/jsonl":  export.FormatNDJSON,
+}
+
+// negotiateFormat picks the response format from the format query parameter
+// or, when it is absent, from the Accept header. JSON is the default.
+func negotiateFormat(c *gin.Context) (string, error) {
+	if format := c.Query("format"); format != "" {
+		if _, ok := export.ContentTypes[format]; !ok {
+			return "", fmt.Errorf("unsupported format %q", format)
+		}
+		return format, nil
+	}
+
+	offered := []string{export.MIMEJSON, export.MIMECSV, export.MIMEXLSX, export.MIMENDJSON, "application/ndjson", "application/jsonl"}
+	if format, ok := exportMIMEFormats[c.NegotiateFormat(offered...)]; ok {
+		return format, nil
+	}
+	return export.FormatJSON, nil
+}
+
+// writeExport serves items in a non-JSON format: CSV and XLSX as a downloadable
+// table, NDJSON as one item per line.
+func writeExport[T any](c *gin.Context, format, fileName string, table export.Table, items []T) error {
+	c.Header("Content-Type", export.ContentTypes[format])
+	if format != export.FormatNDJSON {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, fileName, format))
+	}
+	c.Status(http.StatusOK)
+
+	switch format {
+	case export.FormatCSV:
+		return export.WriteCSV(c.Writer, table)
+	case export.FormatXLSX:
+		return export.WriteXLSX(c.Writer, table)
+	case export.FormatNDJSON:
+		return export.WriteNDJSON(c.Writer, items)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+var subscriptionColumns = []string{
+	"service_name", "price", "currency", "billing_interval", "billing_interval_count", "user_id", "start_date", "end_date", "trial_end", "category", "tags",
+}
+
+func subscriptionRow(sub types.Subscription) []interface{} {
+	return []interface{}{
+		sub.ServiceName, export.Number(sub.Price.String()), sub.Currency, sub.BillingInterval, sub.BillingIntervalCount, sub.UserID.String(), sub.StartDate, sub.EndDate, sub.TrialEnd, sub.Category, strings.Join(sub.Tags, ","),
+	}
+}
+
+func subscriptionsTable(subs []types.Subscription) export.Table {
+	table := export.Table{
+		Sheet:  "Subscriptions",
+		Header: subscriptionColumns,
+		Rows:   make([][]interface{}, 0, len(subs)),
+	}
+	for _, sub := range subs {
+		table.Rows = append(table.Rows, subscriptionRow(sub))
+	}
+	return table
+}
+
+var costColumns = []string{
+	"service_name", "category", "user_id", "start_date", "end_date", "price", "currency",
+	"billing_interval", "billing_interval_count", "months", "charges", "cost", "cost_currency",
+}
+
+func costTable(items []types.CostItem, currency string) (export.Table, error) {
+	table := export.Table{
+		Sheet:  "Total cost",
+		Header: costColumns,
+		Rows:   make([][]interface{}, 0, len(items)+1),
+	}
+	total := money.New(0, currency)
+	for _, item := range items {
+		table.Rows = append(table.Rows, []interface{}{
+			item.ServiceName, item.Category, item.UserID.String(), item.StartDate, item.EndDate, export.Number(item.Price.Decimal()), item.Price.Currency,
+			item.BillingInterval, item.BillingIntervalCount, item.Months, item.Charges, export.Number(item.Cost.Decimal()), item.Cost.Currency,
+		})
+		var err error
+		if total, err = total.Add(item.Cost); err != nil {
+			return export.Table{}, err
+		}
+	}
+	table.Rows = append(table.Rows, []interface{}{"total", "", "", "", "", "", "", "", "", "", "", export.Number(total.Decimal()), total.Currency})
+	return table, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ItserX/rest/internal/export"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		query   string
+		accept  string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: export.FormatJSON},
+		{name: "query", query: "?format=xlsx", accept: export.MIMECSV, want: export.FormatXLSX},
+		{name: "unknown query", query: "?format=xml", wantErr: true},
+		{name: "accept csv", accept: "text/csv", want: export.FormatCSV},
+		{name: "accept ndjson alias", accept: "application/jsonl", want: export.FormatNDJSON},
+		{name: "accept list", accept: "text/html, application/x-ndjson, text/csv", want: export.FormatNDJSON},
+		{name: "accept unsupported", accept: "text/html", want: export.FormatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/subscriptions"+tt.query, nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+
+			got, err := negotiateFormat(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("negotiateFormat() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("negotiateFormat() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("negotiateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
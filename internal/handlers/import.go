@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/importer"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Импортировать подписки из файла
+// @Description Загрузить подписки из CSV (с заголовком) или JSON-массива. Каждая строка проверяется, в том числе на наличие пользователя, ошибки возвращаются построчно. Подписки сопоставляются по ключу (user_id, service_name, start_date): существующие обновляются, новые создаются. При наличии ошибок ничего не записывается
+// @Tags Подписки
+// @Accept text/csv,json,mpfd
+// @Produce json
+// @Param format query string false "Формат файла: csv или json (по умолчанию определяется по Content-Type или имени файла)" example("csv")
+// @Param dry_run query bool false "Только проверить файл и посчитать изменения, не сохраняя их" example(true)
+// @Param map query string false "Соответствие полей колонкам файла в виде поле:колонка через запятую" example("service_name:Сервис,price:Стоимость")
+// @Param file formData file false "Файл для импорта (multipart/form-data)"
+// @Success 200 {object} types.ImportResponse
+// @Failure 400 {object} types.InvalidImportFileErrorResponse
+// @Failure 400 {object} types.InvalidColumnMappingErrorResponse
+// @Failure 409 {object} types.CancelledEndDateErrorResponse
+// @Failure 409 {object} types.CurrencyChangeErrorResponse
+// @Failure 422 {object} types.ImportResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/import [post]
+func (h *Handler) ImportSubs(c *gin.Context) {
+	h.logStart(c)
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	mapping, err := importer.ParseMapping(c.Query("map"))
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ParseMapping")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid column mapping"})
+		return
+	}
+
+	body, fileName, err := importBody(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "read import file")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid import file"})
+		return
+	}
+	defer body.Close()
+
+	format := importer.DetectFormat(c.Query("format"), c.ContentType(), fileName)
+	rows, rowErrors, err := importer.Parse(body, format, mapping)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "importer.Parse", "format", format)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid import file"})
+		return
+	}
+
+	resp := types.ImportResponse{
+		DryRun: dryRun,
+		Total:  len(rows) + countRows(rowErrors),
+		Errors: rowErrors,
+	}
+	if len(rowErrors) > 0 {
+		err := errors.New("import file contains invalid rows")
+		h.logError(c, err, http.StatusUnprocessableEntity, "operation", "validate rows", "invalid", len(rowErrors))
+		c.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	subs := make([]types.Subscription, len(rows))
+	for i, row := range rows {
+		subs[i] = row.Sub
+	}
+
+	resp.Errors, err = h.unknownUserErrors(rows)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "UnknownUsers")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to import subscriptions"})
+		return
+	}
+	if len(resp.Errors) > 0 {
+		err := errors.New("import file refers to unknown users")
+		h.logError(c, err, http.StatusUnprocessableEntity, "operation", "check users", "invalid", len(resp.Errors))
+		c.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	result, err := h.Repo.Import(subs, dryRun)
+	if errors.Is(err, storage.ErrCurrencyChange) {
+		h.logError(c, err, http.StatusConflict, "operation", "Import")
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Currency of a subscription with billing history cannot change"})
+		return
+	}
+	if errors.Is(err, storage.ErrEndDateRequired) {
+		h.logError(c, err, http.StatusConflict, "operation", "Import")
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Cancelled subscription must keep an end date, reactivate it instead"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "Import")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to import subscriptions"})
+		return
+	}
+	resp.Created = result.Created
+	resp.Updated = result.Updated
+
+	h.logSuccess(c, "Subscriptions imported", http.StatusOK,
+		"dryRun", dryRun,
+		"created", result.Created,
+		"updated", result.Updated,
+	)
+	c.JSON(http.StatusOK, resp)
+}
+
+// unknownUserErrors reports the rows whose user_id is not a registered user.
+func (h *Handler) unknownUserErrors(rows []importer.Row) ([]types.ImportRowError, error) {
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.Sub.UserID
+	}
+	unknown, err := h.Repo.UnknownUsers(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var rowErrors []types.ImportRowError
+	for _, row := range rows {
+		if slices.Contains(unknown, row.Sub.UserID) {
+			rowErrors = append(rowErrors, types.ImportRowError{Row: row.Number, Field: "user_id", Error: "unknown user"})
+		}
+	}
+	return rowErrors, nil
+}
+
+// importBody returns the uploaded file from a multipart form or, for any other
+// content type, the raw request body.
+func importBody(c *gin.Context) (io.ReadCloser, string, error) {
+	if c.ContentType() != "multipart/form-data" {
+		return c.Request.Body, "", nil
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	return file, header.Filename, nil
+}
+
+func countRows(rowErrors []types.ImportRowError) int {
+	rows := make(map[int]struct{}, len(rowErrors))
+	for _, rowError := range rowErrors {
+		rows[rowError.Row] = struct{}{}
+	}
+	return len(rows)
+}
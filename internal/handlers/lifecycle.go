@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Приостановить подписку
+// @Description Приостановить подписку начиная с указанного месяца. Приостановленные месяцы не учитываются в общей стоимости. Месяц не может быть позже текущего
+// @Tags Статус подписки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param request body types.LifecycleRequest false "Первый приостановленный месяц"
+// @Success 200 {object} types.StatusResponse
+// @Failure 400 {object} types.InvalidDateErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 409 {object} types.InvalidTransitionErrorResponse
+// @Failure 500 {object} types.FailedToUpdateSub
+// @Router /subscriptions/{id}/pause [post]
+func (h *Handler) PauseSub(c *gin.Context) {
+	h.changeStatus(c, storage.ActionPause, h.Repo.Pause)
+}
+
+// @Summary Возобновить подписку
+// @Description Возобновить приостановленную подписку с указанного месяца, не позже текущего
+// @Tags Статус подписки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param request body types.LifecycleRequest false "Первый оплачиваемый месяц после паузы"
+// @Success 200 {object} types.StatusResponse
+// @Failure 400 {object} types.InvalidDateErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 409 {object} types.InvalidTransitionErrorResponse
+// @Failure 500 {object} types.FailedToUpdateSub
+// @Router /subscriptions/{id}/resume [post]
+func (h *Handler) ResumeSub(c *gin.Context) {
+	h.changeStatus(c, storage.ActionResume, h.Repo.Resume)
+}
+
+// @Summary Отменить подписку
+// @Description Отменить подписку: указанная дата (или последний день указанного месяца) становится последним днем подписки и записывается в end_date. Отмена не продлевает подписку: дата после end_date отклоняется, а без даты сохраняется более ранний end_date
+// @Tags Статус подписки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param request body types.LifecycleRequest false "Последний день или месяц подписки"
+// @Success 200 {object} types.StatusResponse
+// @Failure 400 {object} types.InvalidDateErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 409 {object} types.InvalidTransitionErrorResponse
+// @Failure 500 {object} types.FailedToUpdateSub
+// @Router /subscriptions/{id}/cancel [post]
+func (h *Handler) CancelSub(c *gin.Context) {
+	h.changeStatus(c, storage.ActionCancel, h.Repo.Cancel)
+}
+
+// @Summary Возобновить отмененную подписку
+// @Description Снова сделать отмененную или истекшую подписку активной, дата окончания снимается
+// @Tags Статус подписки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Success 200 {object} types.StatusResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 409 {object} types.InvalidTransitionErrorResponse
+// @Failure 500 {object} types.FailedToUpdateSub
+// @Router /subscriptions/{id}/reactivate [post]
+func (h *Handler) ReactivateSub(c *gin.Context) {
+	h.changeStatus(c, storage.ActionReactivate, func(id uuid.UUID, _ string) (string, error) {
+		return h.Repo.Reactivate(id)
+	})
+}
+
+func (h *Handler) changeStatus(c *gin.Context, action string, change func(id uuid.UUID, month string) (string, error)) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var req types.LifecycleRequest
+	if c.Request.ContentLength != 0 {
+		err = c.ShouldBindJSON(&req)
+		if err != nil {
+			h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+			return
+		}
+	}
+
+	status, err := change(id, req.Date)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", action, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
+		return
+	}
+	var transitionErr *storage.TransitionError
+	if errors.As(err, &transitionErr) {
+		h.logError(c, err, http.StatusConflict, "operation", action, "id", id)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Transition not allowed from status " + transitionErr.From})
+		return
+	}
+	if errors.Is(err, storage.ErrInvalidDate) {
+		h.logError(c, err, http.StatusBadRequest, "operation", action, "id", id, "date", req.Date)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid date"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", action, "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update subscription"})
+		return
+	}
+
+	h.logSuccess(c, "Subscription status changed", http.StatusOK, "id", id, "action", action, "status", status)
+	c.JSON(http.StatusOK, types.StatusResponse{ID: id.String(), Status: status})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Участники подписки
+// @Description Получить участников совместной подписки и правило разделения ее стоимости
+// @Tags Совместные подписки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Success 200 {object} types.Sharing
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/{id}/members [get]
+func (h *Handler) GetSharing(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	sharing, err := h.Repo.GetSharing(id)
+	if !h.sharingError(c, err, "GetSharing", id) {
+		return
+	}
+
+	h.logSuccess(c, "Sharing retrieved", http.StatusOK, "id", id, "members", len(sharing.Members))
+	c.JSON(http.StatusOK, sharing)
+}
+
+// @Summary Задать участников подписки
+// @Description Заменить участников совместной подписки и правило разделения стоимости. Владелец платит остаток после долей участников; фиксированные доли указываются за расчетный период в валюте подписки и учитываются по порядку. Пустой список участников отменяет совместное использование
+// @Tags Совместные подписки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param request body types.Sharing true "Участники и правило разделения"
+// @Success 200 {object} types.Sharing
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 400 {object} types.InvalidSharingErrorResponse
+// @Failure 400 {object} types.UnknownUserErrorResponse
+// @Failure 400 {object} types.CurrencyMismatchErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/{id}/members [put]
+func (h *Handler) SetSharing(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var req types.Sharing
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	sharing, err := h.Repo.SetSharing(id, req)
+	if !h.sharingError(c, err, "SetSharing", id) {
+		return
+	}
+
+	h.logSuccess(c, "Sharing updated", http.StatusOK, "id", id, "members", len(sharing.Members))
+	c.JSON(http.StatusOK, sharing)
+}
+
+// sharingError writes the response for a failed sharing request and reports
+// whether the request may go on.
+func (h *Handler) sharingError(c *gin.Context, err error, operation string, id uuid.UUID) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", operation, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
+	case errors.Is(err, storage.ErrUserNotFound):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Unknown user_id"})
+	case errors.Is(err, storage.ErrInvalidSharing):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid sharing"})
+	case errors.Is(err, storage.ErrCurrencyMismatch):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Share currency must match subscription currency"})
+	default:
+		h.logError(c, err, http.StatusInternalServerError, "operation", operation, "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update sharing"})
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Журнал доставки уведомлений
+// @Description Получить последние попытки доставки напоминаний и оповещений по email и вебхуку, сначала новые
+// @Tags Уведомления
+// @Accept json
+// @Produce json
+// @Param user_id query string false "Только уведомления пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param status query string false "Результат доставки: sent, failed или skipped" example("failed")
+// @Success 200 {object} types.ListDeliveriesResponse
+// @Failure 400 {object} types.InvalidUserIDErrorResponse
+// @Failure 400 {object} types.InvalidDeliveryStatusErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /notifications/deliveries [get]
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	h.logStart(c)
+
+	var filter storage.DeliveryFilter
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			h.logError(c, err, http.StatusBadRequest, "operation", "parse user_id")
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user_id format"})
+			return
+		}
+		filter.UserID = userID
+	}
+
+	filter.Status = c.Query("status")
+	switch filter.Status {
+	case "", types.DeliverySent, types.DeliveryFailed, types.DeliverySkipped:
+	default:
+		err := fmt.Errorf("invalid status %q", filter.Status)
+		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid status"})
+		return
+	}
+
+	deliveries, err := h.Repo.ListDeliveries(filter)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListDeliveries")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list deliveries"})
+		return
+	}
+
+	h.logSuccess(c, "Deliveries listed", http.StatusOK, "count", len(deliveries))
+	c.JSON(http.StatusOK, types.ListDeliveriesResponse{
+		Deliveries: deliveries,
+		Count:      len(deliveries),
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+var errUnsupportedPatchType = errors.New("unsupported patch content type")
+
+// applyPatch applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// document to the JSON representation of a resource, depending on the request
+// content type. Plain application/json is treated as a merge patch.
+func applyPatch(contentType string, original, patch []byte) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errUnsupportedPatchType
+	}
+
+	switch mediaType {
+	case mergePatchContentType, "application/json":
+		return jsonpatch.MergePatch(original, patch)
+	case jsonPatchContentType:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		return ops.Apply(original)
+	default:
+		return nil, errUnsupportedPatchType
+	}
+}
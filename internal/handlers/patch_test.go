@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+func TestApplyPatch(t *testing.T) {
+	original := `{"service_name":"Yandex Plus","price":399,"tags":["music","family"],"end_date":"12-2025"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        string
+		wantErr     error
+	}{
+		{
+			name:        "merge patch sets and removes fields",
+			contentType: mergePatchContentType,
+			patch:       `{"price":499,"end_date":null}`,
+			want:        `{"service_name":"Yandex Plus","price":499,"tags":["music","family"]}`,
+		},
+		{
+			name:        "merge patch replaces arrays",
+			contentType: mergePatchContentType,
+			patch:       `{"tags":["work"]}`,
+			want:        `{"service_name":"Yandex Plus","price":399,"tags":["work"],"end_date":"12-2025"}`,
+		},
+		{
+			name:        "plain json is a merge patch",
+			contentType: "application/json; charset=utf-8",
+			patch:       `{"price":499}`,
+			want:        `{"service_name":"Yandex Plus","price":499,"tags":["music","family"],"end_date":"12-2025"}`,
+		},
+		{
+			name:        "json patch replace and remove",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op":"replace","path":"/price","value":499},{"op":"remove","path":"/end_date"}]`,
+			want:        `{"service_name":"Yandex Plus","price":499,"tags":["music","family"]}`,
+		},
+		{
+			name:        "json patch adds at an array index and at the end",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op":"add","path":"/tags/0","value":"video"},{"op":"add","path":"/tags/-","value":"work"}]`,
+			want:        `{"service_name":"Yandex Plus","price":399,"tags":["video","music","family","work"],"end_date":"12-2025"}`,
+		},
+		{
+			name:        "json patch removes an array element",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op":"remove","path":"/tags/1"}]`,
+			want:        `{"service_name":"Yandex Plus","price":399,"tags":["music"],"end_date":"12-2025"}`,
+		},
+		{
+			name:        "json patch move and copy",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op":"move","from":"/end_date","path":"/trial_end"},{"op":"copy","from":"/tags/0","path":"/category"}]`,
+			want:        `{"service_name":"Yandex Plus","price":399,"tags":["music","family"],"trial_end":"12-2025","category":"music"}`,
+		},
+		{
+			name:        "json patch test that holds",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op":"test","path":"/price","value":399},{"op":"replace","path":"/price","value":499}]`,
+			want:        `{"service_name":"Yandex Plus","price":499,"tags":["music","family"],"end_date":"12-2025"}`,
+		},
+		{
+			name:        "json patch test that fails",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op":"test","path":"/price","value":100},{"op":"replace","path":"/price","value":499}]`,
+			wantErr:     jsonpatch.ErrTestFailed,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			patch:       `{"price":499}`,
+			wantErr:     errUnsupportedPatchType,
+		},
+		{
+			name:        "missing content type",
+			contentType: "",
+			patch:       `{"price":499}`,
+			wantErr:     errUnsupportedPatchType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(tt.contentType, []byte(original), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("applyPatch() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPatch() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyPatchRejectsInvalidDocuments(t *testing.T) {
+	original := []byte(`{"tags":["music"]}`)
+	patches := []string{
+		`{"op":"remove","path":"/tags"}`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"add","path":"/tags/5","value":"work"}]`,
+		`[{"op":"rename","path":"/tags"}]`,
+	}
+	for _, patch := range patches {
+		if got, err := applyPatch(jsonPatchContentType, original, []byte(patch)); err == nil {
+			t.Errorf("applyPatch(%s) = %s, want an error", patch, got)
+		}
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
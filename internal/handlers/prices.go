@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary История цен подписки
+// @Description Получить цены подписки с первого месяца, включая запланированные изменения
+// @Tags Цены
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Success 200 {object} types.PriceHistoryResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/{id}/prices [get]
+func (h *Handler) GetPriceHistory(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	h.writePriceHistory(c, id, "Price history retrieved")
+}
+
+// @Summary Запланировать изменение цены
+// @Description Задать новую цену подписки начиная с указанного месяца, не меняя стоимость прошлых месяцев. Изменение на тот же месяц заменяется. Начальную цену меняет обновление подписки
+// @Tags Цены
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param request body types.PriceChangeRequest true "Новая цена и месяц начала ее действия"
+// @Success 200 {object} types.PriceHistoryResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 400 {object} types.InvalidDateErrorResponse
+// @Failure 400 {object} types.CurrencyMismatchErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/{id}/prices [post]
+func (h *Handler) SchedulePriceChange(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var req types.PriceChangeRequest
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if req.Price.Amount <= 0 {
+		err := errors.New("price must be positive")
+		h.logError(c, err, http.StatusBadRequest, "operation", "price validation", "price", req.Price.String())
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	err = h.Repo.SchedulePrice(id, req.EffectiveFrom, req.Price)
+	if !h.priceChangeError(c, err, "SchedulePrice", id) {
+		return
+	}
+
+	h.writePriceHistory(c, id, "Price change scheduled")
+}
+
+// @Summary Удалить изменение цены
+// @Description Отменить изменение цены, начинающееся в указанном месяце
+// @Tags Цены
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" example("8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0")
+// @Param month path string true "Первый месяц действия цены в формате ММ-ГГГГ" example("01-2026")
+// @Success 200 {object} types.PriceHistoryResponse
+// @Failure 400 {object} types.InvalidDateErrorResponse
+// @Failure 404 {object} types.NotFoundErrorResponse
+// @Failure 404 {object} types.PriceChangeNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /subscriptions/{id}/prices/{month} [delete]
+func (h *Handler) DeletePriceChange(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	err = h.Repo.DeletePriceChange(id, c.Param("month"))
+	if !h.priceChangeError(c, err, "DeletePriceChange", id) {
+		return
+	}
+
+	h.writePriceHistory(c, id, "Price change deleted")
+}
+
+func (h *Handler) writePriceHistory(c *gin.Context, id uuid.UUID, msg string) {
+	prices, err := h.Repo.PriceHistory(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "PriceHistory", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "PriceHistory", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get price history"})
+		return
+	}
+
+	h.logSuccess(c, msg, http.StatusOK, "id", id, "count", len(prices))
+	c.JSON(http.StatusOK, types.PriceHistoryResponse{ID: id.String(), Prices: prices})
+}
+
+// priceChangeError writes the response for a failed price change and reports
+// whether the request may go on.
+func (h *Handler) priceChangeError(c *gin.Context, err error, operation string, id uuid.UUID) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", operation, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Subscription not found"})
+	case errors.Is(err, storage.ErrPriceChangeNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", operation, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Price change not found"})
+	case errors.Is(err, storage.ErrInvalidDate):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid date"})
+	case errors.Is(err, storage.ErrCurrencyMismatch):
+		h.logError(c, err, http.StatusBadRequest, "operation", operation, "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Price currency must match subscription currency"})
+	default:
+		h.logError(c, err, http.StatusInternalServerError, "operation", operation, "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update price"})
+	}
+	return false
+}
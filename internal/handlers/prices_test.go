@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/ItserX/rest/internal/logger"
+)
+
+func TestSchedulePriceChangeRejectsZeroPrice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop().Sugar()
+
+	bodies := []string{
+		`{"price": 0, "effective_from": "01-2026"}`,
+		`{"price": {"amount": 0, "currency": "RUB"}, "effective_from": "01-2026"}`,
+		`{"effective_from": "01-2026"}`,
+	}
+	for _, body := range bodies {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"}}
+		c.Request = httptest.NewRequest("POST", "/api/subscriptions/8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0/prices", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		// A nil repository fails the test if the change gets that far.
+		(&Handler{}).SchedulePriceChange(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("SchedulePriceChange(%s) status = %d, want 400", body, w.Code)
+		}
+	}
+}
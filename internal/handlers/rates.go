@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/importer"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Список курсов валют
+// @Description Получить загруженные курсы валют к рублю, упорядоченные по валюте и дате начала действия
+// @Tags Курсы валют
+// @Accept json
+// @Produce json
+// @Param currency query string false "Код валюты для фильтрации" example("USD")
+// @Success 200 {object} types.ExchangeRatesResponse
+// @Failure 400 {object} types.InvalidCurrencyErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /exchange-rates [get]
+func (h *Handler) ListRates(c *gin.Context) {
+	h.logStart(c)
+
+	currency := strings.ToUpper(c.Query("currency"))
+	if currency != "" && !fx.ValidCode(currency) {
+		err := fmt.Errorf("invalid currency %q", currency)
+		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid currency"})
+		return
+	}
+
+	rates, err := h.Repo.ListRates(currency)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListRates")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list exchange rates"})
+		return
+	}
+
+	resp := types.ExchangeRatesResponse{Rates: make([]types.ExchangeRate, 0, len(rates))}
+	for _, rate := range rates {
+		resp.Rates = append(resp.Rates, types.ExchangeRate{
+			Currency: rate.Currency,
+			Date:     rate.Date.Format(dates.DayLayout),
+			Rate:     json.Number(rate.Rate.FloatString(6)),
+		})
+	}
+	resp.Count = len(resp.Rates)
+
+	h.logSuccess(c, "Exchange rates listed", http.StatusOK, "count", resp.Count)
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Загрузить курсы валют
+// @Description Загрузить курсы валют к рублю из CSV (колонки currency, date, rate) или JSON-массива. Курс действует с указанной даты до следующего курса той же валюты; существующие курсы на ту же дату заменяются
+// @Tags Курсы валют
+// @Accept text/csv,json,mpfd
+// @Produce json
+// @Param format query string false "Формат файла: csv или json (по умолчанию определяется по Content-Type или имени файла)" example("csv")
+// @Param rates body []types.ExchangeRate false "Курсы валют"
+// @Success 200 {object} types.LoadRatesResponse
+// @Failure 400 {object} types.InvalidRatesFileErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /exchange-rates [post]
+func (h *Handler) LoadRates(c *gin.Context) {
+	h.logStart(c)
+
+	body, fileName, err := importBody(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "read rates file")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid exchange rates"})
+		return
+	}
+	defer body.Close()
+
+	format := importer.DetectFormat(c.Query("format"), c.ContentType(), fileName)
+	rates, err := importer.ParseRates(body, format)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "importer.ParseRates", "format", format)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid exchange rates: " + err.Error()})
+		return
+	}
+
+	loaded, err := h.Repo.UpsertRates(rates)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "UpsertRates")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to load exchange rates"})
+		return
+	}
+
+	h.logSuccess(c, "Exchange rates loaded", http.StatusOK, "loaded", loaded)
+	c.JSON(http.StatusOK, types.LoadRatesResponse{Loaded: loaded})
+}
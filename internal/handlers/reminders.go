@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Напоминания пользователя
+// @Description Получить последние напоминания о продлении и окончании подписок пользователя, сначала новые
+// @Tags Напоминания
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success 200 {object} types.ListRemindersResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id}/reminders [get]
+func (h *Handler) ListReminders(c *gin.Context) {
+	h.logStart(c)
+
+	userID, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	reminders, err := h.Repo.ListReminders(userID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "ListReminders", "user_id", userID)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListReminders", "user_id", userID)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list reminders"})
+		return
+	}
+
+	h.logSuccess(c, "Reminders listed", http.StatusOK, "user_id", userID, "count", len(reminders))
+	c.JSON(http.StatusOK, types.ListRemindersResponse{
+		Reminders: reminders,
+		Count:     len(reminders),
+	})
+}
+
+// @Summary Настройки напоминаний
+// @Description Получить настройки напоминаний пользователя. Если они не менялись, включены оба типа напоминаний с окном по умолчанию
+// @Tags Напоминания
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success 200 {object} types.ReminderPreferences
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id}/reminder-preferences [get]
+func (h *Handler) GetReminderPreferences(c *gin.Context) {
+	h.logStart(c)
+
+	userID, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	prefs, err := h.Repo.GetReminderPreferences(userID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "GetReminderPreferences", "user_id", userID)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "GetReminderPreferences", "user_id", userID)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get reminder preferences"})
+		return
+	}
+
+	h.logSuccess(c, "Reminder preferences retrieved", http.StatusOK, "user_id", userID)
+	c.JSON(http.StatusOK, prefs)
+}
+
+// @Summary Изменить настройки напоминаний
+// @Description Включить или отключить напоминания о продлении и окончании подписок и задать, за сколько дней до даты напоминать
+// @Tags Напоминания
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param preferences body types.ReminderPreferences true "Настройки напоминаний"
+// @Success 200 {object} types.ReminderPreferences
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id}/reminder-preferences [put]
+func (h *Handler) SetReminderPreferences(c *gin.Context) {
+	h.logStart(c)
+
+	userID, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var prefs types.ReminderPreferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	err = h.Repo.SetReminderPreferences(userID, prefs)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "SetReminderPreferences", "user_id", userID)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "SetReminderPreferences", "user_id", userID)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to set reminder preferences"})
+		return
+	}
+
+	h.logSuccess(c, "Reminder preferences set", http.StatusOK, "user_id", userID)
+	c.JSON(http.StatusOK, prefs)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Список сервисов
+// @Description Получить каталог сервисов с псевдонимами, упорядоченный по названию
+// @Tags Каталог сервисов
+// @Accept json
+// @Produce json
+// @Param category query string false "Категория для фильтрации" example("Музыка и кино")
+// @Success 200 {object} types.ListServicesResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /services [get]
+func (h *Handler) ListServices(c *gin.Context) {
+	h.logStart(c)
+
+	services, err := h.Repo.ListServices(c.Query("category"))
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListServices")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list services"})
+		return
+	}
+
+	h.logSuccess(c, "Services listed", http.StatusOK, "count", len(services))
+	c.JSON(http.StatusOK, types.ListServicesResponse{
+		Services: services,
+		Count:    len(services),
+	})
+}
+
+// @Summary Получить сервис по ID
+// @Description Получить запись каталога сервисов
+// @Tags Каталог сервисов
+// @Accept json
+// @Produce json
+// @Param id path string true "ID сервиса" example("4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d")
+// @Success 200 {object} types.Service
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.ServiceNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /services/{id} [get]
+func (h *Handler) GetService(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	svc, err := h.Repo.GetService(id)
+	if errors.Is(err, storage.ErrServiceNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "GetService", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Service not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "GetService", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get service"})
+		return
+	}
+
+	h.logSuccess(c, "Service retrieved", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, svc)
+}
+
+// @Summary Добавить сервис
+// @Description Добавить сервис в каталог. Подписки, название которых совпадает с названием или псевдонимом сервиса без учета регистра, связываются с ним и получают каноническое название
+// @Tags Каталог сервисов
+// @Accept json
+// @Produce json
+// @Param service body types.Service true "Данные сервиса"
+// @Success 201 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 409 {object} types.ServiceConflictErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /services [post]
+func (h *Handler) CreateService(c *gin.Context) {
+	h.logStart(c)
+
+	var svc types.Service
+	err := c.ShouldBindJSON(&svc)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	id, err := h.Repo.CreateService(svc)
+	if errors.Is(err, storage.ErrServiceConflict) {
+		h.logError(c, err, http.StatusConflict, "operation", "CreateService", "service", svc)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Service name or alias is already used by another service"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "CreateService", "service", svc)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create service"})
+		return
+	}
+
+	h.logSuccess(c, "Service created", http.StatusCreated, "id", id)
+	c.JSON(http.StatusCreated, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Обновить сервис
+// @Description Заменить запись каталога. Связанные подписки получают новое название, подписки с совпадающими псевдонимами связываются с сервисом
+// @Tags Каталог сервисов
+// @Accept json
+// @Produce json
+// @Param id path string true "ID сервиса" example("4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d")
+// @Param service body types.Service true "Данные сервиса"
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 404 {object} types.ServiceNotFoundErrorResponse
+// @Failure 409 {object} types.ServiceConflictErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /services/{id} [put]
+func (h *Handler) UpdateService(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var svc types.Service
+	err = c.ShouldBindJSON(&svc)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	err = h.Repo.UpdateService(id, svc)
+	if errors.Is(err, storage.ErrServiceNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "UpdateService", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Service not found"})
+		return
+	}
+	if errors.Is(err, storage.ErrServiceConflict) {
+		h.logError(c, err, http.StatusConflict, "operation", "UpdateService", "id", id, "service", svc)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "Service name or alias is already used by another service"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "UpdateService", "id", id, "service", svc)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update service"})
+		return
+	}
+
+	h.logSuccess(c, "Service updated", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Удалить сервис
+// @Description Удалить сервис из каталога. Подписки сохраняют название и перестают быть связанными с каталогом
+// @Tags Каталог сервисов
+// @Accept json
+// @Produce json
+// @Param id path string true "ID сервиса" example("4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d")
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.ServiceNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /services/{id} [delete]
+func (h *Handler) DeleteService(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	err = h.Repo.DeleteService(id)
+	if errors.Is(err, storage.ErrServiceNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "DeleteService", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Service not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "DeleteService", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to delete service"})
+		return
+	}
+
+	h.logSuccess(c, "Service deleted", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ItserX/rest/internal/export"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// streamFlushEvery is how many rows are written between explicit flushes so
+// that clients start receiving data before the query finishes.
+const streamFlushEvery = 100
+
+type subscriptionStream interface {
+	write(sub types.Subscription) error
+	close(count int) error
+}
+
+// jsonArrayStream produces the same document as ListSubscriptionsResponse,
+// placing count after the array since it is only known at the end.
+type jsonArrayStream struct {
+	w     io.Writer
+	first bool
+}
+
+func newJSONArrayStream(w io.Writer) (*jsonArrayStream, error) {
+	_, err := io.WriteString(w, `{"subscriptions":[`)
+	return &jsonArrayStream{w: w, first: true}, err
+}
+
+func (s *jsonArrayStream) write(sub types.Subscription) error {
+	if !s.first {
+		if _, err := io.WriteString(s.w, ","); err != nil {
+			return err
+		}
+	}
+	s.first = false
+
+	payload, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(payload)
+	return err
+}
+
+func (s *jsonArrayStream) close(count int) error {
+	payload, err := json.Marshal(count)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(s.w, `],"count":`+string(payload)+"}")
+	return err
+}
+
+type ndjsonStream struct {
+	encoder *json.Encoder
+}
+
+func (s *ndjsonStream) write(sub types.Subscription) error {
+	return s.encoder.Encode(sub)
+}
+
+func (s *ndjsonStream) close(int) error {
+	return nil
+}
+
+type csvStream struct {
+	writer *export.CSVWriter
+}
+
+func (s *csvStream) write(sub types.Subscription) error {
+	return s.writer.Write(subscriptionRow(sub))
+}
+
+func (s *csvStream) close(int) error {
+	return s.writer.Flush()
+}
+
+// streamSubs writes subscriptions to the client while they are being read from
+// the database. Once the first byte is sent the status can no longer change, so
+// failures are only logged and the response is left truncated.
+func (h *Handler) streamSubs(c *gin.Context, format string, filter storage.SubscriptionFilter) {
+	c.Header("Content-Type", export.ContentTypes[format])
+	if format == export.FormatCSV {
+		c.Header("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+	}
+	c.Status(http.StatusOK)
+
+	var (
+		stream subscriptionStream
+		err    error
+	)
+	switch format {
+	case export.FormatCSV:
+		var writer *export.CSVWriter
+		writer, err = export.NewCSVWriter(c.Writer, subscriptionColumns)
+		stream = &csvStream{writer: writer}
+	case export.FormatNDJSON:
+		stream = &ndjsonStream{encoder: json.NewEncoder(c.Writer)}
+	default:
+		stream, err = newJSONArrayStream(c.Writer)
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusOK, "operation", "start stream", "format", format)
+		return
+	}
+
+	count := 0
+	err = h.Repo.Stream(c.Request.Context(), filter, func(sub types.Subscription) error {
+		if err := stream.write(sub); err != nil {
+			return err
+		}
+		count++
+		if count%streamFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		logger.Logger.Warnw("Subscription stream cancelled by client",
+			"method", c.Request.Method,
+			"path", RequestPath(c),
+			"count", count,
+		)
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusOK, "operation", "Stream", "format", format, "count", count)
+		return
+	}
+
+	err = stream.close(count)
+	if err != nil {
+		h.logError(c, err, http.StatusOK, "operation", "close stream", "format", format)
+		return
+	}
+
+	h.logSuccess(c, "Subscriptions streamed", http.StatusOK, "count", count, "format", format)
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+const (
+	summaryRenewals    = 5
+	summaryTopServices = 3
+)
+
+// @Summary Сводка расходов пользователя
+// @Description Получить для экрана личных финансов число действующих подписок, расходы за текущий месяц и с начала года, изменение к предыдущему месяцу, ближайшие списания и самые дорогие сервисы. Совместные подписки учитываются в размере доли пользователя
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param currency query string false "Валюта расчета (по умолчанию RUB)" example("USD")
+// @Success 200 {object} types.UserSummary
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidCurrencyErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 422 {object} types.MissingExchangeRateErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id}/summary [get]
+func (h *Handler) GetUserSummary(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	currency := strings.ToUpper(c.DefaultQuery("currency", fx.Base))
+	if !fx.ValidCode(currency) {
+		err := fmt.Errorf("invalid currency %q", currency)
+		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid currency"})
+		return
+	}
+
+	summary, err := h.userSummary(id, currency, time.Now())
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "userSummary", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if errors.Is(err, fx.ErrNoRate) {
+		h.logError(c, err, http.StatusUnprocessableEntity, "operation", "userSummary", "id", id)
+		c.JSON(http.StatusUnprocessableEntity, types.ErrorResponse{Error: "Exchange rate not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "userSummary", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to build user summary"})
+		return
+	}
+
+	h.logSuccess(c, "User summary built", http.StatusOK, "id", id, "active", summary.ActiveSubscriptions)
+	c.JSON(http.StatusOK, summary)
+}
+
+// userSummary assembles the summary from the user's subscriptions and their
+// cost breakdowns for the current month, the previous one and the year so far.
+func (h *Handler) userSummary(id uuid.UUID, currency string, now time.Time) (types.UserSummary, error) {
+	if _, err := h.Repo.GetUser(id); err != nil {
+		return types.UserSummary{}, err
+	}
+
+	month := dates.MonthStart(now)
+	spend := func(from, to time.Time) ([]types.CostItem, error) {
+		return h.Repo.CostBreakdown(storage.CostQuery{
+			Filter:      storage.SubscriptionFilter{UserID: id},
+			PeriodStart: from.Format(dates.MonthLayout),
+			PeriodEnd:   to.Format(dates.MonthLayout),
+			View:        billing.ViewAccrual,
+			Currency:    currency,
+		})
+	}
+	current, err := spend(month, month)
+	if err != nil {
+		return types.UserSummary{}, err
+	}
+	previous, err := spend(month.AddDate(0, -1, 0), month.AddDate(0, -1, 0))
+	if err != nil {
+		return types.UserSummary{}, err
+	}
+	yearToDate, err := spend(time.Date(month.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), month)
+	if err != nil {
+		return types.UserSummary{}, err
+	}
+
+	subs, err := h.Repo.List(storage.SubscriptionFilter{UserID: id})
+	if err != nil {
+		return types.UserSummary{}, err
+	}
+
+	summary := types.UserSummary{
+		UserID:       id.String(),
+		Currency:     currency,
+		NextRenewals: []types.Renewal{},
+	}
+	if summary.MonthlySpend, err = totalCost(current, currency); err != nil {
+		return types.UserSummary{}, err
+	}
+	if summary.PreviousMonthSpend, err = totalCost(previous, currency); err != nil {
+		return types.UserSummary{}, err
+	}
+	if summary.YearToDateSpend, err = totalCost(yearToDate, currency); err != nil {
+		return types.UserSummary{}, err
+	}
+	if summary.TopServices, err = topServices(current, currency); err != nil {
+		return types.UserSummary{}, err
+	}
+	summary.MonthOverMonthChange = money.New(summary.MonthlySpend.Amount-summary.PreviousMonthSpend.Amount, currency)
+	if summary.PreviousMonthSpend.Amount != 0 {
+		percent := math.Round(float64(summary.MonthOverMonthChange.Amount)/float64(summary.PreviousMonthSpend.Amount)*1000) / 10
+		summary.MonthOverMonthPercent = &percent
+	}
+
+	for _, sub := range subs {
+		if sub.Status != types.StatusActive {
+			continue
+		}
+		summary.ActiveSubscriptions++
+	}
+
+	renewals, err := h.Repo.NextRenewals(id, now, dates.AddMonths(dates.Day(now), 12))
+	if err != nil {
+		return types.UserSummary{}, err
+	}
+	summary.NextRenewals = append(summary.NextRenewals, renewals...)
+	slices.SortStableFunc(summary.NextRenewals, func(a, b types.Renewal) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.ServiceName, b.ServiceName))
+	})
+	if len(summary.NextRenewals) > summaryRenewals {
+		summary.NextRenewals = summary.NextRenewals[:summaryRenewals]
+	}
+	return summary, nil
+}
+
+func totalCost(items []types.CostItem, currency string) (money.Money, error) {
+	total := money.New(0, currency)
+	for _, item := range items {
+		var err error
+		if total, err = total.Add(item.Cost); err != nil {
+			return money.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// topServices adds up the breakdown per service and returns the most
+// expensive ones.
+func topServices(items []types.CostItem, currency string) ([]types.ServiceCost, error) {
+	services := []types.ServiceCost{}
+	for _, item := range items {
+		i := slices.IndexFunc(services, func(sc types.ServiceCost) bool { return sc.ServiceName == item.ServiceName })
+		if i < 0 {
+			services = append(services, types.ServiceCost{ServiceName: item.ServiceName, Cost: money.New(0, currency)})
+			i = len(services) - 1
+		}
+		var err error
+		if services[i].Cost, err = services[i].Cost.Add(item.Cost); err != nil {
+			return nil, err
+		}
+	}
+
+	services = slices.DeleteFunc(services, func(sc types.ServiceCost) bool { return sc.Cost.Amount == 0 })
+	slices.SortStableFunc(services, func(a, b types.ServiceCost) int {
+		return cmp.Compare(b.Cost.Amount, a.Cost.Amount)
+	})
+	if len(services) > summaryTopServices {
+		services = services[:summaryTopServices]
+	}
+	return services, nil
+}
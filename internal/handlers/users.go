@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Список пользователей
+// @Description Получить список пользователей, упорядоченный по имени. Параметры email и external_id позволяют найти пользователя
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param email query string false "Адрес электронной почты, без учета регистра" example("ivan@example.com")
+// @Param external_id query string false "Идентификатор во внешней системе" example("crm-10452")
+// @Success 200 {object} types.ListUsersResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users [get]
+func (h *Handler) ListUsers(c *gin.Context) {
+	h.logStart(c)
+
+	filter := storage.UserFilter{
+		Email:      c.Query("email"),
+		ExternalID: c.Query("external_id"),
+	}
+	users, err := h.Repo.ListUsers(filter)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListUsers")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list users"})
+		return
+	}
+
+	h.logSuccess(c, "Users listed", http.StatusOK, "count", len(users))
+	c.JSON(http.StatusOK, types.ListUsersResponse{
+		Users: users,
+		Count: len(users),
+	})
+}
+
+// @Summary Получить пользователя по ID
+// @Description Получить данные пользователя
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success 200 {object} types.User
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id} [get]
+func (h *Handler) GetUser(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	user, err := h.Repo.GetUser(id)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "GetUser", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "GetUser", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get user"})
+		return
+	}
+
+	h.logSuccess(c, "User retrieved", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, user)
+}
+
+// @Summary Добавить пользователя
+// @Description Зарегистрировать пользователя. Подписки можно создавать только для зарегистрированных пользователей; чтобы зарегистрировать владельца существующих подписок, передайте его ID
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param user body types.User true "Данные пользователя"
+// @Success 201 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 409 {object} types.UserConflictErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users [post]
+func (h *Handler) CreateUser(c *gin.Context) {
+	h.logStart(c)
+
+	var user types.User
+	err := c.ShouldBindJSON(&user)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	id, err := h.Repo.CreateUser(user)
+	if errors.Is(err, storage.ErrUserConflict) {
+		h.logError(c, err, http.StatusConflict, "operation", "CreateUser", "user", user)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "User ID, email or external_id is already used by another user"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "CreateUser", "user", user)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create user"})
+		return
+	}
+
+	h.logSuccess(c, "User created", http.StatusCreated, "id", id)
+	c.JSON(http.StatusCreated, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Обновить пользователя
+// @Description Заменить имя, адрес электронной почты и внешний идентификатор пользователя. Поле id в теле игнорируется
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param user body types.User true "Данные пользователя"
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 409 {object} types.UserConflictErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id} [put]
+func (h *Handler) UpdateUser(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var user types.User
+	err = c.ShouldBindJSON(&user)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	err = h.Repo.UpdateUser(id, user)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "UpdateUser", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if errors.Is(err, storage.ErrUserConflict) {
+		h.logError(c, err, http.StatusConflict, "operation", "UpdateUser", "id", id, "user", user)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "User ID, email or external_id is already used by another user"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "UpdateUser", "id", id, "user", user)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update user"})
+		return
+	}
+
+	h.logSuccess(c, "User updated", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Удалить пользователя
+// @Description Удалить пользователя. Участие в чужих подписках и токен календаря удаляются всегда. Собственные подписки по умолчанию мешают удалению (restrict); их можно удалить вместе с пользователем (delete) или передать другому пользователю (transfer)
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя" example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param subscriptions query string false "Что сделать с подписками пользователя: restrict, delete или transfer" Enums(restrict, delete, transfer) default(restrict)
+// @Param transfer_to query string false "ID пользователя, которому передаются подписки, для transfer" example("2c1e4a6b-8d0f-4a2c-9e1b-3d5f7a9c1e2b")
+// @Success 200 {object} types.DeleteUserResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidUserDeleteErrorResponse
+// @Failure 404 {object} types.UserNotFoundErrorResponse
+// @Failure 409 {object} types.UserHasSubscriptionsErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /users/{id} [delete]
+func (h *Handler) DeleteUser(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	subscriptions := c.DefaultQuery("subscriptions", storage.RestrictUserSubscriptions)
+	var transferTo uuid.UUID
+	if raw := c.Query("transfer_to"); raw != "" {
+		transferTo, err = uuid.Parse(raw)
+		if err != nil {
+			h.logError(c, err, http.StatusBadRequest, "operation", "uuid.Parse", "transfer_to", raw)
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid subscriptions or transfer_to parameter"})
+			return
+		}
+	}
+
+	affected, err := h.Repo.DeleteUser(id, subscriptions, transferTo)
+	switch {
+	case errors.Is(err, storage.ErrInvalidUserDelete):
+		h.logError(c, err, http.StatusBadRequest, "operation", "DeleteUser", "id", id)
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid subscriptions or transfer_to parameter"})
+		return
+	case errors.Is(err, storage.ErrUserNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", "DeleteUser", "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		return
+	case errors.Is(err, storage.ErrUserHasSubscriptions):
+		h.logError(c, err, http.StatusConflict, "operation", "DeleteUser", "id", id)
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: "User still owns subscriptions"})
+		return
+	case err != nil:
+		h.logError(c, err, http.StatusInternalServerError, "operation", "DeleteUser", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to delete user"})
+		return
+	}
+
+	h.logSuccess(c, "User deleted", http.StatusOK, "id", id, "subscriptions", subscriptions, "affected", affected)
+	c.JSON(http.StatusOK, types.DeleteUserResponse{ID: id.String(), Subscriptions: affected})
+}
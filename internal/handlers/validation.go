@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// priceTag is the binding tag of subscription prices: a positive amount with
+// no more decimal places than the subscription's currency has. The importer
+// applies the same rule to imported rows.
+const priceTag = "price"
+
+// dateTag is the binding tag of subscription dates: YYYY-MM-DD or MM-YYYY, as
+// dates.ParseStart and dates.ParseEnd read them.
+const dateTag = "date"
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation(priceTag, func(fl validator.FieldLevel) bool {
+			sub, ok := fl.Parent().Interface().(types.Subscription)
+			if !ok {
+				return false
+			}
+			price, err := sub.Money()
+			return err == nil && price.Amount > 0
+		})
+		v.RegisterValidation(dateTag, func(fl validator.FieldLevel) bool {
+			_, err := dates.ParseStart(fl.Field().String())
+			return err == nil
+		})
+	}
+}
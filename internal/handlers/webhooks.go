@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// @Summary Список вебхуков
+// @Description Получить зарегистрированные адреса для событий об изменении подписок. Секреты не возвращаются
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.ListWebhooksResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks [get]
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	h.logStart(c)
+
+	webhooks, err := h.Repo.ListWebhooks()
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListWebhooks")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list webhooks"})
+		return
+	}
+
+	h.logSuccess(c, "Webhooks listed", http.StatusOK, "count", len(webhooks))
+	c.JSON(http.StatusOK, types.ListWebhooksResponse{
+		Webhooks: webhooks,
+		Count:    len(webhooks),
+	})
+}
+
+// @Summary Получить вебхук по ID
+// @Description Получить адрес, события и состояние вебхука
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID вебхука" example("2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f")
+// @Success 200 {object} types.WebhookEndpoint
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.WebhookNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks/{id} [get]
+func (h *Handler) GetWebhook(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	endpoint, err := h.Repo.GetWebhook(id)
+	if !h.webhookError(c, err, "GetWebhook", id) {
+		return
+	}
+
+	h.logSuccess(c, "Webhook retrieved", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// @Summary Зарегистрировать вебхук
+// @Description Зарегистрировать адрес, на который POST-запросом отправляются события о создании, изменении, удалении и истечении подписок. Каждый запрос подписан: заголовок X-Webhook-Signature содержит sha256= и HMAC-SHA256 от строки "<X-Webhook-Timestamp>.<тело запроса>" с секретом вебхука. Секрет возвращается только в ответе на регистрацию
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Param webhook body types.WebhookEndpoint true "Адрес и события вебхука"
+// @Success 201 {object} types.WebhookEndpoint
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks [post]
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	h.logStart(c)
+
+	var endpoint types.WebhookEndpoint
+	err := c.ShouldBindJSON(&endpoint)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "generate secret")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create webhook"})
+		return
+	}
+	secret := hex.EncodeToString(raw)
+	endpoint.Secret = secret
+
+	id, err := h.Repo.CreateWebhook(endpoint)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "CreateWebhook", "url", endpoint.URL)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create webhook"})
+		return
+	}
+
+	created, err := h.Repo.GetWebhook(id)
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "GetWebhook", "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create webhook"})
+		return
+	}
+	created.Secret = secret
+
+	h.logSuccess(c, "Webhook created", http.StatusCreated, "id", id, "url", created.URL)
+	c.JSON(http.StatusCreated, created)
+}
+
+// @Summary Обновить вебхук
+// @Description Заменить адрес, события и состояние вебхука. Секрет не меняется
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID вебхука" example("2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f")
+// @Param webhook body types.WebhookEndpoint true "Адрес и события вебхука"
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidRequestBodyErrorResponse
+// @Failure 404 {object} types.WebhookNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks/{id} [put]
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	var endpoint types.WebhookEndpoint
+	err = c.ShouldBindJSON(&endpoint)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "ShouldBindJSON")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	err = h.Repo.UpdateWebhook(id, endpoint)
+	if !h.webhookError(c, err, "UpdateWebhook", id) {
+		return
+	}
+
+	h.logSuccess(c, "Webhook updated", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Удалить вебхук
+// @Description Удалить вебхук вместе с историей его доставок
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID вебхука" example("2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f")
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.WebhookNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks/{id} [delete]
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	err = h.Repo.DeleteWebhook(id)
+	if !h.webhookError(c, err, "DeleteWebhook", id) {
+		return
+	}
+
+	h.logSuccess(c, "Webhook deleted", http.StatusOK, "id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// @Summary Доставки вебхука
+// @Description Получить последние доставки событий на вебхук, сначала новые
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Param id path string true "ID вебхука" example("2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f")
+// @Param status query string false "Состояние доставки: pending, delivered или dead" example("dead")
+// @Success 200 {object} types.ListWebhookDeliveriesResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 400 {object} types.InvalidDeliveryStatusErrorResponse
+// @Failure 404 {object} types.WebhookNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := getID(c)
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "getID")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", types.WebhookPending, types.WebhookDelivered, types.WebhookDead:
+	default:
+		err := fmt.Errorf("invalid status %q", status)
+		h.logError(c, err, http.StatusBadRequest, "operation", "parameter validation")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid status"})
+		return
+	}
+
+	deliveries, err := h.Repo.ListWebhookDeliveries(storage.WebhookDeliveryFilter{EndpointID: id, Status: status})
+	if !h.webhookError(c, err, "ListWebhookDeliveries", id) {
+		return
+	}
+
+	h.logSuccess(c, "Webhook deliveries listed", http.StatusOK, "id", id, "count", len(deliveries))
+	c.JSON(http.StatusOK, types.ListWebhookDeliveriesResponse{
+		Deliveries: deliveries,
+		Count:      len(deliveries),
+	})
+}
+
+// @Summary Недоставленные события
+// @Description Получить доставки всех вебхуков, от которых отказались после всех попыток, сначала новые
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.ListWebhookDeliveriesResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks/dead-letters [get]
+func (h *Handler) ListDeadLetters(c *gin.Context) {
+	h.logStart(c)
+
+	deliveries, err := h.Repo.ListWebhookDeliveries(storage.WebhookDeliveryFilter{Status: types.WebhookDead})
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "ListWebhookDeliveries")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list webhook deliveries"})
+		return
+	}
+
+	h.logSuccess(c, "Dead letters listed", http.StatusOK, "count", len(deliveries))
+	c.JSON(http.StatusOK, types.ListWebhookDeliveriesResponse{
+		Deliveries: deliveries,
+		Count:      len(deliveries),
+	})
+}
+
+// @Summary Повторить доставку
+// @Description Снова поставить событие в очередь на доставку с полным набором попыток
+// @Tags Вебхуки
+// @Accept json
+// @Produce json
+// @Param delivery_id path string true "ID доставки" example("7a9c1e3f-5b7d-4f9a-8c1e-3f5b7d9f1a3c")
+// @Success 200 {object} types.IDResponse
+// @Failure 400 {object} types.InvalidIDErrorResponse
+// @Failure 404 {object} types.WebhookDeliveryNotFoundErrorResponse
+// @Failure 500 {object} types.InternalServerErrorResponse
+// @Router /webhooks/deliveries/{delivery_id}/redeliver [post]
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	h.logStart(c)
+
+	id, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		h.logError(c, err, http.StatusBadRequest, "operation", "parse delivery_id")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid ID format"})
+		return
+	}
+
+	err = h.Repo.RedeliverWebhook(id)
+	if errors.Is(err, storage.ErrWebhookDeliveryNotFound) {
+		h.logError(c, err, http.StatusNotFound, "operation", "RedeliverWebhook", "delivery_id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Webhook delivery not found"})
+		return
+	}
+	if err != nil {
+		h.logError(c, err, http.StatusInternalServerError, "operation", "RedeliverWebhook", "delivery_id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to redeliver webhook event"})
+		return
+	}
+
+	h.logSuccess(c, "Webhook event queued for redelivery", http.StatusOK, "delivery_id", id)
+	c.JSON(http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+// webhookError writes the response for a failed webhook request and reports
+// whether the request may go on.
+func (h *Handler) webhookError(c *gin.Context, err error, operation string, id uuid.UUID) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		h.logError(c, err, http.StatusNotFound, "operation", operation, "id", id)
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Webhook not found"})
+	default:
+		h.logError(c, err, http.StatusInternalServerError, "operation", operation, "id", id)
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to process webhook"})
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/ItserX/rest/internal/logger"
+)
+
+func TestCreateWebhookRejectsNonHTTPURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop().Sugar()
+
+	urls := []string{
+		"ftp://crm.example.com/hooks",
+		"mailto:hooks@example.com",
+		"file:///etc/passwd",
+		"crm.example.com/hooks",
+	}
+	for _, url := range urls {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/webhooks", strings.NewReader(`{"url": "`+url+`"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		// A nil repository fails the test if the webhook gets that far.
+		(&Handler{}).CreateWebhook(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("CreateWebhook(%s) status = %d, want 400", url, w.Code)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Fields lists the subscription fields that can be imported, by JSON name.
+var Fields = []string{
+	"service_name", "price", "currency", "billing_interval", "billing_interval_count", "user_id", "start_date", "end_date", "trial_end", "category", "tags",
+}
+
+// Length limits of imported text, the same as the API's.
+const (
+	maxNameLength = 255
+	maxTagLength  = 64
+)
+
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Mapping maps subscription fields to column names in the source file.
+// Fields without an entry are read from a column with the same name.
+type Mapping map[string]string
+
+// ParseMapping parses a "field:column,field:column" specification.
+func ParseMapping(spec string) (Mapping, error) {
+	m := Mapping{}
+	if strings.TrimSpace(spec) == "" {
+		return m, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		field, column, ok := strings.Cut(pair, ":")
+		field = strings.TrimSpace(field)
+		column = strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected field:column", pair)
+		}
+		if !slices.Contains(Fields, field) {
+			return nil, fmt.Errorf("unknown subscription field %q", field)
+		}
+		m[field] = column
+	}
+	return m, nil
+}
+
+func (m Mapping) column(field string) string {
+	if column, ok := m[field]; ok {
+		return column
+	}
+	return field
+}
+
+type Row struct {
+	// Number is the 1-based line (CSV, counting the header) or array position (JSON).
+	Number int
+	Sub    types.Subscription
+}
+
+// Parse reads subscriptions from a CSV file with a header row or from a JSON
+// array of objects. Rows that fail validation are reported as row errors and
+// left out of the returned rows; a non-nil error means the file itself could
+// not be read.
+func Parse(r io.Reader, format string, m Mapping) ([]Row, []types.ImportRowError, error) {
+	records, first, err := readRecords(r, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		rows      []Row
+		rowErrors []types.ImportRowError
+	)
+	for i, record := range records {
+		number := first + i
+		sub, errs := toSubscription(record, m, number)
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		rows = append(rows, Row{Number: number, Sub: sub})
+	}
+	return rows, rowErrors, nil
+}
+
+// readRecords reads the file as a list of column-to-value records and returns
+// the row number of the first record.
+func readRecords(r io.Reader, format string) ([]map[string]string, int, error) {
+	switch format {
+	case FormatCSV:
+		records, err := readCSV(r)
+		return records, 2, err
+	case FormatJSON:
+		records, err := readJSON(r)
+		return records, 1, err
+	default:
+		return nil, 0, ErrUnknownFormat
+	}
+}
+
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	var records []map[string]string
+	for {
+		line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		record := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(line) {
+				record[strings.TrimSpace(column)] = strings.TrimSpace(line[i])
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func readJSON(r io.Reader) ([]map[string]string, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var objects []map[string]interface{}
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("failed to decode json: %w", err)
+	}
+
+	records := make([]map[string]string, 0, len(objects))
+	for _, object := range objects {
+		record := make(map[string]string, len(object))
+		for key, value := range object {
+			switch value := value.(type) {
+			case nil:
+			case []interface{}:
+				items := make([]string, 0, len(value))
+				for _, item := range value {
+					items = append(items, strings.TrimSpace(fmt.Sprint(item)))
+				}
+				record[key] = strings.Join(items, ",")
+			default:
+				record[key] = strings.TrimSpace(fmt.Sprint(value))
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func toSubscription(record map[string]string, m Mapping, number int) (types.Subscription, []types.ImportRowError) {
+	var (
+		sub  types.Subscription
+		errs []types.ImportRowError
+	)
+	fail := func(field, msg string) {
+		errs = append(errs, types.ImportRowError{Row: number, Field: field, Error: msg})
+	}
+	value := func(field string) string {
+		return record[m.column(field)]
+	}
+
+	sub.ServiceName = value("service_name")
+	if sub.ServiceName == "" {
+		fail("service_name", "service_name is required")
+	} else if utf8.RuneCountInString(sub.ServiceName) > maxNameLength {
+		fail("service_name", fmt.Sprintf("service_name must be at most %d characters", maxNameLength))
+	}
+
+	currency := strings.ToUpper(value("currency"))
+	if currency == "" {
+		currency = fx.Base
+	} else if !fx.ValidCode(currency) {
+		fail("currency", "currency must be a three-letter ISO 4217 code")
+	}
+
+	if raw := value("price"); raw == "" {
+		fail("price", "price is required")
+	} else if price, err := money.Parse(raw, currency); err != nil || price.Amount <= 0 {
+		fail("price", "price must be a positive amount in major units, e.g. 399.90")
+	} else {
+		sub.SetMoney(price)
+	}
+
+	sub.BillingInterval = value("billing_interval")
+	if sub.BillingInterval != "" && !billing.ValidInterval(sub.BillingInterval) {
+		fail("billing_interval", "billing_interval must be one of week, month, quarter, year")
+	}
+
+	if raw := value("billing_interval_count"); raw != "" {
+		if count, err := strconv.Atoi(raw); err != nil || count < 1 {
+			fail("billing_interval_count", "billing_interval_count must be a positive integer")
+		} else {
+			sub.BillingIntervalCount = count
+		}
+	}
+
+	if raw := value("user_id"); raw == "" {
+		fail("user_id", "user_id is required")
+	} else if userID, err := uuid.Parse(raw); err != nil {
+		fail("user_id", "user_id must be a UUID")
+	} else {
+		sub.UserID = userID
+	}
+
+	sub.StartDate = value("start_date")
+	if sub.StartDate == "" {
+		fail("start_date", "start_date is required")
+	} else if _, err := dates.ParseStart(sub.StartDate); err != nil {
+		fail("start_date", "start_date must be in YYYY-MM-DD or MM-YYYY format")
+	}
+
+	sub.EndDate = value("end_date")
+	if sub.EndDate != "" {
+		if _, err := dates.ParseEnd(sub.EndDate); err != nil {
+			fail("end_date", "end_date must be in YYYY-MM-DD or MM-YYYY format")
+		}
+	}
+
+	sub.TrialEnd = value("trial_end")
+	if sub.TrialEnd != "" {
+		if _, err := dates.ParseEnd(sub.TrialEnd); err != nil {
+			fail("trial_end", "trial_end must be in YYYY-MM-DD or MM-YYYY format")
+		}
+	}
+
+	sub.Category = value("category")
+	if utf8.RuneCountInString(sub.Category) > maxNameLength {
+		fail("category", fmt.Sprintf("category must be at most %d characters", maxNameLength))
+	}
+
+	if raw := value("tags"); raw != "" {
+		sub.Tags = strings.Split(raw, ",")
+		for _, tag := range sub.Tags {
+			if utf8.RuneCountInString(tag) > maxTagLength {
+				fail("tags", fmt.Sprintf("every tag must be at most %d characters", maxTagLength))
+				break
+			}
+		}
+	}
+
+	return sub, errs
+}
+
+// DetectFormat picks the import format from an explicit name, a content type
+// or a file name, in that order.
+func DetectFormat(explicit, contentType, fileName string) string {
+	switch strings.ToLower(explicit) {
+	case FormatCSV, FormatJSON:
+		return strings.ToLower(explicit)
+	}
+	switch {
+	case strings.Contains(contentType, "csv"):
+		return FormatCSV
+	case strings.Contains(contentType, "json"):
+		return FormatJSON
+	case strings.HasSuffix(strings.ToLower(fileName), ".csv"):
+		return FormatCSV
+	case strings.HasSuffix(strings.ToLower(fileName), ".json"):
+		return FormatJSON
+	}
+	return ""
+}
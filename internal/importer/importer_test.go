@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/types"
+)
+
+const testUserID = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Mapping
+		wantErr bool
+	}{
+		{spec: "", want: Mapping{}},
+		{spec: "   ", want: Mapping{}},
+		{spec: "service_name:Service", want: Mapping{"service_name": "Service"}},
+		{spec: " price : Amount , user_id:Owner ", want: Mapping{"price": "Amount", "user_id": "Owner"}},
+		{spec: "price", wantErr: true},
+		{spec: "price:", wantErr: true},
+		{spec: ":Amount", wantErr: true},
+		{spec: "cost:Amount", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseMapping(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseMapping(%q) = %v, want an error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMapping(%q) error = %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMapping(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "\ufeffservice_name,price,currency,user_id,start_date,tags\n" +
+		"Yandex Plus, 399.90,,  " + testUserID + ",07-2025,\"music,family\"\n" +
+		",0,usd,not-a-uuid,2025-13-01,\n" +
+		"Netflix,9.99,USD," + testUserID + ",2025-07-15,\n"
+
+	rows, rowErrors, err := Parse(strings.NewReader(input), FormatCSV, Mapping{})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Parse() returned %d rows, want 2", len(rows))
+	}
+	first := rows[0]
+	if first.Number != 2 || first.Sub.ServiceName != "Yandex Plus" || first.Sub.Price.String() != "399.90" ||
+		first.Sub.Currency != "RUB" || first.Sub.UserID != uuid.MustParse(testUserID) || first.Sub.StartDate != "07-2025" ||
+		!reflect.DeepEqual(first.Sub.Tags, []string{"music", "family"}) {
+		t.Errorf("first row = %+v", first)
+	}
+	if second := rows[1]; second.Number != 4 || second.Sub.Price.String() != "9.99" || second.Sub.Currency != "USD" {
+		t.Errorf("second row = %+v", second)
+	}
+
+	wantFields := []string{"service_name", "price", "user_id", "start_date"}
+	var gotFields []string
+	for _, rowError := range rowErrors {
+		if rowError.Row != 3 {
+			t.Errorf("row error %+v, want row 3", rowError)
+		}
+		gotFields = append(gotFields, rowError.Field)
+	}
+	if !reflect.DeepEqual(gotFields, wantFields) {
+		t.Errorf("row errors for %v, want %v", gotFields, wantFields)
+	}
+}
+
+func TestParseWithMapping(t *testing.T) {
+	mapping := Mapping{"service_name": "Service", "price": "Amount", "user_id": "Owner", "start_date": "Since"}
+	input := "Service,Amount,Owner,Since\nSpotify,199," + testUserID + ",01-2025\n"
+
+	rows, rowErrors, err := Parse(strings.NewReader(input), FormatCSV, mapping)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(rowErrors) != 0 || len(rows) != 1 {
+		t.Fatalf("Parse() = %+v, %+v; want one valid row", rows, rowErrors)
+	}
+	if sub := rows[0].Sub; sub.ServiceName != "Spotify" || sub.Price.String() != "199.00" || sub.StartDate != "01-2025" {
+		t.Errorf("row = %+v", sub)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `[
+		{"service_name": "Yandex Plus", "price": 399.9, "user_id": "` + testUserID + `", "start_date": "07-2025", "tags": ["music", "family"], "end_date": null},
+		{"service_name": "Netflix", "price": 9.999, "currency": "USD", "user_id": "` + testUserID + `", "start_date": "07-2025"}
+	]`
+
+	rows, rowErrors, err := Parse(strings.NewReader(input), FormatJSON, Mapping{})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Number != 1 || rows[0].Sub.Price.String() != "399.90" ||
+		!reflect.DeepEqual(rows[0].Sub.Tags, []string{"music", "family"}) || rows[0].Sub.EndDate != "" {
+		t.Errorf("rows = %+v", rows)
+	}
+	want := []types.ImportRowError{{Row: 2, Field: "price", Error: "price must be a positive amount in major units, e.g. 399.90"}}
+	if !reflect.DeepEqual(rowErrors, want) {
+		t.Errorf("row errors = %+v, want %+v", rowErrors, want)
+	}
+}
+
+func TestParseRejectsOverlongText(t *testing.T) {
+	long := strings.Repeat("я", 256)
+	input := "service_name,price,user_id,start_date,category,tags\n" +
+		long + ",399," + testUserID + ",07-2025,,\n" +
+		"Yandex Plus,399," + testUserID + ",07-2025," + long + ",\n" +
+		"Yandex Plus,399," + testUserID + ",07-2025,,\"music," + strings.Repeat("a", 65) + "\"\n" +
+		strings.Repeat("я", 255) + ",399," + testUserID + ",07-2025," + strings.Repeat("я", 255) + "," + strings.Repeat("a", 64) + "\n"
+
+	rows, rowErrors, err := Parse(strings.NewReader(input), FormatCSV, Mapping{})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Number != 5 {
+		t.Errorf("rows = %+v, want only row 5 at the limits", rows)
+	}
+
+	want := []types.ImportRowError{
+		{Row: 2, Field: "service_name", Error: "service_name must be at most 255 characters"},
+		{Row: 3, Field: "category", Error: "category must be at most 255 characters"},
+		{Row: 4, Field: "tags", Error: "every tag must be at most 64 characters"},
+	}
+	if !reflect.DeepEqual(rowErrors, want) {
+		t.Errorf("row errors = %+v, want %+v", rowErrors, want)
+	}
+}
+
+func TestParseRejectsUnreadableFiles(t *testing.T) {
+	if _, _, err := Parse(strings.NewReader(""), FormatCSV, Mapping{}); err == nil {
+		t.Error("Parse() of an empty CSV succeeded")
+	}
+	if _, _, err := Parse(strings.NewReader(`{"service_name": "Netflix"}`), FormatJSON, Mapping{}); err == nil {
+		t.Error("Parse() of a JSON object succeeded")
+	}
+	if _, _, err := Parse(strings.NewReader("a,b\n"), "xml", Mapping{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Parse() of an unknown format error = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		explicit, contentType, fileName string
+		want                            string
+	}{
+		{explicit: "CSV", contentType: "application/json", want: FormatCSV},
+		{contentType: "text/csv; charset=utf-8", fileName: "subs.json", want: FormatCSV},
+		{contentType: "application/json", want: FormatJSON},
+		{contentType: "application/octet-stream", fileName: "Subs.CSV", want: FormatCSV},
+		{fileName: "subs.json", want: FormatJSON},
+		{fileName: "subs.xlsx", want: ""},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.explicit, tt.contentType, tt.fileName); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q, %q) = %q, want %q", tt.explicit, tt.contentType, tt.fileName, got, tt.want)
+		}
+	}
+}
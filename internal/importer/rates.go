@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/fx"
+)
+
+// ParseRates reads exchange rates from a CSV file with a currency, date and
+// rate header or from a JSON array of objects with the same keys. The first
+// invalid row fails the whole file.
+func ParseRates(r io.Reader, format string) ([]fx.Rate, error) {
+	records, first, err := readRecords(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]fx.Rate, 0, len(records))
+	for i, record := range records {
+		rate, err := toRate(record)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", first+i, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// ParseRatesFile reads exchange rates from a .csv or .json file.
+func ParseRatesFile(path string) ([]fx.Rate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseRates(file, DetectFormat("", "", filepath.Base(path)))
+}
+
+func toRate(record map[string]string) (fx.Rate, error) {
+	var rate fx.Rate
+
+	rate.Currency = strings.ToUpper(record["currency"])
+	if !fx.ValidCode(rate.Currency) {
+		return rate, fmt.Errorf("currency must be a three-letter ISO 4217 code")
+	}
+
+	date, err := dates.ParseStart(record["date"])
+	if err != nil {
+		return rate, fmt.Errorf("date must be in YYYY-MM-DD or MM-YYYY format")
+	}
+	rate.Date = date
+
+	value, ok := new(big.Rat).SetString(record["rate"])
+	if !ok || value.Sign() <= 0 {
+		return rate, fmt.Errorf("rate must be a positive number")
+	}
+	rate.Rate = value
+
+	return rate, nil
+}
@@ -0,0 +1,141 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// legacyCurrency is the currency of prices sent as a bare number of major
+// units, the format used before prices carried a currency.
+const legacyCurrency = "RUB"
+
+// exponents lists currencies whose minor unit is not a hundredth.
+var exponents = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"VND": 0,
+}
+
+// Exponent returns the number of decimal places of currency's minor unit.
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// @Description Денежная сумма в минимальных единицах валюты (копейках, центах)
+type Money struct {
+	// Сумма в минимальных единицах валюты
+	Amount int64 `json:"amount" binding:"min=0" example:"39900"`
+	// Код валюты ISO 4217
+	Currency string `json:"currency" binding:"required,iso4217" example:"RUB"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Parse reads a decimal amount in major units, such as "3.99", rejecting more
+// decimal places than currency has.
+func Parse(s, currency string) (Money, error) {
+	s = strings.TrimSpace(strings.Replace(s, ",", ".", 1))
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	minor := new(big.Rat).Mul(r, Scale(Exponent(currency)))
+	if !minor.IsInt() {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places", s, Exponent(currency))
+	}
+	if !minor.Num().IsInt64() {
+		return Money{}, fmt.Errorf("amount %q is too large", s)
+	}
+	return Money{Amount: minor.Num().Int64(), Currency: currency}, nil
+}
+
+// Decimal renders the amount in major units with all minor digits.
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+	return new(big.Rat).Quo(new(big.Rat).SetInt64(m.Amount), Scale(exp)).FloatString(exp)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// ErrCurrencyMismatch is returned when adding amounts in different currencies.
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Add returns the sum of two amounts in the same currency, or ErrOverflow if
+// it does not fit into int64 minor units.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: cannot add %s to %s", ErrCurrencyMismatch, other.Currency, m.Currency)
+	}
+	sum := m.Amount + other.Amount
+	if (sum > m.Amount) != (other.Amount > 0) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrOverflow, m, other)
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// UnmarshalJSON accepts {"amount": 39900, "currency": "RUB"} and, for
+// backward compatibility, a bare number of rubles such as 399.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' && !bytes.Equal(data, []byte("null")) {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("price must be an object or a number: %w", err)
+		}
+		parsed, err := Parse(number.String(), legacyCurrency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	type plain Money
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// ErrOverflow is returned when an amount does not fit into int64 minor units.
+var ErrOverflow = errors.New("amount out of range")
+
+// Round converts an exact amount of minor units to an integer, rounding
+// halves away from zero.
+func Round(r *big.Rat) (int64, error) {
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%w: %s minor units", ErrOverflow, q)
+	}
+	return q.Int64(), nil
+}
+
+// Scale returns 10^exp as an exact rational, the number of minor units in a
+// major unit of a currency with that exponent.
+func Scale(exp int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+}
@@ -0,0 +1,172 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{in: "399.90", currency: "RUB", want: 39990},
+		{in: "399.9", currency: "RUB", want: 39990},
+		{in: "399", currency: "RUB", want: 39900},
+		{in: " 399,90 ", currency: "RUB", want: 39990},
+		{in: "0.01", currency: "USD", want: 1},
+		{in: "0", currency: "USD", want: 0},
+		{in: "1500", currency: "JPY", want: 1500},
+		{in: "1.234", currency: "KWD", want: 1234},
+		{in: "0.001", currency: "RUB", wantErr: true},
+		{in: "1.5", currency: "JPY", wantErr: true},
+		{in: "1.2345", currency: "KWD", wantErr: true},
+		{in: "1e3", currency: "RUB", wantErr: true},
+		{in: "1/2", currency: "RUB", wantErr: true},
+		{in: "1,000.00", currency: "RUB", wantErr: true},
+		{in: "abc", currency: "RUB", wantErr: true},
+		{in: "", currency: "RUB", wantErr: true},
+		{in: "92233720368547758.08", currency: "RUB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency+" "+tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in, tt.currency)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q, %s) = %v, want an error", tt.in, tt.currency, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q, %s) error = %v", tt.in, tt.currency, err)
+			}
+			if want := New(tt.want, tt.currency); got != want {
+				t.Errorf("Parse(%q, %s) = %v, want %v", tt.in, tt.currency, got, want)
+			}
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{m: New(39990, "RUB"), want: "399.90"},
+		{m: New(5, "USD"), want: "0.05"},
+		{m: New(1500, "JPY"), want: "1500"},
+		{m: New(1234, "KWD"), want: "1.234"},
+		{m: New(-150, "EUR"), want: "-1.50"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.Decimal(); got != tt.want {
+			t.Errorf("%#v.Decimal() = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		r    *big.Rat
+		want int64
+	}{
+		{r: big.NewRat(5, 2), want: 3},
+		{r: big.NewRat(-5, 2), want: -3},
+		{r: big.NewRat(7, 3), want: 2},
+		{r: big.NewRat(-7, 3), want: -2},
+		{r: big.NewRat(8, 3), want: 3},
+		{r: big.NewRat(1, 2), want: 1},
+		{r: big.NewRat(1, 3), want: 0},
+		{r: big.NewRat(42, 1), want: 42},
+		{r: big.NewRat(math.MaxInt64, 1), want: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		got, err := Round(tt.r)
+		if err != nil || got != tt.want {
+			t.Errorf("Round(%s) = %d, %v; want %d", tt.r, got, err, tt.want)
+		}
+	}
+}
+
+func TestRoundOverflow(t *testing.T) {
+	huge := new(big.Rat).Mul(big.NewRat(math.MaxInt64, 1), big.NewRat(3, 2))
+	for _, r := range []*big.Rat{huge, new(big.Rat).Neg(huge), new(big.Rat).Add(big.NewRat(math.MaxInt64, 1), big.NewRat(1, 2))} {
+		if got, err := Round(r); !errors.Is(err, ErrOverflow) {
+			t.Errorf("Round(%s) = %d, %v; want ErrOverflow", r, got, err)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		exp  int
+		want int64
+	}{
+		{exp: 0, want: 1},
+		{exp: 2, want: 100},
+		{exp: 3, want: 1000},
+	}
+
+	for _, tt := range tests {
+		if got := Scale(tt.exp); got.Cmp(big.NewRat(tt.want, 1)) != 0 {
+			t.Errorf("Scale(%d) = %s, want %d", tt.exp, got, tt.want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	sum, err := New(100, "RUB").Add(New(250, "RUB"))
+	if err != nil || sum != New(350, "RUB") {
+		t.Errorf("Add() = %v, %v; want 3.50 RUB", sum, err)
+	}
+	if _, err := New(100, "RUB").Add(New(100, "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add() of different currencies error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := New(math.MaxInt64, "RUB").Add(New(1, "RUB")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add() past the int64 range error = %v, want ErrOverflow", err)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `{"amount": 39990, "currency": "USD"}`, want: New(39990, "USD")},
+		{in: `399`, want: New(39900, "RUB")},
+		{in: `399.9`, want: New(39990, "RUB")},
+		{in: ` 9.99 `, want: New(999, "RUB")},
+		{in: `null`, want: Money{}},
+		{in: `399.999`, wantErr: true},
+		{in: `true`, wantErr: true},
+		{in: `{"amount": "many"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Unmarshal(%s) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
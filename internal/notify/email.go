@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"time"
+)
+
+// EmailNotifier sends messages as plain text email through an SMTP server to
+// the user's email address.
+type EmailNotifier struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	From string
+	// Auth authenticates with the server; nil sends without authentication.
+	Auth smtp.Auth
+}
+
+func (n *EmailNotifier) Channel() string {
+	return "email"
+}
+
+func (n *EmailNotifier) Recipient(msg Message) string {
+	return msg.User.Email
+}
+
+func (n *EmailNotifier) Send(ctx context.Context, msg Message) error {
+	if msg.User.Email == "" {
+		return ErrNoAddress
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", n.From)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.User.Email)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: base64\r\n")
+	body.WriteString("\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Text))
+	for len(encoded) > 76 {
+		body.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	body.WriteString(encoded + "\r\n")
+
+	if err := smtp.SendMail(n.Addr, n.Auth, n.From, []string{msg.User.Email}, body.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// Events a notification can be sent for.
+const (
+	EventRenewalReminder     = "reminder.renewal"
+	EventExpiryReminder      = "reminder.expiry"
+	EventBudgetAlert         = "budget.alert"
+	EventSubscriptionCreated = types.EventSubscriptionCreated
+	EventSubscriptionUpdated = types.EventSubscriptionUpdated
+	EventSubscriptionDeleted = types.EventSubscriptionDeleted
+)
+
+var (
+	// ErrNoAddress is returned by a notifier that has nowhere to send the
+	// message to, such as email for a user without an address. It is not
+	// retried.
+	ErrNoAddress = errors.New("recipient has no address for this channel")
+	// ErrNotDelivered is returned when no notifier delivered a message.
+	ErrNotDelivered = errors.New("notification not delivered")
+)
+
+// Message is a notification rendered for its recipient.
+type Message struct {
+	Event string
+	// EventID is the subscription event the message is about, if any.
+	EventID  string
+	User     types.User
+	Subject  string
+	Text     string
+	Data     any
+	Language string
+}
+
+// Notifier delivers messages over one channel.
+type Notifier interface {
+	// Channel names the channel in the delivery log.
+	Channel() string
+	// Recipient is where msg would be delivered, for the delivery log.
+	Recipient(msg Message) string
+	Send(ctx context.Context, msg Message) error
+}
+
+// Store is what the dispatcher needs from the repository: recipients and
+// budgets to address notifications, the reminders and budget alerts still to
+// be sent and a place to log deliveries.
+type Store interface {
+	GetUser(id uuid.UUID) (*types.User, error)
+	GetBudget(id uuid.UUID) (*types.Budget, error)
+	ClaimReminders(lease time.Duration, now time.Time) ([]types.Reminder, error)
+	MarkReminderNotified(reminder types.Reminder) error
+	ClaimBudgetAlerts(lease time.Duration) ([]types.BudgetAlert, error)
+	MarkBudgetAlertNotified(alert types.BudgetAlert) error
+	RecordDelivery(delivery types.Delivery) error
+	EventDelivered(eventID uuid.UUID, channel string) (bool, error)
+}
+
+// claimLease is how long reminders and budget alerts claimed for sending are
+// left alone by other runs. It outlasts the retries of a send with the
+// default settings.
+const claimLease = 15 * time.Minute
+
+// Dispatcher renders notifications in the recipient's language and sends
+// them through every notifier, retrying failed sends with exponential
+// backoff. Every send ends up in the delivery log.
+type Dispatcher struct {
+	Store     Store
+	Notifiers []Notifier
+	// Attempts is how many times a send is tried before it is logged as
+	// failed.
+	Attempts int
+	// Backoff is the wait before the first retry; it doubles with every
+	// retry after that.
+	Backoff time.Duration
+}
+
+// BudgetAlertData is what budget alert templates are rendered with.
+type BudgetAlertData struct {
+	Budget types.Budget      `json:"budget"`
+	Alert  types.BudgetAlert `json:"alert"`
+}
+
+// PendingReminders notifies the owners of the subscriptions the reminders
+// not sent yet are for, and marks each reminder sent once a channel delivered
+// it. A reminder cut short by ctx or a crash, or that no channel delivered,
+// stays pending and is sent by a later call once its claim runs out.
+func (d *Dispatcher) PendingReminders(ctx context.Context, now time.Time) {
+	reminders, err := d.Store.ClaimReminders(claimLease, now)
+	if err != nil {
+		logger.Logger.Errorw("Failed to claim reminders",
+			"error", err,
+		)
+		return
+	}
+	for _, reminder := range reminders {
+		event := EventRenewalReminder
+		if reminder.Kind == types.ReminderExpiry {
+			event = EventExpiryReminder
+		}
+		err := d.NotifyUser(ctx, reminder.UserID, event, reminder)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Logger.Warnw("Reminder left pending",
+				"error", err,
+				"reminderID", reminder.ID,
+			)
+			continue
+		}
+		if err := d.Store.MarkReminderNotified(reminder); err != nil {
+			logger.Logger.Errorw("Failed to mark reminder notified",
+				"error", err,
+				"reminderID", reminder.ID,
+			)
+		}
+	}
+}
+
+// budgetAlert notifies every user whose spending a budget covers that it
+// reached a threshold. It fails if no channel delivered the alert to any of
+// them.
+func (d *Dispatcher) budgetAlert(ctx context.Context, alert types.BudgetAlert) error {
+	id, err := uuid.Parse(alert.BudgetID)
+	if err != nil {
+		return fmt.Errorf("invalid budget id in alert: %w", err)
+	}
+	budget, err := d.Store.GetBudget(id)
+	if err != nil {
+		return fmt.Errorf("failed to get budget for alert: %w", err)
+	}
+
+	var (
+		failed    error
+		delivered bool
+	)
+	for _, userID := range budget.UserIDs {
+		if err := d.NotifyUser(ctx, userID, EventBudgetAlert, BudgetAlertData{Budget: *budget, Alert: alert}); err != nil {
+			failed = err
+			continue
+		}
+		delivered = true
+	}
+	if !delivered {
+		return failed
+	}
+	return nil
+}
+
+// PendingBudgetAlerts sends the budget alerts that have not been sent yet
+// and marks each alert sent once a channel delivered it. An alert cut short
+// by ctx or a crash, or that no channel delivered, stays pending and is sent
+// by a later call once its claim runs out.
+func (d *Dispatcher) PendingBudgetAlerts(ctx context.Context) {
+	alerts, err := d.Store.ClaimBudgetAlerts(claimLease)
+	if err != nil {
+		logger.Logger.Errorw("Failed to claim budget alerts",
+			"error", err,
+		)
+		return
+	}
+	for _, alert := range alerts {
+		err := d.budgetAlert(ctx, alert)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Logger.Warnw("Budget alert left pending",
+				"error", err,
+				"budgetID", alert.BudgetID,
+				"threshold", alert.Threshold,
+			)
+			continue
+		}
+		if err := d.Store.MarkBudgetAlertNotified(alert); err != nil {
+			logger.Logger.Errorw("Failed to mark budget alert notified",
+				"error", err,
+				"budgetID", alert.BudgetID,
+			)
+		}
+	}
+}
+
+// SubscriptionChanged notifies the owner of the event's subscription that it
+// was created, updated or deleted. Channels that already delivered the event
+// are skipped, so a failed event can be handed in again until every channel
+// has delivered it; the error is that of a channel that did not.
+func (d *Dispatcher) SubscriptionChanged(ctx context.Context, event types.SubscriptionEvent) error {
+	if event.Data == nil {
+		return nil
+	}
+	eventID, err := uuid.Parse(event.ID)
+	if err != nil {
+		return fmt.Errorf("invalid event id: %w", err)
+	}
+	user, err := d.Store.GetUser(event.Data.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get notification recipient: %w", err)
+	}
+	if len(d.Notifiers) == 0 {
+		return nil
+	}
+
+	msg, err := render(event.Type, *user, *event.Data)
+	if err != nil {
+		return err
+	}
+	msg.EventID = event.ID
+
+	var failed error
+	for _, notifier := range d.Notifiers {
+		delivered, err := d.Store.EventDelivered(eventID, notifier.Channel())
+		if err != nil {
+			failed = err
+			continue
+		}
+		if delivered {
+			continue
+		}
+		if err := d.deliver(ctx, notifier, msg); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// NotifyUser looks the user up and sends them a notification about event.
+// Like Notify, it fails if no notifier delivered it.
+func (d *Dispatcher) NotifyUser(ctx context.Context, userID uuid.UUID, event string, data any) error {
+	user, err := d.Store.GetUser(userID)
+	if err != nil {
+		logger.Logger.Errorw("Failed to get notification recipient",
+			"error", err,
+			"userID", userID,
+			"event", event,
+		)
+		return fmt.Errorf("failed to get notification recipient: %w", err)
+	}
+	return d.Notify(ctx, *user, event, data)
+}
+
+// Notify renders the event's template with data and sends it to user
+// through every notifier. It returns ErrNotDelivered, along with the error of
+// a notifier, if none of them delivered it. A notifier without an address for
+// the user counts as done, since trying again would not change that.
+func (d *Dispatcher) Notify(ctx context.Context, user types.User, event string, data any) error {
+	if len(d.Notifiers) == 0 {
+		return nil
+	}
+
+	msg, err := render(event, user, data)
+	if err != nil {
+		logger.Logger.Errorw("Failed to render notification",
+			"error", err,
+			"userID", user.ID,
+			"event", event,
+		)
+		return err
+	}
+
+	var (
+		failed    error
+		delivered bool
+	)
+	for _, notifier := range d.Notifiers {
+		if err := d.deliver(ctx, notifier, msg); err != nil {
+			failed = err
+			continue
+		}
+		delivered = true
+	}
+	if !delivered {
+		return fmt.Errorf("%w: %w", ErrNotDelivered, failed)
+	}
+	return nil
+}
+
+// deliver sends msg through notifier, retrying as configured, and logs the
+// outcome. It returns the error of a send that failed, or of logging it.
+func (d *Dispatcher) deliver(ctx context.Context, notifier Notifier, msg Message) error {
+	delivery := types.Delivery{
+		Channel:   notifier.Channel(),
+		Event:     msg.Event,
+		EventID:   msg.EventID,
+		Recipient: notifier.Recipient(msg),
+		Subject:   msg.Subject,
+	}
+	if userID, err := uuid.Parse(msg.User.ID); err == nil {
+		delivery.UserID = userID
+	}
+
+	err := d.send(ctx, notifier, msg, &delivery.Attempts)
+	switch {
+	case errors.Is(err, ErrNoAddress):
+		err = nil
+		delivery.Status = types.DeliverySkipped
+	case err != nil:
+		delivery.Status = types.DeliveryFailed
+		delivery.Error = err.Error()
+		logger.Logger.Errorw("Notification delivery failed",
+			"error", err,
+			"channel", delivery.Channel,
+			"event", delivery.Event,
+			"userID", delivery.UserID,
+			"attempts", delivery.Attempts,
+		)
+	default:
+		delivery.Status = types.DeliverySent
+		logger.Logger.Infow("Notification delivered",
+			"channel", delivery.Channel,
+			"event", delivery.Event,
+			"userID", delivery.UserID,
+			"attempts", delivery.Attempts,
+		)
+	}
+
+	if recordErr := d.Store.RecordDelivery(delivery); recordErr != nil {
+		logger.Logger.Errorw("Failed to record notification delivery",
+			"error", recordErr,
+			"channel", delivery.Channel,
+			"event", delivery.Event,
+		)
+		if err == nil {
+			err = recordErr
+		}
+	}
+	return err
+}
+
+// send tries notifier until it succeeds, the attempts run out or ctx is
+// done, counting the tries in attempts.
+func (d *Dispatcher) send(ctx context.Context, notifier Notifier, msg Message, attempts *int) error {
+	backoff := d.Backoff
+	for {
+		*attempts++
+		err := notifier.Send(ctx, msg)
+		if err == nil || errors.Is(err, ErrNoAddress) || *attempts >= d.Attempts {
+			return err
+		}
+
+		logger.Logger.Warnw("Notification delivery attempt failed",
+			"error", err,
+			"channel", notifier.Channel(),
+			"event", msg.Event,
+			"attempt", *attempts,
+			"retryIn", backoff,
+		)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up after %d attempts)", err, *attempts)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,512 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop().Sugar()
+	m.Run()
+}
+
+type fakeStore struct {
+	users             map[uuid.UUID]types.User
+	budgets           map[uuid.UUID]types.Budget
+	pendingReminders  []types.Reminder
+	notifiedReminders []types.Reminder
+	pending           []types.BudgetAlert
+	notified          []types.BudgetAlert
+	deliveries        []types.Delivery
+}
+
+func (s *fakeStore) GetUser(id uuid.UUID) (*types.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (s *fakeStore) GetBudget(id uuid.UUID) (*types.Budget, error) {
+	budget, ok := s.budgets[id]
+	if !ok {
+		return nil, errors.New("budget not found")
+	}
+	return &budget, nil
+}
+
+func (s *fakeStore) ClaimReminders(time.Duration, time.Time) ([]types.Reminder, error) {
+	reminders := s.pendingReminders
+	s.pendingReminders = nil
+	return reminders, nil
+}
+
+func (s *fakeStore) MarkReminderNotified(reminder types.Reminder) error {
+	s.notifiedReminders = append(s.notifiedReminders, reminder)
+	return nil
+}
+
+func (s *fakeStore) ClaimBudgetAlerts(time.Duration) ([]types.BudgetAlert, error) {
+	alerts := s.pending
+	s.pending = nil
+	return alerts, nil
+}
+
+func (s *fakeStore) MarkBudgetAlertNotified(alert types.BudgetAlert) error {
+	s.notified = append(s.notified, alert)
+	return nil
+}
+
+func (s *fakeStore) RecordDelivery(delivery types.Delivery) error {
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func (s *fakeStore) EventDelivered(eventID uuid.UUID, channel string) (bool, error) {
+	for _, delivery := range s.deliveries {
+		if delivery.EventID == eventID.String() && delivery.Channel == channel && delivery.Status != types.DeliveryFailed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// flakyNotifier fails the first failures sends with err.
+type flakyNotifier struct {
+	failures int
+	err      error
+	sent     []Message
+}
+
+func (n *flakyNotifier) Channel() string { return "test" }
+
+func (n *flakyNotifier) Recipient(msg Message) string { return msg.User.Email }
+
+func (n *flakyNotifier) Send(_ context.Context, msg Message) error {
+	if n.failures > 0 {
+		n.failures--
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func testUser(language string) types.User {
+	return types.User{
+		ID:       uuid.NewString(),
+		Name:     "Ivan",
+		Email:    "ivan@example.com",
+		Language: language,
+	}
+}
+
+func testReminder() types.Reminder {
+	price := money.New(39990, "RUB")
+	return types.Reminder{
+		ServiceName: "Yandex Plus",
+		Kind:        types.ReminderRenewal,
+		Date:        "2026-03-15",
+		Price:       &price,
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantAttempts int
+		wantStatus   string
+	}{
+		{name: "first attempt", failures: 0, wantAttempts: 1, wantStatus: types.DeliverySent},
+		{name: "after a retry", failures: 2, wantAttempts: 3, wantStatus: types.DeliverySent},
+		{name: "attempts run out", failures: 5, wantAttempts: 3, wantStatus: types.DeliveryFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			notifier := &flakyNotifier{failures: tt.failures, err: errors.New("unavailable")}
+			d := &Dispatcher{Store: store, Notifiers: []Notifier{notifier}, Attempts: 3, Backoff: time.Millisecond}
+
+			err := d.Notify(context.Background(), testUser(types.LanguageRussian), EventRenewalReminder, testReminder())
+
+			if delivered := tt.wantStatus == types.DeliverySent; delivered != (err == nil) {
+				t.Errorf("Notify() error = %v, want delivered = %v", err, delivered)
+			}
+			if len(store.deliveries) != 1 {
+				t.Fatalf("recorded %d deliveries, want 1", len(store.deliveries))
+			}
+			delivery := store.deliveries[0]
+			if delivery.Attempts != tt.wantAttempts || delivery.Status != tt.wantStatus {
+				t.Errorf("delivery = %d attempts, %s; want %d attempts, %s", delivery.Attempts, delivery.Status, tt.wantAttempts, tt.wantStatus)
+			}
+			if tt.wantStatus == types.DeliveryFailed && delivery.Error == "" {
+				t.Error("failed delivery has no error")
+			}
+		})
+	}
+}
+
+func TestDispatcherSkipsRecipientsWithoutAddress(t *testing.T) {
+	store := &fakeStore{}
+	notifier := &flakyNotifier{failures: 5, err: ErrNoAddress}
+	d := &Dispatcher{Store: store, Notifiers: []Notifier{notifier}, Attempts: 3, Backoff: time.Millisecond}
+
+	if err := d.Notify(context.Background(), testUser(types.LanguageRussian), EventRenewalReminder, testReminder()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if len(store.deliveries) != 1 {
+		t.Fatalf("recorded %d deliveries, want 1", len(store.deliveries))
+	}
+	if delivery := store.deliveries[0]; delivery.Attempts != 1 || delivery.Status != types.DeliverySkipped {
+		t.Errorf("delivery = %d attempts, %s; want 1 attempt, skipped", delivery.Attempts, delivery.Status)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		language     string
+		wantLanguage string
+		wantSubject  string
+		wantText     string
+	}{
+		{
+			language:     types.LanguageRussian,
+			wantLanguage: types.LanguageRussian,
+			wantSubject:  "Скоро продление подписки Yandex Plus",
+			wantText:     "Подписка Yandex Plus будет продлена 2026-03-15, к списанию 399.90 RUB.",
+		},
+		{
+			language:     types.LanguageEnglish,
+			wantLanguage: types.LanguageEnglish,
+			wantSubject:  "Your Yandex Plus subscription renews soon",
+			wantText:     "Your Yandex Plus subscription renews on 2026-03-15 for 399.90 RUB.",
+		},
+		{
+			language:     "",
+			wantLanguage: types.LanguageRussian,
+			wantSubject:  "Скоро продление подписки Yandex Plus",
+			wantText:     "Подписка Yandex Plus будет продлена 2026-03-15, к списанию 399.90 RUB.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantLanguage+"/"+tt.language, func(t *testing.T) {
+			msg, err := render(EventRenewalReminder, testUser(tt.language), testReminder())
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			if msg.Language != tt.wantLanguage || msg.Subject != tt.wantSubject || msg.Text != tt.wantText {
+				t.Errorf("render() = %q, %q, %q; want %q, %q, %q", msg.Language, msg.Subject, msg.Text, tt.wantLanguage, tt.wantSubject, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestRenderRejectsUnknownEvent(t *testing.T) {
+	if _, err := render("reminder.unknown", testUser(types.LanguageRussian), nil); err == nil {
+		t.Error("render() of an unknown event succeeded")
+	}
+}
+
+func TestPendingBudgetAlerts(t *testing.T) {
+	budgetID, userID := uuid.New(), uuid.New()
+	user := testUser(types.LanguageEnglish)
+	user.ID = userID.String()
+	alert := types.BudgetAlert{BudgetID: budgetID.String(), PeriodStart: "03-2026", Threshold: 80, Spent: money.New(80000, "RUB")}
+
+	t.Run("sent and marked", func(t *testing.T) {
+		store := &fakeStore{
+			users:   map[uuid.UUID]types.User{userID: user},
+			budgets: map[uuid.UUID]types.Budget{budgetID: {Name: "Home", UserIDs: []uuid.UUID{userID}, Amount: money.New(100000, "RUB")}},
+			pending: []types.BudgetAlert{alert},
+		}
+		notifier := &flakyNotifier{}
+		d := &Dispatcher{Store: store, Notifiers: []Notifier{notifier}, Attempts: 1}
+
+		d.PendingBudgetAlerts(context.Background())
+
+		if len(notifier.sent) != 1 || notifier.sent[0].Event != EventBudgetAlert {
+			t.Fatalf("sent %+v, want one budget alert", notifier.sent)
+		}
+		if len(store.notified) != 1 || store.notified[0] != alert {
+			t.Errorf("marked %+v, want the alert", store.notified)
+		}
+	})
+
+	t.Run("left pending when every channel fails", func(t *testing.T) {
+		store := &fakeStore{
+			users:   map[uuid.UUID]types.User{userID: user},
+			budgets: map[uuid.UUID]types.Budget{budgetID: {Name: "Home", UserIDs: []uuid.UUID{userID}, Amount: money.New(100000, "RUB")}},
+			pending: []types.BudgetAlert{alert},
+		}
+		unavailable := errors.New("unavailable")
+		d := &Dispatcher{
+			Store:     store,
+			Notifiers: []Notifier{&flakyNotifier{failures: 5, err: unavailable}, &flakyNotifier{failures: 5, err: unavailable}},
+			Attempts:  2,
+			Backoff:   time.Millisecond,
+		}
+
+		d.PendingBudgetAlerts(context.Background())
+
+		if len(store.notified) != 0 {
+			t.Errorf("marked %+v, want none", store.notified)
+		}
+		if len(store.deliveries) != 2 {
+			t.Errorf("recorded %d deliveries, want one per channel", len(store.deliveries))
+		}
+	})
+
+	t.Run("left pending when cancelled", func(t *testing.T) {
+		store := &fakeStore{
+			users:   map[uuid.UUID]types.User{userID: user},
+			budgets: map[uuid.UUID]types.Budget{budgetID: {Name: "Home", UserIDs: []uuid.UUID{userID}, Amount: money.New(100000, "RUB")}},
+			pending: []types.BudgetAlert{alert},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		d := &Dispatcher{Store: store, Notifiers: []Notifier{&flakyNotifier{failures: 1, err: errors.New("unavailable")}}, Attempts: 3, Backoff: time.Hour}
+
+		d.PendingBudgetAlerts(ctx)
+
+		if len(store.notified) != 0 {
+			t.Errorf("marked %+v, want none", store.notified)
+		}
+	})
+}
+
+func TestPendingReminders(t *testing.T) {
+	userID := uuid.New()
+	user := testUser(types.LanguageRussian)
+	user.ID = userID.String()
+	renewal := testReminder()
+	renewal.ID, renewal.UserID = uuid.NewString(), userID
+	expiry := testReminder()
+	expiry.ID, expiry.UserID, expiry.Kind, expiry.Price = uuid.NewString(), userID, types.ReminderExpiry, nil
+
+	t.Run("sent and marked", func(t *testing.T) {
+		store := &fakeStore{
+			users:            map[uuid.UUID]types.User{userID: user},
+			pendingReminders: []types.Reminder{renewal, expiry},
+		}
+		notifier := &flakyNotifier{}
+		d := &Dispatcher{Store: store, Notifiers: []Notifier{notifier}, Attempts: 1}
+
+		d.PendingReminders(context.Background(), time.Now())
+
+		if len(notifier.sent) != 2 || notifier.sent[0].Event != EventRenewalReminder || notifier.sent[1].Event != EventExpiryReminder {
+			t.Fatalf("sent %+v, want a renewal and an expiry reminder", notifier.sent)
+		}
+		if len(store.notifiedReminders) != 2 {
+			t.Errorf("marked %d reminders, want 2", len(store.notifiedReminders))
+		}
+	})
+
+	t.Run("left pending when every channel fails", func(t *testing.T) {
+		store := &fakeStore{
+			users:            map[uuid.UUID]types.User{userID: user},
+			pendingReminders: []types.Reminder{renewal},
+		}
+		d := &Dispatcher{Store: store, Notifiers: []Notifier{&flakyNotifier{failures: 5, err: errors.New("unavailable")}}, Attempts: 2, Backoff: time.Millisecond}
+
+		d.PendingReminders(context.Background(), time.Now())
+
+		if len(store.notifiedReminders) != 0 {
+			t.Errorf("marked %+v, want none", store.notifiedReminders)
+		}
+	})
+
+	t.Run("marked when one channel delivers", func(t *testing.T) {
+		store := &fakeStore{
+			users:            map[uuid.UUID]types.User{userID: user},
+			pendingReminders: []types.Reminder{renewal},
+		}
+		d := &Dispatcher{
+			Store:     store,
+			Notifiers: []Notifier{&flakyNotifier{failures: 5, err: errors.New("unavailable")}, &flakyNotifier{}},
+			Attempts:  1,
+		}
+
+		d.PendingReminders(context.Background(), time.Now())
+
+		if len(store.notifiedReminders) != 1 {
+			t.Errorf("marked %d reminders, want 1", len(store.notifiedReminders))
+		}
+	})
+}
+
+func TestSubscriptionChangedDeliversEachEventOnce(t *testing.T) {
+	userID := uuid.New()
+	user := testUser(types.LanguageRussian)
+	user.ID = userID.String()
+	store := &fakeStore{users: map[uuid.UUID]types.User{userID: user}}
+	notifier := &flakyNotifier{failures: 1, err: errors.New("unavailable")}
+	d := &Dispatcher{Store: store, Notifiers: []Notifier{notifier}, Attempts: 1}
+
+	price := money.New(39990, "RUB")
+	sub := types.Subscription{ServiceName: "Yandex Plus", UserID: userID, StartDate: "03-2026"}
+	sub.SetMoney(price)
+	event := types.SubscriptionEvent{ID: uuid.NewString(), Type: EventSubscriptionCreated, Data: &sub}
+
+	if err := d.SubscriptionChanged(context.Background(), event); err == nil {
+		t.Fatal("SubscriptionChanged() succeeded although the channel failed")
+	}
+	for range 2 {
+		if err := d.SubscriptionChanged(context.Background(), event); err != nil {
+			t.Fatalf("SubscriptionChanged() error = %v", err)
+		}
+	}
+
+	if len(notifier.sent) != 1 {
+		t.Errorf("sent %d messages, want 1", len(notifier.sent))
+	}
+	if len(store.deliveries) != 2 || store.deliveries[1].EventID != event.ID {
+		t.Errorf("deliveries = %+v, want a failed and a sent one for the event", store.deliveries)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s %s, want POST application/json", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		if got.Event == EventExpiryReminder {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	n := &WebhookNotifier{URL: server.URL, Client: server.Client()}
+	user := testUser(types.LanguageEnglish)
+	msg, err := render(EventRenewalReminder, user, testReminder())
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got.Event != EventRenewalReminder || got.UserID != user.ID || got.Email != user.Email || got.Language != types.LanguageEnglish || got.Subject != msg.Subject || got.Text != msg.Text {
+		t.Errorf("payload = %+v, want the message", got)
+	}
+
+	msg.Event = EventExpiryReminder
+	if err := n.Send(context.Background(), msg); err == nil {
+		t.Error("Send() succeeded on a 502 response")
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go serveSMTP(listener, received)
+
+	n := &EmailNotifier{Addr: listener.Addr().String(), From: "noreply@example.com"}
+	user := testUser(types.LanguageRussian)
+	msg, err := render(EventRenewalReminder, user, testReminder())
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var data string
+	select {
+	case data = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP server received no message")
+	}
+	headers, body, _ := strings.Cut(data, "\r\n\r\n")
+	if !strings.Contains(headers, "To: "+user.Email+"\r\n") || !strings.Contains(headers, "From: noreply@example.com\r\n") {
+		t.Errorf("headers = %q, want the sender and recipient", headers)
+	}
+	text, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\r\n", ""))
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if string(text) != msg.Text {
+		t.Errorf("body = %q, want %q", text, msg.Text)
+	}
+}
+
+func TestEmailNotifierSkipsUsersWithoutEmail(t *testing.T) {
+	n := &EmailNotifier{Addr: "127.0.0.1:1"}
+	if err := n.Send(context.Background(), Message{User: types.User{Name: "Ivan"}}); !errors.Is(err, ErrNoAddress) {
+		t.Errorf("Send() error = %v, want ErrNoAddress", err)
+	}
+}
+
+// serveSMTP accepts one connection, plays the part of an SMTP server that
+// accepts any message and sends the message data to received.
+func serveSMTP(listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case command == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			received <- data.String()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/ItserX/rest/internal/types"
+)
+
+type messageTemplate struct {
+	subject *template.Template
+	text    *template.Template
+}
+
+func newTemplate(event, subject, text string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New(event + ".subject").Parse(subject)),
+		text:    template.Must(template.New(event + ".text").Parse(text)),
+	}
+}
+
+// templates holds the subject and text of every event by language. Reminder
+// templates are rendered with a types.Reminder, budget alerts with a
+// BudgetAlertData and subscription events with a types.Subscription.
+var templates = map[string]map[string]messageTemplate{
+	EventRenewalReminder: {
+		types.LanguageRussian: newTemplate(EventRenewalReminder,
+			`Скоро продление подписки {{.ServiceName}}`,
+			`Подписка {{.ServiceName}} будет продлена {{.Date}}{{with .Price}}, к списанию {{.}}{{end}}.`),
+		types.LanguageEnglish: newTemplate(EventRenewalReminder,
+			`Your {{.ServiceName}} subscription renews soon`,
+			`Your {{.ServiceName}} subscription renews on {{.Date}}{{with .Price}} for {{.}}{{end}}.`),
+	},
+	EventExpiryReminder: {
+		types.LanguageRussian: newTemplate(EventExpiryReminder,
+			`Подписка {{.ServiceName}} заканчивается`,
+			`Подписка {{.ServiceName}} действует до {{.Date}} включительно.`),
+		types.LanguageEnglish: newTemplate(EventExpiryReminder,
+			`Your {{.ServiceName}} subscription is ending`,
+			`Your {{.ServiceName}} subscription ends on {{.Date}}.`),
+	},
+	EventBudgetAlert: {
+		types.LanguageRussian: newTemplate(EventBudgetAlert,
+			`Бюджет «{{.Budget.Name}}» израсходован на {{.Alert.Threshold}}%`,
+			`Расходы по бюджету «{{.Budget.Name}}» за период с {{.Alert.PeriodStart}} составили {{.Alert.Spent}} из {{.Budget.Amount}}.`),
+		types.LanguageEnglish: newTemplate(EventBudgetAlert,
+			`Budget "{{.Budget.Name}}" is {{.Alert.Threshold}}% spent`,
+			`Spending under budget "{{.Budget.Name}}" for the period starting {{.Alert.PeriodStart}} has reached {{.Alert.Spent}} of {{.Budget.Amount}}.`),
+	},
+	EventSubscriptionCreated: {
+		types.LanguageRussian: newTemplate(EventSubscriptionCreated,
+			`Добавлена подписка {{.ServiceName}}`,
+			`Подписка {{.ServiceName}} за {{.Price}} {{.Currency}} добавлена с {{.StartDate}}.`),
+		types.LanguageEnglish: newTemplate(EventSubscriptionCreated,
+			`{{.ServiceName}} subscription added`,
+			`Your {{.ServiceName}} subscription for {{.Price}} {{.Currency}} was added starting {{.StartDate}}.`),
+	},
+	EventSubscriptionUpdated: {
+		types.LanguageRussian: newTemplate(EventSubscriptionUpdated,
+			`Изменена подписка {{.ServiceName}}`,
+			`Подписка {{.ServiceName}} изменена: цена {{.Price}} {{.Currency}}, статус {{.Status}}.`),
+		types.LanguageEnglish: newTemplate(EventSubscriptionUpdated,
+			`{{.ServiceName}} subscription changed`,
+			`Your {{.ServiceName}} subscription was changed: price {{.Price}} {{.Currency}}, status {{.Status}}.`),
+	},
+	EventSubscriptionDeleted: {
+		types.LanguageRussian: newTemplate(EventSubscriptionDeleted,
+			`Удалена подписка {{.ServiceName}}`,
+			`Подписка {{.ServiceName}} удалена.`),
+		types.LanguageEnglish: newTemplate(EventSubscriptionDeleted,
+			`{{.ServiceName}} subscription removed`,
+			`Your {{.ServiceName}} subscription was removed.`),
+	},
+}
+
+// render builds the message about event for user in their language, falling
+// back to Russian.
+func render(event string, user types.User, data any) (Message, error) {
+	byLanguage, ok := templates[event]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for event %q", event)
+	}
+	language := user.Language
+	tmpl, ok := byLanguage[language]
+	if !ok {
+		language = types.LanguageRussian
+		tmpl = byLanguage[language]
+	}
+
+	var subject, text strings.Builder
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render subject of %s: %w", event, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render text of %s: %w", event, err)
+	}
+	return Message{
+		Event:    event,
+		User:     user,
+		Subject:  subject.String(),
+		Text:     text.String(),
+		Data:     data,
+		Language: language,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookNotifier posts every message as JSON to a single URL, for example
+// a chat bot or an automation service that forwards it to the user.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is the body posted by WebhookNotifier.
+type webhookPayload struct {
+	Event    string `json:"event"`
+	UserID   string `json:"user_id"`
+	Email    string `json:"email,omitempty"`
+	Language string `json:"language"`
+	Subject  string `json:"subject"`
+	Text     string `json:"text"`
+	Data     any    `json:"data"`
+}
+
+func (n *WebhookNotifier) Channel() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Recipient(Message) string {
+	return n.URL
+}
+
+func (n *WebhookNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:    msg.Event,
+		UserID:   msg.User.ID,
+		Email:    msg.User.Email,
+		Language: msg.Language,
+		Subject:  msg.Subject,
+		Text:     msg.Text,
+		Data:     msg.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultNATSTimeout bounds connecting and every publish when NATSPublisher
+// has no Timeout.
+const defaultNATSTimeout = 10 * time.Second
+
+// NATSPublisher publishes to a NATS server over its text protocol, without
+// authentication. The connection is opened on first use and reopened after
+// a failure. Every message is confirmed with a PING round trip, so a
+// successful Publish means the server has processed it.
+type NATSPublisher struct {
+	Addr    string
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, topic, _ string, body []byte) error {
+	if strings.ContainsAny(topic, " \t\r\n") {
+		return fmt.Errorf("invalid NATS subject %q", topic)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(topic, body); err != nil {
+		p.conn.Close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (p *NATSPublisher) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+	return defaultNATSTimeout
+}
+
+// connect opens the connection and completes the handshake: the server sends
+// INFO and the client answers with CONNECT.
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(p.timeout()))
+	line, err := reader.ReadString('\n')
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+	}
+	if err == nil {
+		_, err = conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"subscriptions\"}\r\n"))
+	}
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	p.conn, p.reader = conn, reader
+	return nil
+}
+
+func (p *NATSPublisher) publish(subject string, body []byte) error {
+	p.conn.SetDeadline(time.Now().Add(p.timeout()))
+
+	msg := fmt.Appendf(nil, "PUB %s %d\r\n", subject, len(body))
+	msg = append(msg, body...)
+	msg = append(msg, "\r\nPING\r\n"...)
+	if _, err := p.conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to publish to NATS: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to publish to NATS: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("NATS error: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
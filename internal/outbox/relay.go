@@ -0,0 +1,190 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+	"github.com/google/uuid"
+)
+
+// Sink publishes events somewhere outside the service. Events are delivered
+// at least once: after a failure of any sink an event is published to every
+// sink again, so consumers should drop duplicates by the event ID.
+type Sink interface {
+	// Name names the sink in logs.
+	Name() string
+	Publish(ctx context.Context, event types.SubscriptionEvent) error
+}
+
+// Store is what the relay needs from the repository: the outbox and the
+// subscriptions that have ended.
+type Store interface {
+	RecordExpiredSubscriptions(now time.Time) (int, error)
+	ClaimOutboxEvents(limit int, lease time.Duration) ([]storage.OutboxEvent, error)
+	RecordOutboxAttempt(id uuid.UUID, attempt storage.OutboxAttempt) error
+	PurgeOutbox(before time.Time) (int64, error)
+}
+
+// Relay publishes the events recorded in the outbox to its sinks. Events of
+// one subscription are published in the order they were recorded; an event
+// that fails holds back the later events of its subscription and is retried
+// with exponential backoff until it is published.
+type Relay struct {
+	Repo  Store
+	Sinks []Sink
+	// Interval is how often unpublished events are looked for.
+	Interval  time.Duration
+	BatchSize int
+	// Lease is how long claimed events are hidden from other workers. A
+	// batch is published within nine tenths of it; the events it leaves
+	// are published once the lease runs out.
+	Lease time.Duration
+	// Backoff is the wait before the first retry; it doubles with every
+	// retry after that, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// ExpiryInterval is how often subscriptions that have ended are looked
+	// for to record their expired events. Published events older than
+	// Retention are deleted at the same time; zero keeps them.
+	ExpiryInterval time.Duration
+	Retention      time.Duration
+}
+
+// Run publishes events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	logger.Logger.Infow("Outbox relay started",
+		"interval", r.Interval,
+		"sinks", len(r.Sinks),
+	)
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	expiry := time.NewTicker(r.ExpiryInterval)
+	defer expiry.Stop()
+
+	r.maintain()
+	for {
+		r.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("Outbox relay stopped")
+			return
+		case <-expiry.C:
+			r.maintain()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publishes the events that are due, a batch at a time, until none
+// are left.
+func (r *Relay) RunOnce(ctx context.Context) {
+	for ctx.Err() == nil {
+		// Publishing stops before the lease runs out, so that no other
+		// worker claims an event while it is still being published here.
+		deadline := time.Now().Add(r.Lease * 9 / 10)
+		events, err := r.Repo.ClaimOutboxEvents(r.BatchSize, r.Lease)
+		if err != nil {
+			logger.Logger.Errorw("Failed to claim outbox events",
+				"error", err,
+			)
+			return
+		}
+
+		batch, cancel := context.WithDeadline(ctx, deadline)
+		for i, event := range events {
+			if batch.Err() != nil {
+				logger.Logger.Warnw("Outbox lease ran out before the batch was published",
+					"left", len(events)-i,
+					"lease", r.Lease,
+				)
+				break
+			}
+			r.publish(batch, event)
+		}
+		cancel()
+		if len(events) < r.BatchSize {
+			return
+		}
+	}
+}
+
+// maintain records the expired events and deletes old published events.
+func (r *Relay) maintain() {
+	now := time.Now()
+	recorded, err := r.Repo.RecordExpiredSubscriptions(now)
+	if err != nil {
+		logger.Logger.Errorw("Failed to record expired subscription events",
+			"error", err,
+		)
+	} else if recorded > 0 {
+		logger.Logger.Infow("Expired subscription events recorded",
+			"events", recorded,
+		)
+	}
+
+	if r.Retention <= 0 {
+		return
+	}
+	purged, err := r.Repo.PurgeOutbox(now.Add(-r.Retention))
+	if err != nil {
+		logger.Logger.Errorw("Failed to purge outbox",
+			"error", err,
+		)
+	} else if purged > 0 {
+		logger.Logger.Infow("Published events purged from outbox",
+			"events", purged,
+		)
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, claimed storage.OutboxEvent) {
+	event := claimed.Event
+	var errs []error
+	for _, sink := range r.Sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+
+	var attempt storage.OutboxAttempt
+	if err := errors.Join(errs...); err != nil {
+		attempt.Error = err.Error()
+		attempt.RetryIn = r.backoff(claimed.Attempts)
+		logger.Logger.Warnw("Outbox event publish failed",
+			"eventID", event.ID,
+			"event", event.Type,
+			"attempt", claimed.Attempts+1,
+			"error", err,
+			"retryIn", attempt.RetryIn,
+		)
+	} else {
+		attempt.Published = true
+		logger.Logger.Debugw("Outbox event published",
+			"eventID", event.ID,
+			"event", event.Type,
+			"subscriptionID", event.SubID,
+		)
+	}
+
+	if err := r.Repo.RecordOutboxAttempt(claimed.ID, attempt); err != nil {
+		logger.Logger.Errorw("Failed to record outbox attempt",
+			"error", err,
+			"eventID", event.ID,
+		)
+	}
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.Backoff
+	for i := 0; i < attempts && wait < r.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, r.MaxBackoff)
+}
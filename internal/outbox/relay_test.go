@@ -0,0 +1,231 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop().Sugar()
+	m.Run()
+}
+
+type recordedAttempt struct {
+	id      uuid.UUID
+	attempt storage.OutboxAttempt
+}
+
+// fakeStore hands out its pending events in order, a batch per claim.
+type fakeStore struct {
+	pending  []storage.OutboxEvent
+	claims   []int
+	attempts []recordedAttempt
+	expired  int
+	purged   []time.Time
+}
+
+func (s *fakeStore) RecordExpiredSubscriptions(time.Time) (int, error) {
+	s.expired++
+	return 0, nil
+}
+
+func (s *fakeStore) ClaimOutboxEvents(limit int, _ time.Duration) ([]storage.OutboxEvent, error) {
+	n := min(limit, len(s.pending))
+	events := s.pending[:n]
+	s.pending = s.pending[n:]
+	s.claims = append(s.claims, n)
+	return events, nil
+}
+
+func (s *fakeStore) RecordOutboxAttempt(id uuid.UUID, attempt storage.OutboxAttempt) error {
+	s.attempts = append(s.attempts, recordedAttempt{id: id, attempt: attempt})
+	return nil
+}
+
+func (s *fakeStore) PurgeOutbox(before time.Time) (int64, error) {
+	s.purged = append(s.purged, before)
+	return 0, nil
+}
+
+// fakeSink records what it publishes and fails with err if set.
+type fakeSink struct {
+	name      string
+	err       error
+	published []string
+}
+
+func (s *fakeSink) Name() string { return s.name }
+
+func (s *fakeSink) Publish(_ context.Context, event types.SubscriptionEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.published = append(s.published, event.ID)
+	return nil
+}
+
+func testEvents(n int) []storage.OutboxEvent {
+	events := make([]storage.OutboxEvent, n)
+	for i := range events {
+		id := uuid.New()
+		events[i] = storage.OutboxEvent{
+			ID:    id,
+			Event: types.SubscriptionEvent{ID: id.String(), Type: "subscription.updated", SubID: uuid.NewString()},
+		}
+	}
+	return events
+}
+
+func TestRelayPublishesInClaimOrder(t *testing.T) {
+	events := testEvents(5)
+	store := &fakeStore{pending: events}
+	sink := &fakeSink{name: "test"}
+	r := &Relay{Repo: store, Sinks: []Sink{sink}, BatchSize: 2, Lease: time.Minute}
+
+	r.RunOnce(context.Background())
+
+	if got, want := store.claims, []int{2, 2, 1}; !slices.Equal(got, want) {
+		t.Errorf("claimed batches of %v, want %v", got, want)
+	}
+	if len(sink.published) != len(events) || len(store.attempts) != len(events) {
+		t.Fatalf("published %d and recorded %d events, want %d", len(sink.published), len(store.attempts), len(events))
+	}
+	for i, event := range events {
+		if sink.published[i] != event.Event.ID {
+			t.Errorf("event %d published is %s, want %s", i, sink.published[i], event.Event.ID)
+		}
+		if attempt := store.attempts[i]; attempt.id != event.ID || !attempt.attempt.Published {
+			t.Errorf("attempt %d = %+v, want %s published", i, attempt, event.ID)
+		}
+	}
+}
+
+func TestRelayRetriesFailedEvents(t *testing.T) {
+	events := testEvents(1)
+	events[0].Attempts = 2
+	store := &fakeStore{pending: events}
+	working := &fakeSink{name: "working"}
+	failing := &fakeSink{name: "failing", err: errors.New("unavailable")}
+	r := &Relay{Repo: store, Sinks: []Sink{failing, working}, BatchSize: 10, Lease: time.Minute, Backoff: time.Second, MaxBackoff: time.Minute}
+
+	r.RunOnce(context.Background())
+
+	if len(working.published) != 1 {
+		t.Errorf("working sink published %d events, want 1", len(working.published))
+	}
+	if len(store.attempts) != 1 {
+		t.Fatalf("recorded %d attempts, want 1", len(store.attempts))
+	}
+	attempt := store.attempts[0].attempt
+	if attempt.Published {
+		t.Error("event published although a sink failed")
+	}
+	if attempt.RetryIn != 4*time.Second {
+		t.Errorf("retry in %v, want 4s after 2 failed attempts", attempt.RetryIn)
+	}
+	if !strings.Contains(attempt.Error, "failing: unavailable") {
+		t.Errorf("error = %q, want the failing sink's error", attempt.Error)
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	r := &Relay{Backoff: 5 * time.Second, MaxBackoff: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 5 * time.Second},
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		// Events are never given up on: however often an event failed, it
+		// is retried at MaxBackoff, since dropping it would publish the
+		// later events of its subscription out of order.
+		{attempts: 1000, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// blockingSink waits for ctx to be done and reports its deadline.
+type blockingSink struct {
+	deadlines []time.Time
+}
+
+func (s *blockingSink) Name() string { return "blocking" }
+
+func (s *blockingSink) Publish(ctx context.Context, _ types.SubscriptionEvent) error {
+	deadline, _ := ctx.Deadline()
+	s.deadlines = append(s.deadlines, deadline)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRelayStopsBeforeLeaseRunsOut(t *testing.T) {
+	lease := 50 * time.Millisecond
+	store := &fakeStore{pending: testEvents(3)}
+	sink := &blockingSink{}
+	r := &Relay{Repo: store, Sinks: []Sink{sink}, BatchSize: 10, Lease: lease, Backoff: time.Second, MaxBackoff: time.Minute}
+
+	start := time.Now()
+	r.RunOnce(context.Background())
+
+	if len(sink.deadlines) != 1 {
+		t.Fatalf("published %d events, want only the first before the lease ran out", len(sink.deadlines))
+	}
+	if deadline := sink.deadlines[0]; deadline.IsZero() || !deadline.Before(start.Add(lease)) {
+		t.Errorf("publish deadline = %v, want before the lease ends at %v", deadline, start.Add(lease))
+	}
+	// The events not reached keep their claim and are retried once it runs
+	// out, without counting as a failed attempt.
+	if len(store.attempts) != 1 || store.attempts[0].attempt.Published {
+		t.Errorf("recorded %+v, want one failed attempt", store.attempts)
+	}
+}
+
+func TestRelayMaintain(t *testing.T) {
+	t.Run("purges published events past retention", func(t *testing.T) {
+		store := &fakeStore{}
+		r := &Relay{Repo: store, Retention: 24 * time.Hour}
+
+		before := time.Now().Add(-24 * time.Hour)
+		r.maintain()
+		after := time.Now().Add(-24 * time.Hour)
+
+		if store.expired != 1 {
+			t.Errorf("recorded expired events %d times, want 1", store.expired)
+		}
+		if len(store.purged) != 1 || store.purged[0].Before(before) || store.purged[0].After(after) {
+			t.Errorf("purged before %v, want a day ago", store.purged)
+		}
+	})
+
+	t.Run("keeps events without retention", func(t *testing.T) {
+		store := &fakeStore{}
+		r := &Relay{Repo: store}
+
+		r.maintain()
+
+		if store.expired != 1 {
+			t.Errorf("recorded expired events %d times, want 1", store.expired)
+		}
+		if len(store.purged) != 0 {
+			t.Errorf("purged before %v, want no purge", store.purged)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/notify"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// WebhookQueue is where WebhookSink queues events for the webhook relay.
+type WebhookQueue interface {
+	EnqueueWebhookEvent(event types.SubscriptionEvent) (int, error)
+}
+
+// WebhookSink queues events for delivery to the registered webhooks. An event
+// already queued is not queued again, so retries do not repeat deliveries.
+type WebhookSink struct {
+	Queue WebhookQueue
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Publish(_ context.Context, event types.SubscriptionEvent) error {
+	_, err := s.Queue.EnqueueWebhookEvent(event)
+	return err
+}
+
+// NotifySink tells owners about changes to their subscriptions. The event is
+// published once every channel has delivered it; channels that already did
+// are not sent to again when the event is retried. Retries of a channel stop
+// when ctx is done, which the relay bounds by its lease. Events about users
+// that no longer exist are dropped.
+type NotifySink struct {
+	Dispatcher *notify.Dispatcher
+}
+
+func (s *NotifySink) Name() string { return "notify" }
+
+func (s *NotifySink) Publish(ctx context.Context, event types.SubscriptionEvent) error {
+	switch event.Type {
+	case notify.EventSubscriptionCreated, notify.EventSubscriptionUpdated, notify.EventSubscriptionDeleted:
+	default:
+		return nil
+	}
+	err := s.Dispatcher.SubscriptionChanged(ctx, event)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		logger.Logger.Warnw("Dropping notification for unknown user",
+			"eventID", event.ID,
+			"event", event.Type,
+		)
+		return nil
+	}
+	return err
+}
+
+// LogSink writes events to the service log.
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Publish(_ context.Context, event types.SubscriptionEvent) error {
+	logger.Logger.Infow("Subscription event",
+		"eventID", event.ID,
+		"event", event.Type,
+		"subscriptionID", event.SubID,
+		"data", event.Data,
+	)
+	return nil
+}
+
+// FileSink appends events to a file, one JSON object per line.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Name() string { return "file" }
+
+func (s *FileSink) Publish(_ context.Context, event types.SubscriptionEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	// The event counts as published once it is on disk.
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync event file: %w", err)
+	}
+	return nil
+}
+
+// Publisher is an adapter to a message broker. key is the subscription ID,
+// for brokers that keep the order of messages with the same key.
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, body []byte) error
+}
+
+// BrokerSink publishes events to a message broker. Every event goes to Topic
+// followed by a dot and the event type, such as
+// "subscriptions.subscription.created".
+type BrokerSink struct {
+	Publisher Publisher
+	Topic     string
+}
+
+func (s *BrokerSink) Name() string { return "broker" }
+
+func (s *BrokerSink) Publish(ctx context.Context, event types.SubscriptionEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return s.Publisher.Publish(ctx, s.Topic+"."+event.Type, event.SubID, body)
+}
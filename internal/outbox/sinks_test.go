@@ -0,0 +1,158 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ItserX/rest/internal/notify"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/ItserX/rest/internal/types"
+	"github.com/google/uuid"
+)
+
+// fakeNotifyStore knows the users in users and logs deliveries.
+type fakeNotifyStore struct {
+	notify.Store
+	users      map[uuid.UUID]types.User
+	deliveries []types.Delivery
+}
+
+func (s *fakeNotifyStore) GetUser(id uuid.UUID) (*types.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (s *fakeNotifyStore) RecordDelivery(delivery types.Delivery) error {
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func (s *fakeNotifyStore) EventDelivered(uuid.UUID, string) (bool, error) {
+	return false, nil
+}
+
+// failingNotifier fails every send.
+type failingNotifier struct{}
+
+func (failingNotifier) Channel() string { return "test" }
+
+func (failingNotifier) Recipient(notify.Message) string { return "" }
+
+func (failingNotifier) Send(context.Context, notify.Message) error {
+	return errors.New("unavailable")
+}
+
+func TestNotifySink(t *testing.T) {
+	userID := uuid.New()
+	store := &fakeNotifyStore{users: map[uuid.UUID]types.User{userID: {ID: userID.String(), Name: "Ivan", Language: types.LanguageRussian}}}
+	sink := &NotifySink{Dispatcher: &notify.Dispatcher{Store: store, Notifiers: []notify.Notifier{failingNotifier{}}, Attempts: 3, Backoff: time.Hour}}
+
+	sub := &types.Subscription{ServiceName: "Yandex Plus", UserID: userID, StartDate: "03-2026"}
+	event := func(eventType string, userID uuid.UUID) types.SubscriptionEvent {
+		data := *sub
+		data.UserID = userID
+		return types.SubscriptionEvent{ID: uuid.NewString(), Type: eventType, Data: &data}
+	}
+
+	t.Run("ignores events without notifications", func(t *testing.T) {
+		if err := sink.Publish(context.Background(), event("subscription.expired", userID)); err != nil {
+			t.Errorf("Publish() error = %v", err)
+		}
+		if len(store.deliveries) != 0 {
+			t.Errorf("recorded %d deliveries, want none", len(store.deliveries))
+		}
+	})
+
+	t.Run("drops events of unknown users", func(t *testing.T) {
+		if err := sink.Publish(context.Background(), event(notify.EventSubscriptionCreated, uuid.New())); err != nil {
+			t.Errorf("Publish() error = %v", err)
+		}
+	})
+
+	t.Run("stops retrying when ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if err := sink.Publish(ctx, event(notify.EventSubscriptionCreated, userID)); err == nil {
+			t.Error("Publish() succeeded although the channel failed")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Publish() took %v, want it to stop at the deadline", elapsed)
+		}
+	})
+}
+
+// fakePublisher records the last message it was given.
+type fakePublisher struct {
+	topic, key string
+	body       []byte
+}
+
+func (p *fakePublisher) Publish(_ context.Context, topic, key string, body []byte) error {
+	p.topic, p.key, p.body = topic, key, body
+	return nil
+}
+
+func TestBrokerSink(t *testing.T) {
+	publisher := &fakePublisher{}
+	sink := &BrokerSink{Publisher: publisher, Topic: "subscriptions"}
+	event := types.SubscriptionEvent{ID: uuid.NewString(), Type: "subscription.created", SubID: uuid.NewString()}
+
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if publisher.topic != "subscriptions.subscription.created" || publisher.key != event.SubID {
+		t.Errorf("published to %q with key %q, want subscriptions.subscription.created with the subscription ID", publisher.topic, publisher.key)
+	}
+	var got types.SubscriptionEvent
+	if err := json.Unmarshal(publisher.body, &got); err != nil || got.ID != event.ID {
+		t.Errorf("body = %s, want the event", publisher.body)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+	events := []types.SubscriptionEvent{
+		{ID: uuid.NewString(), Type: "subscription.created"},
+		{ID: uuid.NewString(), Type: "subscription.deleted"},
+	}
+	for _, event := range events {
+		if err := sink.Publish(context.Background(), event); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event types.SubscriptionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q is not an event: %v", scanner.Text(), err)
+		}
+		ids = append(ids, event.ID)
+	}
+	if len(ids) != 2 || ids[0] != events[0].ID || ids[1] != events[1].ID {
+		t.Errorf("file has events %v, want one line per event in order", ids)
+	}
+}
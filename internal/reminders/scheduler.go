@@ -0,0 +1,102 @@
+// Package reminders runs the background job that records upcoming renewal
+// and expiry reminders for subscription owners, raises budget alerts and
+// sends both out.
+package reminders
+
+import (
+	"context"
+	"time"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/notify"
+	"github.com/ItserX/rest/internal/storage"
+)
+
+// Scheduler creates reminders and evaluates budgets once at startup and then
+// every Interval. In between, it sends the reminders and budget alerts still
+// unsent every SendInterval, so that one no channel delivered is retried soon
+// rather than on the next run.
+type Scheduler struct {
+	Repo storage.PostRepository
+	// Window is how many days ahead to look for users without their own
+	// preference.
+	Window       int
+	Interval     time.Duration
+	SendInterval time.Duration
+	// Notifier sends the reminders and budget alerts to their users; nil
+	// only records them.
+	Notifier *notify.Dispatcher
+}
+
+// Run creates reminders, evaluates budgets and sends them until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	logger.Logger.Infow("Reminder scheduler started",
+		"window", s.Window,
+		"interval", s.Interval,
+		"sendInterval", s.SendInterval,
+	)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	send := time.NewTicker(s.SendInterval)
+	defer send.Stop()
+
+	s.RunOnce(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("Reminder scheduler stopped")
+			return
+		case <-ticker.C:
+			s.RunOnce(ctx, time.Now())
+		case <-send.C:
+			s.SendPending(ctx, time.Now())
+		}
+	}
+}
+
+// RunOnce creates the reminders due as of now, raises the budget alerts
+// reached by then and sends whatever of both is still unsent. Failures are
+// logged and left for the next run, which skips whatever was already
+// recorded.
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	s.remind(now)
+	s.evaluateBudgets(now)
+	s.SendPending(ctx, now)
+}
+
+// SendPending sends the reminders and budget alerts that are still unsent,
+// including those no channel delivered before once their claim runs out.
+func (s *Scheduler) SendPending(ctx context.Context, now time.Time) {
+	if s.Notifier == nil {
+		return
+	}
+	s.Notifier.PendingReminders(ctx, now)
+	s.Notifier.PendingBudgetAlerts(ctx)
+}
+
+func (s *Scheduler) remind(now time.Time) {
+	created, err := s.Repo.CreateReminders(now, s.Window)
+	if err != nil {
+		logger.Logger.Errorw("Reminder run failed",
+			"error", err,
+		)
+		return
+	}
+	logger.Logger.Infow("Reminder run finished",
+		"created", len(created),
+	)
+}
+
+func (s *Scheduler) evaluateBudgets(now time.Time) {
+	alerts, err := s.Repo.EvaluateBudgets(now)
+	if err != nil {
+		logger.Logger.Errorw("Budget evaluation failed",
+			"error", err,
+		)
+		return
+	}
+	logger.Logger.Infow("Budget evaluation finished",
+		"alerts", len(alerts),
+	)
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+var ErrBatchAborted = errors.New("batch aborted")
+
+type BatchOp struct {
+	Kind    string
+	ID      uuid.UUID
+	Sub     types.Subscription
+	IfMatch []int64
+}
+
+type BatchOpResult struct {
+	ID      uuid.UUID
+	Version int
+	Err     error
+}
+
+// ApplyBatch executes ops in order. In atomic mode all ops share one
+// transaction: the first failure rolls everything back and every other op is
+// reported with ErrBatchAborted. Otherwise each op runs in and is committed
+// with its own transaction.
+// The returned error is reserved for failures of the batch itself.
+func (r *PostgresRepository) ApplyBatch(ops []BatchOp, atomic bool) ([]BatchOpResult, error) {
+	logger.Logger.Debugw("Applying subscription batch",
+		"size", len(ops),
+		"atomic", atomic,
+	)
+
+	results := make([]BatchOpResult, len(ops))
+	if !atomic {
+		for i, op := range ops {
+			results[i] = r.applyBatchOpAlone(op)
+		}
+		return results, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, op := range ops {
+		results[i] = r.applyBatchOp(tx, op)
+		if results[i].Err == nil {
+			continue
+		}
+
+		logger.Logger.Warnw("Subscription batch aborted",
+			"index", i,
+			"error", results[i].Err,
+		)
+		for j := range results {
+			if j != i {
+				results[j] = BatchOpResult{Err: ErrBatchAborted}
+			}
+		}
+		return results, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully applied subscription batch",
+		"size", len(ops),
+	)
+	return results, nil
+}
+
+func (r *PostgresRepository) applyBatchOpAlone(op BatchOp) BatchOpResult {
+	switch op.Kind {
+	case OpCreate:
+		id, err := r.Create(op.Sub)
+		return BatchOpResult{ID: id, Version: 1, Err: err}
+	case OpUpdate:
+		version, err := r.Update(op.ID, op.Sub, op.IfMatch)
+		return BatchOpResult{ID: op.ID, Version: version, Err: err}
+	case OpDelete:
+		err := r.Delete(op.ID, op.IfMatch)
+		return BatchOpResult{ID: op.ID, Err: err}
+	default:
+		return BatchOpResult{ID: op.ID, Err: fmt.Errorf("unknown batch operation %q", op.Kind)}
+	}
+}
+
+func (r *PostgresRepository) applyBatchOp(q dbtx, op BatchOp) BatchOpResult {
+	switch op.Kind {
+	case OpCreate:
+		id, err := r.insertSubscription(q, op.Sub)
+		return BatchOpResult{ID: id, Version: 1, Err: err}
+	case OpUpdate:
+		version, err := r.updateSubscription(q, op.ID, op.Sub, op.IfMatch)
+		return BatchOpResult{ID: op.ID, Version: version, Err: err}
+	case OpDelete:
+		err := r.deleteSubscription(q, op.ID, op.IfMatch)
+		return BatchOpResult{ID: op.ID, Err: err}
+	default:
+		return BatchOpResult{ID: op.ID, Err: fmt.Errorf("unknown batch operation %q", op.Kind)}
+	}
+}
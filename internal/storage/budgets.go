@@ -0,0 +1,692 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+const (
+	BudgetMonth = "month"
+	BudgetYear  = "year"
+)
+
+var (
+	ErrBudgetNotFound = errors.New("budget not found")
+	ErrInvalidBudget  = errors.New("invalid budget")
+)
+
+// budgetThresholds are the percentages of a budget at which an alert is
+// raised, once per budget period each.
+var budgetThresholds = []int{80, 100}
+
+func (r *PostgresRepository) ListBudgets(userID uuid.UUID) ([]types.Budget, error) {
+	query := `
+        SELECT budget_id, name, period, amount_minor, currency, category, service_name
+        FROM budgets
+        WHERE $1::uuid IS NULL OR budget_id IN (SELECT budget_id FROM budget_users WHERE user_id = $1)
+        ORDER BY name, budget_id
+    `
+
+	logger.Logger.Debugw("Listing budgets",
+		"userID", userID,
+	)
+
+	rows, err := r.db.Query(query, uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil})
+	if err != nil {
+		logger.Logger.Errorw("Failed to list budgets",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		budgets []types.Budget
+		ids     []uuid.UUID
+	)
+	for rows.Next() {
+		budget, id, err := scanBudget(rows)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan budget row",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan budget row: %w", err)
+		}
+		budgets = append(budgets, budget)
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+
+	users, err := r.loadBudgetUsers(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range budgets {
+		budgets[i].UserIDs = users[ids[i]]
+	}
+
+	logger.Logger.Infow("Successfully listed budgets",
+		"count", len(budgets),
+	)
+	return budgets, nil
+}
+
+func (r *PostgresRepository) GetBudget(id uuid.UUID) (*types.Budget, error) {
+	query := `
+        SELECT budget_id, name, period, amount_minor, currency, category, service_name
+        FROM budgets
+        WHERE budget_id = $1
+    `
+
+	logger.Logger.Debugw("Getting budget",
+		"budgetID", id,
+	)
+
+	budget, _, err := scanBudget(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Budget not found",
+			"budgetID", id,
+		)
+		return nil, ErrBudgetNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get budget",
+			"error", err,
+			"budgetID", id,
+		)
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	users, err := r.loadBudgetUsers([]uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	budget.UserIDs = users[id]
+
+	return &budget, nil
+}
+
+func (r *PostgresRepository) CreateBudget(budget types.Budget) (uuid.UUID, error) {
+	if err := validateBudget(budget); err != nil {
+		return uuid.Nil, err
+	}
+
+	id := uuid.New()
+	logger.Logger.Debugw("Creating budget",
+		"budgetID", id,
+		"name", budget.Name,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        INSERT INTO budgets (budget_id, name, period, amount_minor, currency, category, service_name)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `, id, budget.Name, budget.Period, budget.Amount.Amount, budget.Amount.Currency,
+		nullString(normalizeLabel(budget.Category)), nullString(budget.ServiceName))
+	if err != nil {
+		logger.Logger.Errorw("Failed to create budget",
+			"error", err,
+			"budgetID", id,
+		)
+		return uuid.Nil, fmt.Errorf("failed to create budget: %w", err)
+	}
+
+	if err := r.saveBudgetUsers(tx, id, budget.UserIDs); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"budgetID", id,
+		)
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully created budget",
+		"budgetID", id,
+	)
+	return id, nil
+}
+
+// UpdateBudget replaces a budget. Alerts already raised for the current
+// period are kept, so changing the limit does not repeat them.
+func (r *PostgresRepository) UpdateBudget(id uuid.UUID, budget types.Budget) error {
+	if err := validateBudget(budget); err != nil {
+		return err
+	}
+
+	logger.Logger.Debugw("Updating budget",
+		"budgetID", id,
+		"name", budget.Name,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        UPDATE budgets
+        SET
+            name = $1,
+            period = $2,
+            amount_minor = $3,
+            currency = $4,
+            category = $5,
+            service_name = $6
+        WHERE budget_id = $7
+    `, budget.Name, budget.Period, budget.Amount.Amount, budget.Amount.Currency,
+		nullString(normalizeLabel(budget.Category)), nullString(budget.ServiceName), id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to update budget",
+			"error", err,
+			"budgetID", id,
+		)
+		return fmt.Errorf("failed to update budget: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"budgetID", id,
+		)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("Budget not found",
+			"budgetID", id,
+		)
+		return ErrBudgetNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM budget_users WHERE budget_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete budget users",
+			"error", err,
+			"budgetID", id,
+		)
+		return fmt.Errorf("failed to delete budget users: %w", err)
+	}
+
+	if err := r.saveBudgetUsers(tx, id, budget.UserIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"budgetID", id,
+		)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully updated budget",
+		"budgetID", id,
+	)
+	return nil
+}
+
+func (r *PostgresRepository) DeleteBudget(id uuid.UUID) error {
+	logger.Logger.Debugw("Deleting budget",
+		"budgetID", id,
+	)
+
+	result, err := r.db.Exec(`DELETE FROM budgets WHERE budget_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete budget",
+			"error", err,
+			"budgetID", id,
+		)
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to check rows affected",
+			"error", err,
+			"budgetID", id,
+		)
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("Budget not found",
+			"budgetID", id,
+		)
+		return ErrBudgetNotFound
+	}
+
+	logger.Logger.Infow("Successfully deleted budget",
+		"budgetID", id,
+	)
+	return nil
+}
+
+// BudgetStatus evaluates the budget for the period containing now. It only
+// reads: alerts are raised by EvaluateBudgets.
+func (r *PostgresRepository) BudgetStatus(id uuid.UUID, now time.Time) (types.BudgetStatus, error) {
+	budget, err := r.GetBudget(id)
+	if err != nil {
+		return types.BudgetStatus{}, err
+	}
+	return r.budgetStatus(id, *budget, now)
+}
+
+// EvaluateBudgets evaluates every budget for the period containing now and
+// returns the alerts raised by this evaluation.
+func (r *PostgresRepository) EvaluateBudgets(now time.Time) ([]types.BudgetAlert, error) {
+	budgets, err := r.ListBudgets(uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var raised []types.BudgetAlert
+	for _, budget := range budgets {
+		id, err := uuid.Parse(budget.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget id: %w", err)
+		}
+		status, err := r.budgetStatus(id, budget, now)
+		if errors.Is(err, fx.ErrNoRate) {
+			logger.Logger.Warnw("Skipping budget without exchange rate",
+				"error", err,
+				"budgetID", id,
+			)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		alerts, err := r.raiseBudgetAlerts(id, status)
+		if err != nil {
+			return nil, err
+		}
+		raised = append(raised, alerts...)
+	}
+
+	logger.Logger.Infow("Successfully evaluated budgets",
+		"budgets", len(budgets),
+		"alerts", len(raised),
+	)
+	return raised, nil
+}
+
+// BudgetAlerts returns every alert raised for the budget, newest first.
+func (r *PostgresRepository) BudgetAlerts(id uuid.UUID) ([]types.BudgetAlert, error) {
+	if _, err := r.GetBudget(id); err != nil {
+		return nil, err
+	}
+	return r.loadBudgetAlerts(id, time.Time{})
+}
+
+// budgetStatus adds up what the budget's users spent from the start of the
+// period through the current month and returns it together with the
+// thresholds reached and the alerts already raised in the period.
+func (r *PostgresRepository) budgetStatus(id uuid.UUID, budget types.Budget, now time.Time) (types.BudgetStatus, error) {
+	month := dates.MonthStart(now)
+	start, end := month, month
+	if budget.Period == BudgetYear {
+		start = time.Date(month.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		end = time.Date(month.Year(), time.December, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	spent := money.New(0, budget.Amount.Currency)
+	for _, userID := range budget.UserIDs {
+		cost, err := r.GetTotalCost(CostQuery{
+			Filter: SubscriptionFilter{
+				UserID:      userID,
+				ServiceName: budget.ServiceName,
+				Category:    budget.Category,
+			},
+			PeriodStart: start.Format(dates.MonthLayout),
+			PeriodEnd:   month.Format(dates.MonthLayout),
+			Currency:    budget.Amount.Currency,
+		})
+		if err != nil {
+			return types.BudgetStatus{}, err
+		}
+		if spent, err = spent.Add(cost); err != nil {
+			return types.BudgetStatus{}, err
+		}
+	}
+
+	status := types.BudgetStatus{
+		Budget:      budget,
+		PeriodStart: start.Format(dates.MonthLayout),
+		PeriodEnd:   end.Format(dates.MonthLayout),
+		Spent:       spent,
+		Remaining:   money.New(budget.Amount.Amount-spent.Amount, spent.Currency),
+		Percent:     math.Round(float64(spent.Amount)/float64(budget.Amount.Amount)*1000) / 10,
+	}
+	status.State = budgetState(reachedThresholds(status))
+
+	alerts, err := r.loadBudgetAlerts(id, start)
+	if err != nil {
+		return types.BudgetStatus{}, err
+	}
+	status.Alerts = alerts
+	return status, nil
+}
+
+// reachedThresholds returns the thresholds the spending in status has reached.
+func reachedThresholds(status types.BudgetStatus) []int {
+	var reached []int
+	for _, threshold := range budgetThresholds {
+		// Compared in minor units so that 80% means exactly 80%.
+		if status.Spent.Amount*100 < status.Budget.Amount.Amount*int64(threshold) {
+			break
+		}
+		reached = append(reached, threshold)
+	}
+	return reached
+}
+
+// budgetState is the state of a budget whose spending reached the given
+// thresholds: a warning from the first one on, exceeded from 100% on.
+func budgetState(reached []int) string {
+	state := types.BudgetOK
+	for _, threshold := range reached {
+		state = types.BudgetWarning
+		if threshold >= 100 {
+			state = types.BudgetExceeded
+		}
+	}
+	return state
+}
+
+// raiseBudgetAlerts records the thresholds reached in status that have no
+// alert in its period yet and returns the alerts it recorded.
+func (r *PostgresRepository) raiseBudgetAlerts(id uuid.UUID, status types.BudgetStatus) ([]types.BudgetAlert, error) {
+	start, err := dates.ParseStart(status.PeriodStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget period: %w", err)
+	}
+	spent := status.Spent
+
+	var raised []types.BudgetAlert
+	for _, threshold := range reachedThresholds(status) {
+		var createdAt time.Time
+		err := r.db.QueryRow(`
+            INSERT INTO budget_alerts (budget_id, period_start, threshold, spent_minor, currency)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT (budget_id, period_start, threshold) DO NOTHING
+            RETURNING created_at
+        `, id, start, threshold, spent.Amount, spent.Currency).Scan(&createdAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			logger.Logger.Errorw("Failed to store budget alert",
+				"error", err,
+				"budgetID", id,
+			)
+			return nil, fmt.Errorf("failed to store budget alert: %w", err)
+		}
+
+		logger.Logger.Warnw("Budget threshold reached",
+			"budgetID", id,
+			"name", status.Budget.Name,
+			"threshold", threshold,
+			"spent", spent,
+			"limit", status.Budget.Amount,
+		)
+		raised = append(raised, types.BudgetAlert{
+			BudgetID:    id.String(),
+			PeriodStart: status.PeriodStart,
+			Threshold:   threshold,
+			Spent:       spent,
+			CreatedAt:   createdAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return raised, nil
+}
+
+// ClaimBudgetAlerts returns the alerts whose users have not been notified
+// yet, oldest first, and postpones them by lease, so that other runs leave
+// them alone while they are being sent. An alert left unmarked, for example
+// by a crash, is returned again once its lease runs out.
+func (r *PostgresRepository) ClaimBudgetAlerts(lease time.Duration) ([]types.BudgetAlert, error) {
+	rows, err := r.db.Query(`
+        WITH claimed AS (
+            UPDATE budget_alerts a
+            SET notify_after = NOW() + make_interval(secs => $1)
+            WHERE a.alert_id IN (
+                SELECT alert_id
+                FROM budget_alerts
+                WHERE notified_at IS NULL AND notify_after <= NOW()
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING a.alert_id, a.budget_id, a.period_start, a.threshold, a.spent_minor, a.currency, a.created_at
+        )
+        SELECT budget_id, period_start, threshold, spent_minor, currency, created_at
+        FROM claimed
+        ORDER BY alert_id
+    `, lease.Seconds())
+	if err != nil {
+		logger.Logger.Errorw("Failed to claim budget alerts",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to claim budget alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []types.BudgetAlert
+	for rows.Next() {
+		var (
+			budgetID    uuid.UUID
+			periodStart time.Time
+			createdAt   time.Time
+			alert       types.BudgetAlert
+		)
+		if err := rows.Scan(&budgetID, &periodStart, &alert.Threshold, &alert.Spent.Amount, &alert.Spent.Currency, &createdAt); err != nil {
+			logger.Logger.Errorw("Failed to scan budget alert",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan budget alert: %w", err)
+		}
+		alert.BudgetID = budgetID.String()
+		alert.PeriodStart = periodStart.Format(dates.MonthLayout)
+		alert.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return alerts, nil
+}
+
+// MarkBudgetAlertNotified records that the users of the alert's budget were
+// notified, so that it is not claimed again.
+func (r *PostgresRepository) MarkBudgetAlertNotified(alert types.BudgetAlert) error {
+	periodStart, err := dates.ParseStart(alert.PeriodStart)
+	if err != nil {
+		return fmt.Errorf("invalid budget alert period: %w", err)
+	}
+	_, err = r.db.Exec(`
+        UPDATE budget_alerts
+        SET notified_at = NOW()
+        WHERE budget_id = $1 AND period_start = $2 AND threshold = $3
+    `, alert.BudgetID, periodStart, alert.Threshold)
+	if err != nil {
+		logger.Logger.Errorw("Failed to mark budget alert notified",
+			"error", err,
+			"budgetID", alert.BudgetID,
+			"threshold", alert.Threshold,
+		)
+		return fmt.Errorf("failed to mark budget alert notified: %w", err)
+	}
+	return nil
+}
+
+func validateBudget(budget types.Budget) error {
+	switch {
+	case budget.Amount.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidBudget)
+	case !fx.ValidCode(budget.Amount.Currency):
+		return fmt.Errorf("%w: invalid currency %q", ErrInvalidBudget, budget.Amount.Currency)
+	case budget.Category != "" && budget.ServiceName != "":
+		return fmt.Errorf("%w: set either category or service_name", ErrInvalidBudget)
+	}
+	return nil
+}
+
+// saveBudgetUsers links the budget to its users, all of whom must exist.
+func (r *PostgresRepository) saveBudgetUsers(tx *sql.Tx, id uuid.UUID, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		if err := r.checkUser(tx, userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+            INSERT INTO budget_users (budget_id, user_id)
+            VALUES ($1, $2)
+            ON CONFLICT DO NOTHING
+        `, id, userID)
+		if err != nil {
+			logger.Logger.Errorw("Failed to store budget user",
+				"error", err,
+				"budgetID", id,
+				"userID", userID,
+			)
+			return fmt.Errorf("failed to store budget user: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadBudgetUsers returns the users of the given budgets keyed by ID.
+func (r *PostgresRepository) loadBudgetUsers(ids []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	users := make(map[uuid.UUID][]uuid.UUID)
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	rows, err := r.db.Query(`
+        SELECT budget_id, user_id
+        FROM budget_users
+        WHERE budget_id = ANY($1)
+        ORDER BY user_id
+    `, pq.Array(ids))
+	if err != nil {
+		logger.Logger.Errorw("Failed to load budget users",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to load budget users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var budgetID, userID uuid.UUID
+		if err := rows.Scan(&budgetID, &userID); err != nil {
+			logger.Logger.Errorw("Failed to scan budget user",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan budget user: %w", err)
+		}
+		users[budgetID] = append(users[budgetID], userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return users, nil
+}
+
+// loadBudgetAlerts returns the alerts of the budget for periods starting on
+// or after since, newest first.
+func (r *PostgresRepository) loadBudgetAlerts(id uuid.UUID, since time.Time) ([]types.BudgetAlert, error) {
+	rows, err := r.db.Query(`
+        SELECT period_start, threshold, spent_minor, currency, created_at
+        FROM budget_alerts
+        WHERE budget_id = $1 AND period_start >= $2
+        ORDER BY period_start DESC, threshold DESC
+    `, id, since)
+	if err != nil {
+		logger.Logger.Errorw("Failed to load budget alerts",
+			"error", err,
+			"budgetID", id,
+		)
+		return nil, fmt.Errorf("failed to load budget alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []types.BudgetAlert{}
+	for rows.Next() {
+		var (
+			periodStart time.Time
+			createdAt   time.Time
+			alert       = types.BudgetAlert{BudgetID: id.String()}
+		)
+		if err := rows.Scan(&periodStart, &alert.Threshold, &alert.Spent.Amount, &alert.Spent.Currency, &createdAt); err != nil {
+			logger.Logger.Errorw("Failed to scan budget alert",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan budget alert: %w", err)
+		}
+		alert.PeriodStart = periodStart.Format(dates.MonthLayout)
+		alert.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return alerts, nil
+}
+
+func scanBudget(row rowScanner) (types.Budget, uuid.UUID, error) {
+	var (
+		budget      types.Budget
+		id          uuid.UUID
+		category    sql.NullString
+		serviceName sql.NullString
+	)
+	if err := row.Scan(&id, &budget.Name, &budget.Period, &budget.Amount.Amount, &budget.Amount.Currency, &category, &serviceName); err != nil {
+		return budget, id, err
+	}
+
+	budget.ID = id.String()
+	budget.Category = category.String
+	budget.ServiceName = serviceName.String
+	return budget, id, nil
+}
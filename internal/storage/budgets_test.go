@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+func TestReachedThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int64
+		spent     int64
+		want      []int
+		wantState string
+	}{
+		{name: "nothing spent", limit: 100000, spent: 0, wantState: types.BudgetOK},
+		{name: "79.9%", limit: 100000, spent: 79900, wantState: types.BudgetOK},
+		{name: "one minor unit short of 80%", limit: 100000, spent: 79999, wantState: types.BudgetOK},
+		{name: "exactly 80%", limit: 100000, spent: 80000, want: []int{80}, wantState: types.BudgetWarning},
+		{name: "99.99%", limit: 100000, spent: 99999, want: []int{80}, wantState: types.BudgetWarning},
+		{name: "exactly 100%", limit: 100000, spent: 100000, want: []int{80, 100}, wantState: types.BudgetExceeded},
+		{name: "over 100%", limit: 100000, spent: 150000, want: []int{80, 100}, wantState: types.BudgetExceeded},
+		// 80% of 333.33 is 266.664, which 266.66 falls short of.
+		{name: "below 80% of an odd limit", limit: 33333, spent: 26666, wantState: types.BudgetOK},
+		{name: "above 80% of an odd limit", limit: 33333, spent: 26667, want: []int{80}, wantState: types.BudgetWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := types.BudgetStatus{
+				Budget: types.Budget{Amount: money.New(tt.limit, "RUB")},
+				Spent:  money.New(tt.spent, "RUB"),
+			}
+
+			reached := reachedThresholds(status)
+			if !slices.Equal(reached, tt.want) {
+				t.Errorf("reachedThresholds() = %v, want %v", reached, tt.want)
+			}
+			if state := budgetState(reached); state != tt.wantState {
+				t.Errorf("budgetState(%v) = %q, want %q", reached, state, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var ErrTokenNotFound = errors.New("calendar token not found")
+
+// SetCalendarToken stores the hash of a user's calendar feed token, replacing
+// any previously issued one.
+func (r *PostgresRepository) SetCalendarToken(userID uuid.UUID, tokenHash string) error {
+	query := `
+        INSERT INTO calendar_tokens (user_id, token_hash)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE
+        SET token_hash = EXCLUDED.token_hash, created_at = NOW()
+    `
+
+	logger.Logger.Debugw("Issuing calendar token",
+		"userID", userID,
+	)
+
+	_, err := r.db.Exec(query, userID, tokenHash)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		logger.Logger.Warnw("User not found",
+			"userID", userID,
+		)
+		return ErrUserNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to store calendar token",
+			"error", err,
+			"userID", userID,
+		)
+		return fmt.Errorf("failed to store calendar token: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully issued calendar token",
+		"userID", userID,
+	)
+	return nil
+}
+
+func (r *PostgresRepository) DeleteCalendarToken(userID uuid.UUID) error {
+	logger.Logger.Debugw("Revoking calendar token",
+		"userID", userID,
+	)
+
+	result, err := r.db.Exec(`DELETE FROM calendar_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		logger.Logger.Errorw("Failed to revoke calendar token",
+			"error", err,
+			"userID", userID,
+		)
+		return fmt.Errorf("failed to revoke calendar token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to check rows affected",
+			"error", err,
+			"userID", userID,
+		)
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("Calendar token not found for revocation",
+			"userID", userID,
+		)
+		return ErrTokenNotFound
+	}
+
+	logger.Logger.Infow("Successfully revoked calendar token",
+		"userID", userID,
+	)
+	return nil
+}
+
+// CalendarEntries resolves a calendar token hash to its user and returns that
+// user's subscriptions ordered by start date.
+func (r *PostgresRepository) CalendarEntries(tokenHash string) (uuid.UUID, []types.CalendarEntry, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRow(`SELECT user_id FROM calendar_tokens WHERE token_hash = $1`, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Calendar token not found")
+		return uuid.Nil, nil, ErrTokenNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to look up calendar token",
+			"error", err,
+		)
+		return uuid.Nil, nil, fmt.Errorf("failed to look up calendar token: %w", err)
+	}
+
+	query := `
+        SELECT sub_id, service_name, ` + priceSQL + `, currency, billing_interval, billing_interval_count, start_date, end_date
+        FROM subscriptions
+        WHERE user_id = $1
+        ORDER BY start_date
+    `
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		logger.Logger.Errorw("Failed to list calendar entries",
+			"error", err,
+			"userID", userID,
+		)
+		return uuid.Nil, nil, fmt.Errorf("failed to list calendar entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []types.CalendarEntry
+	for rows.Next() {
+		var (
+			entry     types.CalendarEntry
+			dbEndDate sql.NullTime
+		)
+		if err := rows.Scan(
+			&entry.SubID,
+			&entry.ServiceName,
+			&entry.Price.Amount,
+			&entry.Price.Currency,
+			&entry.BillingInterval,
+			&entry.BillingIntervalCount,
+			&entry.StartDate,
+			&dbEndDate,
+		); err != nil {
+			logger.Logger.Errorw("Failed to scan calendar entry",
+				"error", err,
+			)
+			return uuid.Nil, nil, fmt.Errorf("failed to scan calendar entry: %w", err)
+		}
+
+		if dbEndDate.Valid {
+			entry.EndDate = &dbEndDate.Time
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return uuid.Nil, nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+
+	ids := make([]uuid.UUID, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.SubID
+	}
+	pauses, err := r.loadPauses(r.db, ids)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	for i := range entries {
+		entries[i].Pauses = pauses[entries[i].SubID]
+	}
+
+	logger.Logger.Debugw("Successfully listed calendar entries",
+		"userID", userID,
+		"count", len(entries),
+	)
+	return userID, entries, nil
+}
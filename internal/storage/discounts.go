@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var (
+	ErrInvalidDiscount  = errors.New("invalid discount")
+	ErrDiscountNotFound = errors.New("discount not found")
+)
+
+// discount is a stored discount together with its ID.
+type discount struct {
+	id uuid.UUID
+	billing.Discount
+}
+
+func (d discount) view(currency string) types.Discount {
+	v := types.Discount{
+		ID:        d.id.String(),
+		Percent:   d.Percent,
+		StartDate: dates.FormatStart(d.From),
+	}
+	if d.Percent == 0 {
+		amount := money.New(d.Amount, currency)
+		v.Amount = &amount
+	}
+	if d.To != nil {
+		v.EndDate = dates.FormatEnd(*d.To)
+	}
+	return v
+}
+
+// AddDiscount attaches a discount to the subscription. It starts on the
+// subscription's first day unless req says otherwise and lasts for req.Months
+// months, until req.EndDate or, with neither, until the subscription ends.
+func (r *PostgresRepository) AddDiscount(id uuid.UUID, req types.DiscountRequest) (types.Discount, error) {
+	logger.Logger.Debugw("Adding discount",
+		"subscriptionID", id,
+		"discount", req,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return types.Discount{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		currency  string
+		startDate time.Time
+	)
+	err = tx.QueryRow(`
+        SELECT currency, start_date
+        FROM subscriptions
+        WHERE sub_id = $1
+        FOR UPDATE
+    `, id).Scan(&currency, &startDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Subscription not found",
+			"subscriptionID", id,
+		)
+		return types.Discount{}, ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Discount{}, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	d, err := newDiscount(req, currency, startDate)
+	if err != nil {
+		return types.Discount{}, err
+	}
+
+	var percent, amount sql.NullInt64
+	if d.Percent != 0 {
+		percent = sql.NullInt64{Int64: int64(d.Percent), Valid: true}
+	} else {
+		amount = sql.NullInt64{Int64: d.Amount, Valid: true}
+	}
+	_, err = tx.Exec(`
+        INSERT INTO subscription_discounts (discount_id, sub_id, percent, amount_minor, start_date, end_date)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, d.id, id, percent, amount, d.From, d.To)
+	if err != nil {
+		logger.Logger.Errorw("Failed to store discount",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Discount{}, fmt.Errorf("failed to store discount: %w", err)
+	}
+
+	if err := r.bumpVersion(tx, id); err != nil {
+		return types.Discount{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Discount{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully added discount",
+		"subscriptionID", id,
+		"discountID", d.id,
+	)
+	return d.view(currency), nil
+}
+
+// newDiscount validates req against the subscription it is added to.
+func newDiscount(req types.DiscountRequest, currency string, startDate time.Time) (discount, error) {
+	d := discount{id: uuid.New()}
+
+	switch {
+	case (req.Percent == 0) == (req.Amount == nil):
+		return d, fmt.Errorf("%w: set either percent or amount", ErrInvalidDiscount)
+	case req.Amount != nil && req.Amount.Currency != currency:
+		return d, fmt.Errorf("%w: subscription is billed in %s", ErrCurrencyMismatch, currency)
+	case req.Amount != nil && req.Amount.Amount <= 0:
+		return d, fmt.Errorf("%w: amount must be positive", ErrInvalidDiscount)
+	case req.Months != 0 && req.EndDate != "":
+		return d, fmt.Errorf("%w: set either months or end_date", ErrInvalidDiscount)
+	}
+	d.Percent = req.Percent
+	if req.Amount != nil {
+		d.Amount = req.Amount.Amount
+	}
+
+	d.From = dates.Day(startDate)
+	if req.StartDate != "" {
+		from, err := dates.ParseStart(req.StartDate)
+		if err != nil {
+			return d, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+		}
+		d.From = from
+	}
+
+	switch {
+	case req.Months != 0:
+		to := dates.AddMonths(d.From, req.Months).AddDate(0, 0, -1)
+		d.To = &to
+	case req.EndDate != "":
+		to, err := dates.ParseEnd(req.EndDate)
+		if err != nil {
+			return d, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+		}
+		if to.Before(d.From) {
+			return d, fmt.Errorf("%w: discount ends before it starts", ErrInvalidDate)
+		}
+		d.To = &to
+	}
+	return d, nil
+}
+
+// DeleteDiscount removes a discount from the subscription.
+func (r *PostgresRepository) DeleteDiscount(id, discountID uuid.UUID) error {
+	logger.Logger.Debugw("Deleting discount",
+		"subscriptionID", id,
+		"discountID", discountID,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM subscription_discounts WHERE sub_id = $1 AND discount_id = $2`, id, discountID)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete discount",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to delete discount: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM subscriptions WHERE sub_id = $1)`, id).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check subscription: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrDiscountNotFound
+	}
+
+	if err := r.bumpVersion(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully deleted discount",
+		"subscriptionID", id,
+		"discountID", discountID,
+	)
+	return nil
+}
+
+// loadDiscounts returns the discounts of the given subscriptions keyed by ID,
+// ordered by the day they start.
+func (r *PostgresRepository) loadDiscounts(q dbtx, ids []uuid.UUID) (map[uuid.UUID][]discount, error) {
+	discounts := make(map[uuid.UUID][]discount)
+	if len(ids) == 0 {
+		return discounts, nil
+	}
+
+	rows, err := q.Query(`
+        SELECT sub_id, discount_id, percent, amount_minor, start_date, end_date
+        FROM subscription_discounts
+        WHERE sub_id = ANY($1)
+        ORDER BY start_date, discount_id
+    `, pq.Array(ids))
+	if err != nil {
+		logger.Logger.Errorw("Failed to load discounts",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to load discounts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			subID   uuid.UUID
+			d       discount
+			percent sql.NullInt64
+			amount  sql.NullInt64
+			to      sql.NullTime
+		)
+		if err := rows.Scan(&subID, &d.id, &percent, &amount, &d.From, &to); err != nil {
+			logger.Logger.Errorw("Failed to scan discount",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan discount: %w", err)
+		}
+
+		d.Percent = int(percent.Int64)
+		d.Amount = amount.Int64
+		if to.Valid {
+			d.To = &to.Time
+		}
+		discounts[subID] = append(discounts[subID], d)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return discounts, nil
+}
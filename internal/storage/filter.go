@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SubscriptionFilter narrows subscriptions down to those matching every set
+// field. A user matches the subscriptions they own or share, and tags match
+// subscriptions carrying all of them.
+type SubscriptionFilter struct {
+	UserID      uuid.UUID
+	ServiceName string
+	Category    string
+	Tags        []string
+}
+
+// where appends the filter conditions to a query that already has a WHERE
+// clause, numbering placeholders after the existing args.
+func (f SubscriptionFilter) where(query string, args []interface{}) (string, []interface{}) {
+	if f.UserID != uuid.Nil {
+		args = append(args, f.UserID)
+		query += fmt.Sprintf(
+			" AND (user_id = $%[1]d OR sub_id IN (SELECT sub_id FROM subscription_members WHERE user_id = $%[1]d))",
+			len(args),
+		)
+	}
+	if f.ServiceName != "" {
+		// A name known to the catalog matches every subscription linked to
+		// that service, however it was spelled.
+		args = append(args, f.ServiceName, serviceKey(f.ServiceName))
+		query += fmt.Sprintf(
+			" AND (service_name = $%d OR service_id IN (SELECT service_id FROM service_aliases WHERE alias_key = $%d))",
+			len(args)-1, len(args),
+		)
+	}
+	if f.Category != "" {
+		args = append(args, normalizeLabel(f.Category))
+		query += fmt.Sprintf(" AND "+categorySQL+" = $%d", len(args))
+	}
+	if tags := normalizeTags(f.Tags); len(tags) > 0 {
+		args = append(args, pq.Array(tags))
+		query += fmt.Sprintf(" AND tags @> $%d", len(args))
+	}
+	return query, args
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+// CreateIdempotent creates a subscription once per idempotency key. A repeated
+// key with the same fingerprint returns the originally created ID with
+// replayed set to true; a different fingerprint yields ErrIdempotencyMismatch.
+// Keys older than ttl are treated as unused.
+func (r *PostgresRepository) CreateIdempotent(sub types.Subscription, key, fingerprint string, ttl time.Duration) (uuid.UUID, bool, error) {
+	logger.Logger.Debugw("Creating subscription with idempotency key",
+		"idempotencyKey", key,
+		"userID", sub.UserID,
+		"serviceName", sub.ServiceName,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return uuid.Nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Concurrent retries with the same key are serialized until commit.
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, key)
+	if err != nil {
+		logger.Logger.Errorw("Failed to lock idempotency key",
+			"error", err,
+			"idempotencyKey", key,
+		)
+		return uuid.Nil, false, fmt.Errorf("failed to lock idempotency key: %w", err)
+	}
+
+	expiredBefore := time.Now().Add(-ttl)
+	_, err = tx.Exec(`DELETE FROM idempotency_keys WHERE created_at < $1`, expiredBefore)
+	if err != nil {
+		logger.Logger.Errorw("Failed to purge expired idempotency keys",
+			"error", err,
+		)
+		return uuid.Nil, false, fmt.Errorf("failed to purge expired idempotency keys: %w", err)
+	}
+
+	var (
+		dbFingerprint string
+		dbSubID       uuid.UUID
+	)
+	err = tx.QueryRow(`
+        SELECT fingerprint, sub_id
+        FROM idempotency_keys
+        WHERE idempotency_key = $1
+    `, key).Scan(&dbFingerprint, &dbSubID)
+
+	switch {
+	case err == nil:
+		if dbFingerprint != fingerprint {
+			logger.Logger.Warnw("Idempotency key reused with a different payload",
+				"idempotencyKey", key,
+			)
+			return uuid.Nil, false, ErrIdempotencyMismatch
+		}
+		logger.Logger.Infow("Replaying subscription creation",
+			"idempotencyKey", key,
+			"subscriptionID", dbSubID,
+		)
+		return dbSubID, true, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		logger.Logger.Errorw("Failed to look up idempotency key",
+			"error", err,
+			"idempotencyKey", key,
+		)
+		return uuid.Nil, false, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+
+	subID, err := r.insertSubscription(tx, sub)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO idempotency_keys (idempotency_key, fingerprint, sub_id)
+        VALUES ($1, $2, $3)
+    `, key, fingerprint, subID)
+	if err != nil {
+		logger.Logger.Errorw("Failed to store idempotency key",
+			"error", err,
+			"idempotencyKey", key,
+		)
+		return uuid.Nil, false, fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"idempotencyKey", key,
+		)
+		return uuid.Nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return subID, false, nil
+}
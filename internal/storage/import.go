@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+type ImportResult struct {
+	Created int
+	Updated int
+}
+
+// Import upserts subscriptions by their natural key (user, service name and
+// start month) in a single transaction. A dry run performs the same writes and
+// rolls them back, so the counts reflect what a real import would do.
+func (r *PostgresRepository) Import(subs []types.Subscription, dryRun bool) (ImportResult, error) {
+	logger.Logger.Debugw("Importing subscriptions",
+		"count", len(subs),
+		"dryRun", dryRun,
+	)
+
+	var result ImportResult
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT sub_id
+        FROM subscriptions
+        WHERE user_id = $1 AND service_name = $2 AND start_date = $3
+        LIMIT 1
+        FOR UPDATE
+    `
+
+	for _, sub := range subs {
+		startDate, err := dates.ParseStart(sub.StartDate)
+		if err != nil {
+			return result, fmt.Errorf("invalid start_date format: %w", err)
+		}
+		if err := r.resolveService(tx, &sub); err != nil {
+			return result, err
+		}
+
+		var id uuid.UUID
+		err = tx.QueryRow(query, sub.UserID, sub.ServiceName, startDate).Scan(&id)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := r.insertSubscription(tx, sub); err != nil {
+				return result, err
+			}
+			result.Created++
+		case err != nil:
+			logger.Logger.Errorw("Failed to look up subscription by natural key",
+				"error", err,
+				"userID", sub.UserID,
+				"serviceName", sub.ServiceName,
+			)
+			return result, fmt.Errorf("failed to look up subscription: %w", err)
+		default:
+			if _, err := r.updateSubscription(tx, id, sub, nil); err != nil {
+				return result, err
+			}
+			result.Updated++
+		}
+	}
+
+	if dryRun {
+		logger.Logger.Infow("Dry-run import finished, rolling back",
+			"created", result.Created,
+			"updated", result.Updated,
+		)
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+		)
+		return ImportResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully imported subscriptions",
+		"created", result.Created,
+		"updated", result.Updated,
+	)
+	return result, nil
+}
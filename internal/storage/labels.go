@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"slices"
+)
+
+// categorySQL is the effective category of a subscription: its own one or,
+// without it, the category of its catalog service.
+const categorySQL = `COALESCE(category, (SELECT category FROM services WHERE services.service_id = subscriptions.service_id))`
+
+// normalizeLabel brings a category or tag into the form it is stored and
+// filtered in, the same one service names are matched in.
+func normalizeLabel(label string) string {
+	return serviceKey(label)
+}
+
+// normalizeTags normalizes tags and returns them sorted, without blanks and
+// duplicates.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = normalizeLabel(tag); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+const (
+	ActionPause      = "pause"
+	ActionResume     = "resume"
+	ActionCancel     = "cancel"
+	ActionReactivate = "reactivate"
+)
+
+var (
+	ErrInvalidTransition = errors.New("status transition not allowed")
+	ErrInvalidDate       = errors.New("invalid date")
+	ErrEndDateRequired   = errors.New("cancelled subscription must keep an end date")
+)
+
+// allowedFrom lists the statuses each lifecycle action may be applied to.
+var allowedFrom = map[string][]string{
+	ActionPause:      {types.StatusActive},
+	ActionResume:     {types.StatusPaused},
+	ActionCancel:     {types.StatusActive, types.StatusPaused},
+	ActionReactivate: {types.StatusCancelled, types.StatusExpired},
+}
+
+type TransitionError struct {
+	Action string
+	From   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot %s a subscription with status %s", e.Action, e.From)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// effectiveStatus derives the reported status from the stored one: running
+// subscriptions whose last day has passed are expired.
+func effectiveStatus(status string, endDate sql.NullTime, now time.Time) string {
+	if status != types.StatusCancelled && endDate.Valid && endDate.Time.Before(dates.Day(now)) {
+		return types.StatusExpired
+	}
+	return status
+}
+
+// afterCurrentMonth reports whether month starts after the current month.
+func afterCurrentMonth(month time.Time) bool {
+	return month.After(dates.MonthStart(time.Now()))
+}
+
+type subscriptionState struct {
+	status    string
+	startDate time.Time
+	endDate   sql.NullTime
+}
+
+// Pause suspends billing starting with month (current month if empty). Pauses
+// are tracked by month, so a date pauses its whole month. The status changes
+// at once, so a later month is rejected rather than paused ahead of time.
+func (r *PostgresRepository) Pause(id uuid.UUID, month string) (string, error) {
+	return r.transition(id, ActionPause, month, func(tx *sql.Tx, state subscriptionState, at time.Time) (string, error) {
+		if afterCurrentMonth(at) {
+			return "", fmt.Errorf("%w: pause month is after the current month", ErrInvalidDate)
+		}
+		if at.Before(dates.MonthStart(state.startDate)) || (state.endDate.Valid && at.After(state.endDate.Time)) {
+			return "", fmt.Errorf("%w: pause month is outside the subscription", ErrInvalidDate)
+		}
+
+		_, err := tx.Exec(`INSERT INTO subscription_pauses (sub_id, start_month) VALUES ($1, $2)`, id, at)
+		if err != nil {
+			return "", fmt.Errorf("failed to record pause: %w", err)
+		}
+		return types.StatusPaused, nil
+	})
+}
+
+// Resume restarts billing from month (current month if empty), closing
+// the open pause on the month before. Resuming in the month the pause started
+// drops the pause. Like Pause, it rejects a month after the current one.
+func (r *PostgresRepository) Resume(id uuid.UUID, month string) (string, error) {
+	return r.transition(id, ActionResume, month, func(tx *sql.Tx, state subscriptionState, at time.Time) (string, error) {
+		if afterCurrentMonth(at) {
+			return "", fmt.Errorf("%w: resume month is after the current month", ErrInvalidDate)
+		}
+
+		var pauseStart time.Time
+		err := tx.QueryRow(`
+            SELECT start_month
+            FROM subscription_pauses
+            WHERE sub_id = $1 AND end_month IS NULL
+            ORDER BY start_month DESC
+            LIMIT 1
+        `, id).Scan(&pauseStart)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("failed to find open pause: %w", err)
+		}
+
+		if err == nil && at.Before(pauseStart) {
+			return "", fmt.Errorf("%w: resume month is before the pause started", ErrInvalidDate)
+		}
+		if err := closePause(tx, id, at.AddDate(0, -1, 0)); err != nil {
+			return "", err
+		}
+		return types.StatusActive, nil
+	})
+}
+
+// Cancel ends the subscription on date: the last day of the month for MM-YYYY
+// or an empty date (the current month), the day itself otherwise. Cancelling
+// never extends a subscription: an explicit date after its end is rejected and
+// the current month keeps an earlier end.
+func (r *PostgresRepository) Cancel(id uuid.UUID, date string) (string, error) {
+	return r.transition(id, ActionCancel, date, func(tx *sql.Tx, state subscriptionState, at time.Time) (string, error) {
+		end := dates.MonthEnd(at)
+		if date != "" {
+			end, _ = dates.ParseEnd(date)
+		}
+		if end.Before(state.startDate) {
+			return "", fmt.Errorf("%w: cancellation month is before the subscription started", ErrInvalidDate)
+		}
+		if state.endDate.Valid && end.After(state.endDate.Time) {
+			if date != "" {
+				return "", fmt.Errorf("%w: cancellation date is after the subscription ends", ErrInvalidDate)
+			}
+			end = state.endDate.Time
+		}
+
+		if err := closePause(tx, id, at); err != nil {
+			return "", err
+		}
+		_, err := tx.Exec(`UPDATE subscriptions SET end_date = $1 WHERE sub_id = $2`, end, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to set end date: %w", err)
+		}
+		return types.StatusCancelled, nil
+	})
+}
+
+// Reactivate makes a cancelled or expired subscription open-ended again. Any
+// pause left open when it ended is closed on the previous month.
+func (r *PostgresRepository) Reactivate(id uuid.UUID) (string, error) {
+	return r.transition(id, ActionReactivate, "", func(tx *sql.Tx, state subscriptionState, at time.Time) (string, error) {
+		if err := closePause(tx, id, at.AddDate(0, -1, 0)); err != nil {
+			return "", err
+		}
+		_, err := tx.Exec(`UPDATE subscriptions SET end_date = NULL WHERE sub_id = $1`, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to clear end date: %w", err)
+		}
+		return types.StatusActive, nil
+	})
+}
+
+// checkEndDate rejects clearing the end date of a cancelled subscription,
+// which only Reactivate may do.
+func checkEndDate(q dbtx, id uuid.UUID, endDate *time.Time) error {
+	if endDate != nil {
+		return nil
+	}
+
+	var status string
+	err := q.QueryRow(`SELECT status FROM subscriptions WHERE sub_id = $1`, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription status",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to get subscription status: %w", err)
+	}
+	if status == types.StatusCancelled {
+		return ErrEndDateRequired
+	}
+	return nil
+}
+
+// closePause ends the open pause of the subscription with the month last. A
+// pause starting after last never took effect and is dropped instead.
+func closePause(tx *sql.Tx, id uuid.UUID, last time.Time) error {
+	_, err := tx.Exec(`DELETE FROM subscription_pauses WHERE sub_id = $1 AND end_month IS NULL AND start_month > $2`, id, last)
+	if err != nil {
+		return fmt.Errorf("failed to drop pause: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE subscription_pauses SET end_month = $1 WHERE sub_id = $2 AND end_month IS NULL`, last, id)
+	if err != nil {
+		return fmt.Errorf("failed to close pause: %w", err)
+	}
+	return nil
+}
+
+// transition locks the subscription, checks that action is allowed from its
+// current status, runs apply and stores the resulting status.
+func (r *PostgresRepository) transition(
+	id uuid.UUID,
+	action, month string,
+	apply func(tx *sql.Tx, state subscriptionState, at time.Time) (string, error),
+) (string, error) {
+	now := time.Now()
+	at := dates.MonthStart(now)
+	if month != "" {
+		parsed, err := parseMonth(month)
+		if err != nil {
+			return "", err
+		}
+		at = parsed
+	}
+
+	logger.Logger.Debugw("Changing subscription status",
+		"subscriptionID", id,
+		"action", action,
+		"month", at.Format(dates.MonthLayout),
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state subscriptionState
+	err = tx.QueryRow(`
+        SELECT status, start_date, end_date
+        FROM subscriptions
+        WHERE sub_id = $1
+        FOR UPDATE
+    `, id).Scan(&state.status, &state.startDate, &state.endDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Subscription not found",
+			"subscriptionID", id,
+		)
+		return "", ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return "", fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	state.status = effectiveStatus(state.status, state.endDate, now)
+	if !slices.Contains(allowedFrom[action], state.status) {
+		logger.Logger.Warnw("Subscription status transition not allowed",
+			"subscriptionID", id,
+			"action", action,
+			"status", state.status,
+		)
+		return "", &TransitionError{Action: action, From: state.status}
+	}
+
+	status, err := apply(tx, state, at)
+	if err != nil {
+		logger.Logger.Errorw("Failed to change subscription status",
+			"error", err,
+			"subscriptionID", id,
+			"action", action,
+		)
+		return "", err
+	}
+
+	_, err = tx.Exec(`UPDATE subscriptions SET status = $1, version = version + 1 WHERE sub_id = $2`, status, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to update subscription status",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return "", fmt.Errorf("failed to update subscription status: %w", err)
+	}
+
+	if err := r.recordChange(tx, types.EventSubscriptionUpdated, id); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully changed subscription status",
+		"subscriptionID", id,
+		"action", action,
+		"status", status,
+	)
+	return status, nil
+}
+
+// loadPauses returns the pause ranges of the given subscriptions keyed by ID.
+func (r *PostgresRepository) loadPauses(q dbtx, ids []uuid.UUID) (map[uuid.UUID][]billing.Range, error) {
+	pauses := make(map[uuid.UUID][]billing.Range)
+	if len(ids) == 0 {
+		return pauses, nil
+	}
+
+	rows, err := q.Query(`
+        SELECT sub_id, start_month, end_month
+        FROM subscription_pauses
+        WHERE sub_id = ANY($1)
+        ORDER BY start_month
+    `, pq.Array(ids))
+	if err != nil {
+		logger.Logger.Errorw("Failed to load subscription pauses",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to load subscription pauses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			subID uuid.UUID
+			from  time.Time
+			to    sql.NullTime
+		)
+		if err := rows.Scan(&subID, &from, &to); err != nil {
+			logger.Logger.Errorw("Failed to scan subscription pause",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan subscription pause: %w", err)
+		}
+
+		pause := billing.Range{From: from}
+		if to.Valid {
+			pause.To = &to.Time
+		}
+		pauses[subID] = append(pauses[subID], pause)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return pauses, nil
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ItserX/rest/internal/dates"
+)
+
+func TestAfterCurrentMonth(t *testing.T) {
+	current := dates.MonthStart(time.Now())
+	tests := []struct {
+		month time.Time
+		want  bool
+	}{
+		{month: current.AddDate(0, -1, 0), want: false},
+		{month: current, want: false},
+		{month: current.AddDate(0, 1, 0), want: true},
+	}
+
+	for _, tt := range tests {
+		if got := afterCurrentMonth(tt.month); got != tt.want {
+			t.Errorf("afterCurrentMonth(%s) = %v, want %v", tt.month.Format(dates.MonthLayout), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var ErrInvalidSharing = errors.New("invalid sharing")
+
+// sharing is how a subscription is split between its owner and its members,
+// who are kept in the order their fixed shares are taken in.
+type sharing struct {
+	rule    string
+	members []uuid.UUID
+	shares  []int64
+}
+
+func (s sharing) view(currency string) types.Sharing {
+	v := types.Sharing{SplitRule: s.rule, Members: make([]types.Member, 0, len(s.members))}
+	for i, userID := range s.members {
+		member := types.Member{UserID: userID}
+		switch s.rule {
+		case billing.SplitPercent:
+			member.Percent = int(s.shares[i])
+		case billing.SplitFixed:
+			amount := money.New(s.shares[i], currency)
+			member.Amount = &amount
+		}
+		v.Members = append(v.Members, member)
+	}
+	return v
+}
+
+func (s sharing) split() billing.Split {
+	return billing.Split{Rule: s.rule, Shares: s.shares}
+}
+
+// payer returns the index of userID in the split, 0 being the owner, or -1
+// if the user does not pay for the subscription.
+func (s sharing) payer(userID, owner uuid.UUID) int {
+	if userID == owner {
+		return 0
+	}
+	for i, member := range s.members {
+		if member == userID {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// GetSharing returns the members of the subscription and how its cost is split.
+func (r *PostgresRepository) GetSharing(id uuid.UUID) (types.Sharing, error) {
+	var rule, currency string
+	err := r.db.QueryRow(`SELECT split_rule, currency FROM subscriptions WHERE sub_id = $1`, id).Scan(&rule, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Subscription not found",
+			"subscriptionID", id,
+		)
+		return types.Sharing{}, ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Sharing{}, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	shared, err := r.loadSharing(r.db, []uuid.UUID{id})
+	if err != nil {
+		return types.Sharing{}, err
+	}
+	s, ok := shared[id]
+	if !ok {
+		s = sharing{rule: rule}
+	}
+	return s.view(currency), nil
+}
+
+// SetSharing replaces the members of the subscription and its split rule.
+// Percent shares may not add up to more than 100; the owner pays the rest.
+func (r *PostgresRepository) SetSharing(id uuid.UUID, req types.Sharing) (types.Sharing, error) {
+	logger.Logger.Debugw("Setting subscription sharing",
+		"subscriptionID", id,
+		"splitRule", req.SplitRule,
+		"members", len(req.Members),
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return types.Sharing{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		owner    uuid.UUID
+		currency string
+	)
+	err = tx.QueryRow(`
+        SELECT user_id, currency
+        FROM subscriptions
+        WHERE sub_id = $1
+        FOR UPDATE
+    `, id).Scan(&owner, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Subscription not found",
+			"subscriptionID", id,
+		)
+		return types.Sharing{}, ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Sharing{}, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	s, err := newSharing(req, owner, currency)
+	if err != nil {
+		return types.Sharing{}, err
+	}
+	for _, userID := range s.members {
+		if err := r.checkUser(tx, userID); err != nil {
+			return types.Sharing{}, err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE subscriptions SET split_rule = $1 WHERE sub_id = $2`, s.rule, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to update split rule",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Sharing{}, fmt.Errorf("failed to update split rule: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM subscription_members WHERE sub_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete subscription members",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Sharing{}, fmt.Errorf("failed to delete subscription members: %w", err)
+	}
+
+	for i, userID := range s.members {
+		_, err = tx.Exec(`
+            INSERT INTO subscription_members (sub_id, user_id, position, share)
+            VALUES ($1, $2, $3, $4)
+        `, id, userID, i, s.shares[i])
+		if err != nil {
+			logger.Logger.Errorw("Failed to store subscription member",
+				"error", err,
+				"subscriptionID", id,
+				"userID", userID,
+			)
+			return types.Sharing{}, fmt.Errorf("failed to store subscription member: %w", err)
+		}
+	}
+
+	if err := r.bumpVersion(tx, id); err != nil {
+		return types.Sharing{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return types.Sharing{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully set subscription sharing",
+		"subscriptionID", id,
+		"splitRule", s.rule,
+		"members", len(s.members),
+	)
+	return s.view(currency), nil
+}
+
+// newSharing validates req for a subscription owned by owner.
+func newSharing(req types.Sharing, owner uuid.UUID, currency string) (sharing, error) {
+	s := sharing{rule: req.SplitRule}
+	var percent int64
+	for _, member := range req.Members {
+		switch {
+		case member.UserID == owner:
+			return s, fmt.Errorf("%w: the owner is not listed as a member", ErrInvalidSharing)
+		case s.payer(member.UserID, owner) > 0:
+			return s, fmt.Errorf("%w: member %s is listed twice", ErrInvalidSharing, member.UserID)
+		}
+
+		var share int64
+		switch req.SplitRule {
+		case billing.SplitEqual:
+			if member.Percent != 0 || member.Amount != nil {
+				return s, fmt.Errorf("%w: equal split takes no percent or amount", ErrInvalidSharing)
+			}
+		case billing.SplitPercent:
+			if member.Percent == 0 || member.Amount != nil {
+				return s, fmt.Errorf("%w: percent split needs a percent for every member", ErrInvalidSharing)
+			}
+			share = int64(member.Percent)
+			percent += share
+		case billing.SplitFixed:
+			if member.Amount == nil || member.Percent != 0 {
+				return s, fmt.Errorf("%w: fixed split needs an amount for every member", ErrInvalidSharing)
+			}
+			if member.Amount.Currency != currency {
+				return s, fmt.Errorf("%w: subscription is billed in %s", ErrCurrencyMismatch, currency)
+			}
+			if member.Amount.Amount <= 0 {
+				return s, fmt.Errorf("%w: amount must be positive", ErrInvalidSharing)
+			}
+			share = member.Amount.Amount
+		default:
+			return s, fmt.Errorf("%w: unknown split rule %q", ErrInvalidSharing, req.SplitRule)
+		}
+
+		s.members = append(s.members, member.UserID)
+		s.shares = append(s.shares, share)
+	}
+	if percent > 100 {
+		return s, fmt.Errorf("%w: percent shares add up to %d", ErrInvalidSharing, percent)
+	}
+	return s, nil
+}
+
+// loadSharing returns how the given subscriptions are shared, keyed by ID.
+// Subscriptions without members are left out.
+func (r *PostgresRepository) loadSharing(q dbtx, ids []uuid.UUID) (map[uuid.UUID]sharing, error) {
+	shared := make(map[uuid.UUID]sharing)
+	if len(ids) == 0 {
+		return shared, nil
+	}
+
+	rows, err := q.Query(`
+        SELECT m.sub_id, s.split_rule, m.user_id, m.share
+        FROM subscription_members m
+        JOIN subscriptions s ON s.sub_id = m.sub_id
+        WHERE m.sub_id = ANY($1)
+        ORDER BY m.sub_id, m.position
+    `, pq.Array(ids))
+	if err != nil {
+		logger.Logger.Errorw("Failed to load subscription members",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to load subscription members: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			subID  uuid.UUID
+			rule   string
+			userID uuid.UUID
+			share  int64
+		)
+		if err := rows.Scan(&subID, &rule, &userID, &share); err != nil {
+			logger.Logger.Errorw("Failed to scan subscription member",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan subscription member: %w", err)
+		}
+
+		s := shared[subID]
+		s.rule = rule
+		s.members = append(s.members, userID)
+		s.shares = append(s.shares, share)
+		shared[subID] = s
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return shared, nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// deliveriesLimit caps how many of the latest deliveries are listed.
+const deliveriesLimit = 100
+
+// DeliveryFilter narrows the delivery log to a user or an outcome. Empty
+// fields match every delivery.
+type DeliveryFilter struct {
+	UserID uuid.UUID
+	Status string
+}
+
+func (r *PostgresRepository) RecordDelivery(delivery types.Delivery) error {
+	_, err := r.db.Exec(`
+        INSERT INTO notification_deliveries (channel, event, event_id, user_id, recipient, subject, status, attempts, error)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `,
+		delivery.Channel,
+		delivery.Event,
+		nullString(delivery.EventID),
+		uuid.NullUUID{UUID: delivery.UserID, Valid: delivery.UserID != uuid.Nil},
+		nullString(delivery.Recipient),
+		delivery.Subject,
+		delivery.Status,
+		delivery.Attempts,
+		nullString(delivery.Error),
+	)
+	if err != nil {
+		logger.Logger.Errorw("Failed to record delivery",
+			"error", err,
+			"channel", delivery.Channel,
+			"event", delivery.Event,
+		)
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	return nil
+}
+
+// EventDelivered reports whether a notification about the subscription event
+// was already sent through channel, or skipped for lack of an address there.
+func (r *PostgresRepository) EventDelivered(eventID uuid.UUID, channel string) (bool, error) {
+	var delivered bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM notification_deliveries
+            WHERE event_id = $1 AND channel = $2 AND status IN ('sent', 'skipped')
+        )
+    `, eventID, channel).Scan(&delivered)
+	if err != nil {
+		logger.Logger.Errorw("Failed to check event delivery",
+			"error", err,
+			"eventID", eventID,
+			"channel", channel,
+		)
+		return false, fmt.Errorf("failed to check event delivery: %w", err)
+	}
+	return delivered, nil
+}
+
+// ListDeliveries returns the latest deliveries matching filter, newest first.
+func (r *PostgresRepository) ListDeliveries(filter DeliveryFilter) ([]types.Delivery, error) {
+	logger.Logger.Debugw("Listing deliveries",
+		"userID", filter.UserID,
+		"status", filter.Status,
+	)
+
+	rows, err := r.db.Query(`
+        SELECT delivery_id, channel, event, event_id, user_id, recipient, subject, status, attempts, error, created_at
+        FROM notification_deliveries
+        WHERE ($1::uuid IS NULL OR user_id = $1) AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, delivery_id DESC
+        LIMIT $3
+    `, uuid.NullUUID{UUID: filter.UserID, Valid: filter.UserID != uuid.Nil}, filter.Status, deliveriesLimit)
+	if err != nil {
+		logger.Logger.Errorw("Failed to list deliveries",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []types.Delivery{}
+	for rows.Next() {
+		var (
+			delivery  types.Delivery
+			eventID   uuid.NullUUID
+			userID    uuid.NullUUID
+			recipient sql.NullString
+			errText   sql.NullString
+			createdAt time.Time
+		)
+		err := rows.Scan(&delivery.ID, &delivery.Channel, &delivery.Event, &eventID, &userID, &recipient,
+			&delivery.Subject, &delivery.Status, &delivery.Attempts, &errText, &createdAt)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan delivery",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+
+		if eventID.Valid {
+			delivery.EventID = eventID.UUID.String()
+		}
+		delivery.UserID = userID.UUID
+		delivery.Recipient = recipient.String
+		delivery.Error = errText.String
+		delivery.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// expiredLookback is how long after its last day a subscription still gets an
+// expired event, so that events are not lost while the service is down.
+const expiredLookback = 7
+
+// OutboxEvent is an event claimed for publishing.
+type OutboxEvent struct {
+	ID    uuid.UUID
+	Event types.SubscriptionEvent
+	// Attempts is how many times publishing the event has failed so far.
+	Attempts int
+}
+
+// OutboxAttempt is the outcome of publishing an event once.
+type OutboxAttempt struct {
+	Error     string
+	Published bool
+	// RetryIn is the wait before the next attempt of an event that failed.
+	RetryIn time.Duration
+}
+
+// recordChange records eventType to the outbox with the state the
+// subscription has within q.
+func (r *PostgresRepository) recordChange(q dbtx, eventType string, id uuid.UUID) error {
+	sub, err := r.getSubscription(q, id)
+	if err != nil {
+		return err
+	}
+	_, err = recordEvent(q, eventType, id, sub, "")
+	return err
+}
+
+// recordChanges records an updated event for every subscription in ids, for
+// changes made to many subscriptions at once.
+func (r *PostgresRepository) recordChanges(q dbtx, ids []uuid.UUID) error {
+	for _, id := range ids {
+		if err := r.recordChange(q, types.EventSubscriptionUpdated, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordEvent writes an event to the outbox. Called with the transaction of
+// the change it describes, the event is stored if and only if the change is
+// committed. An event with a dedupKey already used is not stored again, which
+// is reported as false.
+func recordEvent(q dbtx, eventType string, id uuid.UUID, sub *types.Subscription, dedupKey string) (bool, error) {
+	eventID := uuid.New()
+	payload, err := json.Marshal(types.SubscriptionEvent{
+		ID:        eventID.String(),
+		Type:      eventType,
+		SubID:     id.String(),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Data:      sub,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	result, err := q.Exec(`
+        INSERT INTO outbox_events (event_id, event_type, sub_id, dedup_key, payload)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (dedup_key) DO NOTHING
+    `, eventID, eventType, id, nullString(dedupKey), payload)
+	if err != nil {
+		logger.Logger.Errorw("Failed to record event",
+			"error", err,
+			"event", eventType,
+			"subscriptionID", id,
+		)
+		return false, fmt.Errorf("failed to record event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"eventID", eventID,
+		)
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Debugw("Event already recorded",
+			"dedupKey", dedupKey,
+		)
+		return false, nil
+	}
+
+	logger.Logger.Debugw("Event recorded",
+		"eventID", eventID,
+		"event", eventType,
+		"subscriptionID", id,
+	)
+	return true, nil
+}
+
+// RecordExpiredSubscriptions records an expired event for every subscription
+// whose last day has recently passed and that got none for that day yet. It
+// returns the number of events recorded.
+func (r *PostgresRepository) RecordExpiredSubscriptions(now time.Time) (int, error) {
+	today := dates.Day(now)
+	rows, err := r.db.Query(`
+        SELECT s.sub_id, s.end_date
+        FROM subscriptions s
+        WHERE s.status <> 'cancelled' AND s.end_date < $1 AND s.end_date >= $2
+          AND NOT EXISTS (
+              SELECT 1 FROM outbox_events e
+              WHERE e.dedup_key = $3::text || ':' || s.sub_id || ':' || to_char(s.end_date, 'YYYY-MM-DD')
+          )
+    `, today, today.AddDate(0, 0, -expiredLookback), types.EventSubscriptionExpired)
+	if err != nil {
+		logger.Logger.Errorw("Failed to find expired subscriptions",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to find expired subscriptions: %w", err)
+	}
+
+	type expired struct {
+		id      uuid.UUID
+		endDate time.Time
+	}
+	var subs []expired
+	for rows.Next() {
+		var sub expired
+		if err := rows.Scan(&sub.id, &sub.endDate); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error after scanning rows: %w", err)
+	}
+
+	recorded := 0
+	for _, expired := range subs {
+		sub, err := r.Get(expired.id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return recorded, err
+		}
+		dedupKey := fmt.Sprintf("%s:%s:%s", types.EventSubscriptionExpired, expired.id, expired.endDate.Format(dates.DayLayout))
+		ok, err := recordEvent(r.db, types.EventSubscriptionExpired, expired.id, sub, dedupKey)
+		if err != nil {
+			return recorded, err
+		}
+		if ok {
+			recorded++
+		}
+	}
+	return recorded, nil
+}
+
+// ClaimOutboxEvents returns up to limit unpublished events that are due,
+// oldest first, and postpones them by lease, so that other workers leave them
+// alone while they are being published. An event is only claimed once every
+// earlier event of its subscription is published, which keeps the events of a
+// subscription in order.
+func (r *PostgresRepository) ClaimOutboxEvents(limit int, lease time.Duration) ([]OutboxEvent, error) {
+	rows, err := r.db.Query(`
+        WITH claimed AS (
+            UPDATE outbox_events o
+            SET next_attempt_at = NOW() + make_interval(secs => $2)
+            WHERE o.event_id IN (
+                SELECT e.event_id
+                FROM outbox_events e
+                WHERE e.published_at IS NULL AND e.next_attempt_at <= NOW()
+                  AND NOT EXISTS (
+                      SELECT 1 FROM outbox_events p
+                      WHERE p.sub_id = e.sub_id AND p.published_at IS NULL
+                        AND (p.created_at, p.event_id) < (e.created_at, e.event_id)
+                  )
+                ORDER BY e.created_at, e.event_id
+                LIMIT $1
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING o.event_id, o.payload, o.attempts, o.created_at
+        )
+        SELECT event_id, payload, attempts
+        FROM claimed
+        ORDER BY created_at, event_id
+    `, limit, lease.Seconds())
+	if err != nil {
+		logger.Logger.Errorw("Failed to claim outbox events",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var (
+			event   OutboxEvent
+			payload []byte
+		)
+		if err := rows.Scan(&event.ID, &payload, &event.Attempts); err != nil {
+			logger.Logger.Errorw("Failed to scan outbox event",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		if err := json.Unmarshal(payload, &event.Event); err != nil {
+			logger.Logger.Errorw("Failed to decode outbox event",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to decode outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return events, nil
+}
+
+// RecordOutboxAttempt stores the outcome of publishing an event: published or
+// scheduled for another attempt.
+func (r *PostgresRepository) RecordOutboxAttempt(id uuid.UUID, attempt OutboxAttempt) error {
+	var err error
+	if attempt.Published {
+		_, err = r.db.Exec(`
+            UPDATE outbox_events
+            SET published_at = NOW(), last_error = NULL
+            WHERE event_id = $1
+        `, id)
+	} else {
+		_, err = r.db.Exec(`
+            UPDATE outbox_events
+            SET attempts = attempts + 1,
+                last_error = $2,
+                next_attempt_at = NOW() + make_interval(secs => $3)
+            WHERE event_id = $1
+        `, id, attempt.Error, attempt.RetryIn.Seconds())
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to record outbox attempt",
+			"error", err,
+			"eventID", id,
+		)
+		return fmt.Errorf("failed to record outbox attempt: %w", err)
+	}
+	return nil
+}
+
+// PurgeOutbox deletes the events published before the given time and returns
+// how many were deleted.
+func (r *PostgresRepository) PurgeOutbox(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM outbox_events WHERE published_at < $1`, before)
+	if err != nil {
+		logger.Logger.Errorw("Failed to purge outbox",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected, nil
+}
@@ -1,14 +1,19 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
 	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
 	"github.com/ItserX/rest/internal/types"
 )
 
@@ -16,7 +21,37 @@ type PostgresRepository struct {
 	db *sql.DB
 }
 
-var ErrNotFound = errors.New("subscription not found")
+var (
+	ErrNotFound        = errors.New("subscription not found")
+	ErrVersionMismatch = errors.New("subscription version mismatch")
+)
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx, so write helpers can run
+// standalone or as part of a transaction.
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// withContext runs the queries of helpers that take a dbtx with ctx, so that
+// they stop when the request that needs them is cancelled.
+type withContext struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+func (w withContext) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return w.db.ExecContext(w.ctx, query, args...)
+}
+
+func (w withContext) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return w.db.QueryContext(w.ctx, query, args...)
+}
+
+func (w withContext) QueryRow(query string, args ...interface{}) *sql.Row {
+	return w.db.QueryRowContext(w.ctx, query, args...)
+}
 
 func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{
@@ -24,33 +59,79 @@ func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 	}
 }
 
+// Create, Update and Delete record their event to the outbox in the same
+// transaction as the change.
 func (r *PostgresRepository) Create(sub types.Subscription) (uuid.UUID, error) {
-	startDate, err := time.Parse("01-2006", sub.StartDate)
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	subID, err := r.insertSubscription(tx, sub)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", subID,
+		)
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return subID, nil
+}
+
+func (r *PostgresRepository) insertSubscription(q dbtx, sub types.Subscription) (uuid.UUID, error) {
+	startDate, err := dates.ParseStart(sub.StartDate)
 	if err != nil {
 		logger.Logger.Errorw("Invalid start_date format",
 			"error", err,
 			"start_date", sub.StartDate,
 		)
-		return uuid.Nil, fmt.Errorf("invalid start_date format, expected MM-YYYY: %w", err)
+		return uuid.Nil, fmt.Errorf("invalid start_date format: %w", err)
 	}
 
 	var endDate *time.Time
 	if sub.EndDate != "" {
-		parsedEndDate, err := time.Parse("01-2006", sub.EndDate)
+		parsedEndDate, err := dates.ParseEnd(sub.EndDate)
 		if err != nil {
 			logger.Logger.Errorw("Invalid end_date format",
 				"error", err,
 				"end_date", sub.EndDate,
 			)
-			return uuid.Nil, fmt.Errorf("invalid end_date format, expected MM-YYYY: %w", err)
+			return uuid.Nil, fmt.Errorf("invalid end_date format: %w", err)
 		}
 		endDate = &parsedEndDate
 	}
 
+	trialEnd, err := parseTrialEnd(sub.TrialEnd)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	price, err := parsePrice(sub)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := r.checkUser(q, sub.UserID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := r.resolveService(q, &sub); err != nil {
+		return uuid.Nil, err
+	}
+
 	query := `
-        INSERT INTO subscriptions (sub_id, user_id, service_name, price, start_date, end_date)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO subscriptions (sub_id, user_id, service_name, service_id, price_minor, currency, billing_interval, billing_interval_count, start_date, end_date, trial_end, category, tags)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
     `
+	interval, count := billingCycle(sub)
 
 	subID := uuid.New()
 	logger.Logger.Debugw("Creating new subscription",
@@ -59,14 +140,21 @@ func (r *PostgresRepository) Create(sub types.Subscription) (uuid.UUID, error) {
 		"serviceName", sub.ServiceName,
 	)
 
-	_, err = r.db.Exec(
+	_, err = q.Exec(
 		query,
 		subID,
 		sub.UserID,
 		sub.ServiceName,
-		sub.Price,
+		sub.ServiceID,
+		price.Amount,
+		price.Currency,
+		interval,
+		count,
 		startDate,
 		endDate,
+		trialEnd,
+		nullString(normalizeLabel(sub.Category)),
+		pq.Array(normalizeTags(sub.Tags)),
 	)
 
 	if err != nil {
@@ -77,6 +165,10 @@ func (r *PostgresRepository) Create(sub types.Subscription) (uuid.UUID, error) {
 		return uuid.Nil, fmt.Errorf("failed to create subscription: %w", err)
 	}
 
+	if err := r.recordChange(q, types.EventSubscriptionCreated, subID); err != nil {
+		return uuid.Nil, err
+	}
+
 	logger.Logger.Infow("Successfully created subscription",
 		"subscriptionID", subID,
 	)
@@ -84,8 +176,12 @@ func (r *PostgresRepository) Create(sub types.Subscription) (uuid.UUID, error) {
 }
 
 func (r *PostgresRepository) Get(id uuid.UUID) (*types.Subscription, error) {
+	return r.getSubscription(r.db, id)
+}
+
+func (r *PostgresRepository) getSubscription(q dbtx, id uuid.UUID) (*types.Subscription, error) {
 	query := `
-        SELECT sub_id, user_id, service_name, price, start_date, end_date
+        SELECT sub_id, user_id, service_name, service_id, ` + priceSQL + `, currency, billing_interval, billing_interval_count, start_date, end_date, trial_end, category, ` + categorySQL + `, tags, status, version
         FROM subscriptions
         WHERE sub_id = $1
     `
@@ -98,18 +194,38 @@ func (r *PostgresRepository) Get(id uuid.UUID) (*types.Subscription, error) {
 		dbSubID       uuid.UUID
 		dbUserID      uuid.UUID
 		dbServiceName string
-		dbPrice       int
+		dbServiceID   uuid.NullUUID
+		dbPrice       int64
+		dbCurrency    string
+		dbInterval    string
+		dbCount       int
 		dbStartDate   time.Time
 		dbEndDate     sql.NullTime
+		dbTrialEnd    sql.NullTime
+		dbCategory    sql.NullString
+		dbEffective   sql.NullString
+		dbTags        pq.StringArray
+		dbStatus      string
+		dbVersion     int
 	)
 
-	err := r.db.QueryRow(query, id).Scan(
+	err := q.QueryRow(query, id).Scan(
 		&dbSubID,
 		&dbUserID,
 		&dbServiceName,
+		&dbServiceID,
 		&dbPrice,
+		&dbCurrency,
+		&dbInterval,
+		&dbCount,
 		&dbStartDate,
 		&dbEndDate,
+		&dbTrialEnd,
+		&dbCategory,
+		&dbEffective,
+		&dbTags,
+		&dbStatus,
+		&dbVersion,
 	)
 
 	if err != nil {
@@ -127,14 +243,31 @@ func (r *PostgresRepository) Get(id uuid.UUID) (*types.Subscription, error) {
 	}
 
 	sub := &types.Subscription{
-		ServiceName: dbServiceName,
-		Price:       dbPrice,
-		UserID:      dbUserID,
-		StartDate:   dbStartDate.Format("01-2006"),
+		ServiceName:          dbServiceName,
+		BillingInterval:      dbInterval,
+		BillingIntervalCount: dbCount,
+		UserID:               dbUserID,
+		StartDate:            dates.FormatStart(dbStartDate),
+		Category:             dbCategory.String,
+		EffectiveCategory:    dbEffective.String,
+		Tags:                 dbTags,
+		Status:               effectiveStatus(dbStatus, dbEndDate, time.Now()),
+		Version:              dbVersion,
 	}
+	sub.SetMoney(money.New(dbPrice, dbCurrency))
 
 	if dbEndDate.Valid {
-		sub.EndDate = dbEndDate.Time.Format("01-2006")
+		sub.EndDate = dates.FormatEnd(dbEndDate.Time)
+	}
+	if dbTrialEnd.Valid {
+		sub.TrialEnd = dates.FormatEnd(dbTrialEnd.Time)
+	}
+	if dbServiceID.Valid {
+		sub.ServiceID = &dbServiceID.UUID
+	}
+
+	if err := r.attachDetails(q, []uuid.UUID{id}, []*types.Subscription{sub}); err != nil {
+		return nil, err
 	}
 
 	logger.Logger.Debugw("Successfully retrieved subscription",
@@ -143,104 +276,221 @@ func (r *PostgresRepository) Get(id uuid.UUID) (*types.Subscription, error) {
 	return sub, nil
 }
 
-func (r *PostgresRepository) Update(id uuid.UUID, sub types.Subscription) error {
-	startDate, err := time.Parse("01-2006", sub.StartDate)
+// attachDetails fills in the pauses, discounts and sharing of subs, whose IDs
+// are given in the same order.
+func (r *PostgresRepository) attachDetails(q dbtx, ids []uuid.UUID, subs []*types.Subscription) error {
+	pauses, err := r.loadPauses(q, ids)
+	if err != nil {
+		return err
+	}
+	discounts, err := r.loadDiscounts(q, ids)
+	if err != nil {
+		return err
+	}
+	shared, err := r.loadSharing(q, ids)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		sub := subs[i]
+		for _, pause := range pauses[id] {
+			p := types.Pause{From: pause.From.Format(dates.MonthLayout)}
+			if pause.To != nil {
+				p.To = pause.To.Format(dates.MonthLayout)
+			}
+			sub.Pauses = append(sub.Pauses, p)
+		}
+		for _, discount := range discounts[id] {
+			sub.Discounts = append(sub.Discounts, discount.view(sub.Currency))
+		}
+		if s, ok := shared[id]; ok {
+			sharing := s.view(sub.Currency)
+			sub.Sharing = &sharing
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRepository) Update(id uuid.UUID, sub types.Subscription, ifMatch []int64) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	version, err := r.updateSubscription(tx, id, sub, ifMatch)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return version, nil
+}
+
+func (r *PostgresRepository) updateSubscription(q dbtx, id uuid.UUID, sub types.Subscription, ifMatch []int64) (int, error) {
+	startDate, err := dates.ParseStart(sub.StartDate)
 	if err != nil {
 		logger.Logger.Errorw("Invalid start_date format",
 			"error", err,
 			"start_date", sub.StartDate,
 		)
-		return fmt.Errorf("invalid start_date format, expected MM-YYYY: %w", err)
+		return 0, fmt.Errorf("invalid start_date format: %w", err)
 	}
 
 	var endDate *time.Time
 	if sub.EndDate != "" {
-		parsedEndDate, err := time.Parse("01-2006", sub.EndDate)
+		parsedEndDate, err := dates.ParseEnd(sub.EndDate)
 		if err != nil {
 			logger.Logger.Errorw("Invalid end_date format",
 				"error", err,
 				"end_date", sub.EndDate,
 			)
-			return fmt.Errorf("invalid end_date format, expected MM-YYYY: %w", err)
+			return 0, fmt.Errorf("invalid end_date format: %w", err)
 		}
 		endDate = &parsedEndDate
 	}
 
+	trialEnd, err := parseTrialEnd(sub.TrialEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	price, err := parsePrice(sub)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.resolveService(q, &sub); err != nil {
+		return 0, err
+	}
+
+	initialPrice, err := updatedPrice(q, id, price, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkEndDate(q, id, endDate); err != nil {
+		return 0, err
+	}
+
 	query := `
         UPDATE subscriptions
         SET 
             service_name = $1,
-            price = $2,
-            start_date = $3,
-            end_date = $4
-        WHERE sub_id = $5
+            service_id = $2,
+            price_minor = $3,
+            currency = $4,
+            billing_interval = $5,
+            billing_interval_count = $6,
+            start_date = $7,
+            end_date = $8,
+            trial_end = $9,
+            category = $10,
+            tags = $11,
+            version = version + 1
+        WHERE sub_id = $12 AND ($13::int[] IS NULL OR version = ANY($13))
+        RETURNING version
     `
+	interval, count := billingCycle(sub)
 
 	logger.Logger.Debugw("Updating subscription",
 		"subscriptionID", id,
 		"updateData", sub,
+		"ifMatch", ifMatch,
 	)
 
-	var result sql.Result
-	if endDate != nil {
-		result, err = r.db.Exec(
-			query,
-			sub.ServiceName,
-			sub.Price,
-			startDate,
-			endDate,
-			id,
-		)
-	} else {
-		result, err = r.db.Exec(
-			query,
-			sub.ServiceName,
-			sub.Price,
-			startDate,
-			nil,
-			id,
-		)
+	var version int
+	err = q.QueryRow(
+		query,
+		sub.ServiceName,
+		sub.ServiceID,
+		initialPrice,
+		price.Currency,
+		interval,
+		count,
+		startDate,
+		endDate,
+		trialEnd,
+		nullString(normalizeLabel(sub.Category)),
+		pq.Array(normalizeTags(sub.Tags)),
+		id,
+		pq.Array(ifMatch),
+	).Scan(&version)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, r.preconditionError(q, id)
 	}
-
 	if err != nil {
 		logger.Logger.Errorw("Failed to update subscription",
 			"error", err,
 			"subscriptionID", id,
 		)
-		return fmt.Errorf("failed to update subscription: %w", err)
+		return 0, fmt.Errorf("failed to update subscription: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if err := r.recordChange(q, types.EventSubscriptionUpdated, id); err != nil {
+		return 0, err
+	}
+
+	logger.Logger.Infow("Successfully updated subscription",
+		"subscriptionID", id,
+		"version", version,
+	)
+	return version, nil
+}
+
+func (r *PostgresRepository) Delete(id uuid.UUID, ifMatch []int64) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		logger.Logger.Errorw("Failed to check rows affected",
+		logger.Logger.Errorw("Failed to begin transaction",
 			"error", err,
-			"subscriptionID", id,
 		)
-		return fmt.Errorf("failed to check rows affected: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	if rowsAffected == 0 {
-		logger.Logger.Warnw("Subscription not found for update",
+	if err := r.deleteSubscription(tx, id, ifMatch); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
 			"subscriptionID", id,
 		)
-		return ErrNotFound
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-
-	logger.Logger.Infow("Successfully updated subscription",
-		"subscriptionID", id,
-		"rowsAffected", rowsAffected,
-	)
 	return nil
 }
 
-func (r *PostgresRepository) Delete(id uuid.UUID) error {
-	query := `DELETE FROM subscriptions WHERE sub_id = $1`
+func (r *PostgresRepository) deleteSubscription(q dbtx, id uuid.UUID, ifMatch []int64) error {
+	// The deleted event carries the last state of the subscription.
+	before, err := r.getSubscription(q, id)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        DELETE FROM subscriptions
+        WHERE sub_id = $1 AND ($2::int[] IS NULL OR version = ANY($2))
+    `
 
 	logger.Logger.Debugw("Deleting subscription",
 		"subscriptionID", id,
+		"ifMatch", ifMatch,
 	)
 
-	result, err := r.db.Exec(query, id)
+	result, err := q.Exec(query, id, pq.Array(ifMatch))
 	if err != nil {
 		logger.Logger.Errorw("Failed to delete subscription",
 			"error", err,
@@ -259,10 +509,11 @@ func (r *PostgresRepository) Delete(id uuid.UUID) error {
 	}
 
 	if rowsAffected == 0 {
-		logger.Logger.Warnw("Subscription not found for deletion",
-			"subscriptionID", id,
-		)
-		return ErrNotFound
+		return r.preconditionError(q, id)
+	}
+
+	if _, err := recordEvent(q, types.EventSubscriptionDeleted, id, before, ""); err != nil {
+		return err
 	}
 
 	logger.Logger.Infow("Successfully deleted subscription",
@@ -272,57 +523,51 @@ func (r *PostgresRepository) Delete(id uuid.UUID) error {
 	return nil
 }
 
-func (r *PostgresRepository) GetTotalCost(userID uuid.UUID, serviceName, periodStart, periodEnd string) (int, error) {
-	startTime, err := time.Parse("01-2006", periodStart)
+// preconditionError explains why a conditional write touched no rows: either
+// the subscription is gone or its version no longer matches If-Match.
+func (r *PostgresRepository) preconditionError(q dbtx, id uuid.UUID) error {
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM subscriptions WHERE sub_id = $1)`, id).Scan(&exists)
 	if err != nil {
-		logger.Logger.Errorw("Invalid period_start format",
+		logger.Logger.Errorw("Failed to check subscription existence",
 			"error", err,
-			"period_start", periodStart,
+			"subscriptionID", id,
 		)
-		return 0, fmt.Errorf("invalid period_start format: %w", err)
+		return fmt.Errorf("failed to check subscription existence: %w", err)
 	}
 
-	endTime, err := time.Parse("01-2006", periodEnd)
-	if err != nil {
-		logger.Logger.Errorw("Invalid period_end format",
-			"error", err,
-			"period_end", periodEnd,
+	if !exists {
+		logger.Logger.Warnw("Subscription not found",
+			"subscriptionID", id,
 		)
-		return 0, fmt.Errorf("invalid period_end format: %w", err)
-	}
-
-	query := `
-        SELECT COALESCE(SUM(price), 0)
-        FROM subscriptions
-        WHERE 
-            start_date <= $1 AND 
-            (end_date >= $2 OR end_date IS NULL)
-    `
-	args := []interface{}{endTime, startTime}
-
-	if userID != uuid.Nil {
-		query += " AND user_id = $3"
-		args = append(args, userID)
-	}
-	if serviceName != "" {
-		query += " AND service_name = $4"
-		args = append(args, serviceName)
+		return ErrNotFound
 	}
 
-	logger.Logger.Debugw("Calculating total cost",
-		"userID", userID,
-		"serviceName", serviceName,
-		"periodStart", periodStart,
-		"periodEnd", periodEnd,
+	logger.Logger.Warnw("Subscription version mismatch",
+		"subscriptionID", id,
 	)
+	return ErrVersionMismatch
+}
 
-	var total int
-	err = r.db.QueryRow(query, args...).Scan(&total)
+// GetTotalCost sums the rounded costs of CostBreakdown, so the total always
+// matches its breakdown.
+func (r *PostgresRepository) GetTotalCost(q CostQuery) (money.Money, error) {
+	total := money.New(0, q.currency())
+	items, err := r.CostBreakdown(q)
 	if err != nil {
 		logger.Logger.Errorw("Failed to calculate total cost",
 			"error", err,
 		)
-		return 0, fmt.Errorf("failed to calculate total cost: %w", err)
+		return total, fmt.Errorf("failed to calculate total cost: %w", err)
+	}
+
+	for _, item := range items {
+		if total, err = total.Add(item.Cost); err != nil {
+			logger.Logger.Errorw("Failed to add up total cost",
+				"error", err,
+			)
+			return money.Money{}, err
+		}
 	}
 
 	logger.Logger.Infow("Successfully calculated total cost",
@@ -331,72 +576,217 @@ func (r *PostgresRepository) GetTotalCost(userID uuid.UUID, serviceName, periodS
 	return total, nil
 }
 
-func (r *PostgresRepository) List() ([]types.Subscription, error) {
+// streamBatchSize is how many subscriptions Stream loads details for at once.
+const streamBatchSize = 100
+
+func (r *PostgresRepository) List(filter SubscriptionFilter) ([]types.Subscription, error) {
+	var subscriptions []types.Subscription
+	err := r.Stream(context.Background(), filter, func(sub types.Subscription) error {
+		subscriptions = append(subscriptions, sub)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Infow("Successfully listed all subscriptions",
+		"count", len(subscriptions),
+	)
+	return subscriptions, nil
+}
+
+// Stream calls fn for every subscription matching filter, a batch of
+// streamBatchSize rows at a time, so callers can write results out without
+// holding the whole table in memory. Subscriptions come with their pauses,
+// discounts and sharing, like Get returns them. Iteration stops at the first
+// error returned by fn or when ctx is cancelled, which also cancels the
+// queries for the details of a batch.
+func (r *PostgresRepository) Stream(ctx context.Context, filter SubscriptionFilter, fn func(types.Subscription) error) error {
 	query := `
-        SELECT sub_id, user_id, service_name, price, start_date, end_date
+        SELECT sub_id, user_id, service_name, service_id, ` + priceSQL + `, currency, billing_interval, billing_interval_count, start_date, end_date, trial_end, category, ` + categorySQL + `, tags, status
         FROM subscriptions
-        ORDER BY start_date DESC
+        WHERE TRUE
     `
+	query, args := filter.where(query, nil)
+	query += " ORDER BY start_date DESC"
 
-	logger.Logger.Debugw("Listing all subscriptions")
+	logger.Logger.Debugw("Listing subscriptions",
+		"userID", filter.UserID,
+		"serviceName", filter.ServiceName,
+	)
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		logger.Logger.Errorw("Failed to list subscriptions",
 			"error", err,
 		)
-		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+		return fmt.Errorf("failed to list subscriptions: %w", err)
 	}
 	defer rows.Close()
 
-	var subscriptions []types.Subscription
+	var (
+		ids   []uuid.UUID
+		batch []*types.Subscription
+	)
+	flush := func() error {
+		if err := r.attachDetails(withContext{ctx: ctx, db: r.db}, ids, batch); err != nil {
+			return err
+		}
+		for _, sub := range batch {
+			if err := fn(*sub); err != nil {
+				return err
+			}
+		}
+		ids, batch = ids[:0], batch[:0]
+		return nil
+	}
+
+	now := time.Now()
 	for rows.Next() {
 		var (
 			dbSubID       uuid.UUID
 			dbUserID      uuid.UUID
 			dbServiceName string
-			dbPrice       int
+			dbServiceID   uuid.NullUUID
+			dbPrice       int64
+			dbCurrency    string
+			dbInterval    string
+			dbCount       int
 			dbStartDate   time.Time
 			dbEndDate     sql.NullTime
+			dbTrialEnd    sql.NullTime
+			dbCategory    sql.NullString
+			dbEffective   sql.NullString
+			dbTags        pq.StringArray
+			dbStatus      string
 		)
 
 		if err := rows.Scan(
 			&dbSubID,
 			&dbUserID,
 			&dbServiceName,
+			&dbServiceID,
 			&dbPrice,
+			&dbCurrency,
+			&dbInterval,
+			&dbCount,
 			&dbStartDate,
 			&dbEndDate,
+			&dbTrialEnd,
+			&dbCategory,
+			&dbEffective,
+			&dbTags,
+			&dbStatus,
 		); err != nil {
 			logger.Logger.Errorw("Failed to scan subscription row",
 				"error", err,
 			)
-			return nil, fmt.Errorf("failed to scan subscription row: %w", err)
+			return fmt.Errorf("failed to scan subscription row: %w", err)
 		}
 
-		sub := types.Subscription{
-			ServiceName: dbServiceName,
-			Price:       dbPrice,
-			UserID:      dbUserID,
-			StartDate:   dbStartDate.Format("01-2006"),
+		sub := &types.Subscription{
+			ServiceName:          dbServiceName,
+			BillingInterval:      dbInterval,
+			BillingIntervalCount: dbCount,
+			UserID:               dbUserID,
+			StartDate:            dates.FormatStart(dbStartDate),
+			Category:             dbCategory.String,
+			EffectiveCategory:    dbEffective.String,
+			Tags:                 dbTags,
+			Status:               effectiveStatus(dbStatus, dbEndDate, now),
 		}
+		sub.SetMoney(money.New(dbPrice, dbCurrency))
 
 		if dbEndDate.Valid {
-			sub.EndDate = dbEndDate.Time.Format("01-2006")
+			sub.EndDate = dates.FormatEnd(dbEndDate.Time)
+		}
+		if dbTrialEnd.Valid {
+			sub.TrialEnd = dates.FormatEnd(dbTrialEnd.Time)
+		}
+		if dbServiceID.Valid {
+			sub.ServiceID = &dbServiceID.UUID
 		}
 
-		subscriptions = append(subscriptions, sub)
+		ids = append(ids, dbSubID)
+		batch = append(batch, sub)
+		if len(batch) == streamBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
 	}
 
 	if err := rows.Err(); err != nil {
 		logger.Logger.Errorw("Error after scanning rows",
 			"error", err,
 		)
-		return nil, fmt.Errorf("error after scanning rows: %w", err)
+		return fmt.Errorf("error after scanning rows: %w", err)
 	}
 
-	logger.Logger.Infow("Successfully listed all subscriptions",
-		"count", len(subscriptions),
-	)
-	return subscriptions, nil
+	return flush()
+}
+
+func parsePeriod(periodStart, periodEnd string) (time.Time, time.Time, error) {
+	startTime, err := dates.ParseStart(periodStart)
+	if err != nil {
+		logger.Logger.Errorw("Invalid period_start format",
+			"error", err,
+			"period_start", periodStart,
+		)
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period_start format: %w", err)
+	}
+
+	endTime, err := dates.ParseEnd(periodEnd)
+	if err != nil {
+		logger.Logger.Errorw("Invalid period_end format",
+			"error", err,
+			"period_end", periodEnd,
+		)
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period_end format: %w", err)
+	}
+
+	return startTime, endTime, nil
+}
+
+// parseTrialEnd parses the optional last day of a trial.
+func parseTrialEnd(trialEnd string) (*time.Time, error) {
+	if trialEnd == "" {
+		return nil, nil
+	}
+	parsed, err := dates.ParseEnd(trialEnd)
+	if err != nil {
+		logger.Logger.Errorw("Invalid trial_end format",
+			"error", err,
+			"trial_end", trialEnd,
+		)
+		return nil, fmt.Errorf("invalid trial_end format: %w", err)
+	}
+	return &parsed, nil
+}
+
+// parsePrice returns the subscription's price in minor units.
+func parsePrice(sub types.Subscription) (money.Money, error) {
+	price, err := sub.Money()
+	if err != nil {
+		logger.Logger.Errorw("Invalid price",
+			"error", err,
+			"price", sub.Price,
+			"currency", sub.Currency,
+		)
+		return money.Money{}, fmt.Errorf("invalid price: %w", err)
+	}
+	return price, nil
+}
+
+// billingCycle returns the subscription's billing interval and count with the
+// monthly defaults applied.
+func billingCycle(sub types.Subscription) (string, int) {
+	interval, count := sub.BillingInterval, sub.BillingIntervalCount
+	if interval == "" {
+		interval = billing.IntervalMonth
+	}
+	if count <= 0 {
+		count = 1
+	}
+	return interval, count
 }
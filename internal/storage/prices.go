@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var (
+	ErrCurrencyMismatch    = errors.New("price currency does not match subscription currency")
+	ErrPriceChangeNotFound = errors.New("price change not found")
+	ErrCurrencyChange      = errors.New("currency of a subscription with amounts in it cannot change")
+)
+
+// priceSQL is the price of a subscription in the current month: the latest
+// price change that has taken effect or, without one, the initial price.
+const priceSQL = `COALESCE((
+    SELECT p.price_minor FROM subscription_prices p
+    WHERE p.sub_id = subscriptions.sub_id AND p.effective_month <= date_trunc('month', NOW())
+    ORDER BY p.effective_month DESC
+    LIMIT 1
+), subscriptions.price_minor)`
+
+// updatedPrice returns the initial price to store when an update sets the
+// subscription's price to price. Billed months keep their prices: once the
+// subscription has started, a new price is recorded as a price change from
+// the current month and the initial price stays. The currency cannot change
+// once months were billed in it or prices, amount discounts or fixed shares
+// are recorded in it.
+func updatedPrice(q dbtx, id uuid.UUID, price money.Money, now time.Time) (int64, error) {
+	var (
+		initial   int64
+		current   int64
+		currency  string
+		startDate time.Time
+		amounts   bool
+	)
+	err := q.QueryRow(`
+        SELECT price_minor, `+priceSQL+`, currency, start_date,
+            EXISTS (SELECT 1 FROM subscription_prices p WHERE p.sub_id = subscriptions.sub_id)
+            OR EXISTS (SELECT 1 FROM subscription_discounts d WHERE d.sub_id = subscriptions.sub_id AND d.amount_minor IS NOT NULL)
+            OR (split_rule = 'fixed' AND EXISTS (SELECT 1 FROM subscription_members m WHERE m.sub_id = subscriptions.sub_id))
+        FROM subscriptions
+        WHERE sub_id = $1
+        FOR UPDATE
+    `, id).Scan(&initial, &current, &currency, &startDate, &amounts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription price",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return 0, fmt.Errorf("failed to get subscription price: %w", err)
+	}
+
+	month := dates.MonthStart(now)
+	started := dates.MonthStart(startDate).Before(month)
+	if price.Currency != currency {
+		if started || amounts {
+			return 0, fmt.Errorf("%w: subscription is billed in %s", ErrCurrencyChange, currency)
+		}
+		return price.Amount, nil
+	}
+	if !started {
+		return price.Amount, nil
+	}
+	if price.Amount == current {
+		return initial, nil
+	}
+
+	_, err = q.Exec(`
+        INSERT INTO subscription_prices (sub_id, effective_month, price_minor)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (sub_id, effective_month) DO UPDATE
+        SET price_minor = EXCLUDED.price_minor
+    `, id, month, price.Amount)
+	if err != nil {
+		logger.Logger.Errorw("Failed to store price change",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return 0, fmt.Errorf("failed to store price change: %w", err)
+	}
+
+	logger.Logger.Infow("Price change recorded from the current month",
+		"subscriptionID", id,
+		"month", month.Format(dates.MonthLayout),
+		"price", price.String(),
+	)
+	return initial, nil
+}
+
+// SchedulePrice makes price the subscription's price from month on, replacing
+// a change already recorded for that month. The month must fall after the
+// first month of the subscription and not after its end; Update changes the
+// price from the current month.
+func (r *PostgresRepository) SchedulePrice(id uuid.UUID, month string, price money.Money) error {
+	from, err := parseMonth(month)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Debugw("Scheduling price change",
+		"subscriptionID", id,
+		"month", from.Format(dates.MonthLayout),
+		"price", price.String(),
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		currency  string
+		startDate time.Time
+		endDate   sql.NullTime
+	)
+	err = tx.QueryRow(`
+        SELECT currency, start_date, end_date
+        FROM subscriptions
+        WHERE sub_id = $1
+        FOR UPDATE
+    `, id).Scan(&currency, &startDate, &endDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Subscription not found",
+			"subscriptionID", id,
+		)
+		return ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	if price.Currency != currency {
+		return fmt.Errorf("%w: subscription is billed in %s", ErrCurrencyMismatch, currency)
+	}
+	if !from.After(dates.MonthStart(startDate)) {
+		return fmt.Errorf("%w: price change must start after the first month of the subscription", ErrInvalidDate)
+	}
+	if endDate.Valid && from.After(endDate.Time) {
+		return fmt.Errorf("%w: price change starts after the subscription ends", ErrInvalidDate)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO subscription_prices (sub_id, effective_month, price_minor)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (sub_id, effective_month) DO UPDATE
+        SET price_minor = EXCLUDED.price_minor
+    `, id, from, price.Amount)
+	if err != nil {
+		logger.Logger.Errorw("Failed to store price change",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to store price change: %w", err)
+	}
+
+	if err := r.bumpVersion(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully scheduled price change",
+		"subscriptionID", id,
+		"month", from.Format(dates.MonthLayout),
+	)
+	return nil
+}
+
+// DeletePriceChange removes the price change starting in month.
+func (r *PostgresRepository) DeletePriceChange(id uuid.UUID, month string) error {
+	from, err := parseMonth(month)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Debugw("Deleting price change",
+		"subscriptionID", id,
+		"month", from.Format(dates.MonthLayout),
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM subscription_prices WHERE sub_id = $1 AND effective_month = $2`, id, from)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete price change",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to delete price change: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM subscriptions WHERE sub_id = $1)`, id).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check subscription: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrPriceChangeNotFound
+	}
+
+	if err := r.bumpVersion(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully deleted price change",
+		"subscriptionID", id,
+		"month", from.Format(dates.MonthLayout),
+	)
+	return nil
+}
+
+// PriceHistory returns the prices of the subscription from its first month on,
+// each with the range of months it applies to.
+func (r *PostgresRepository) PriceHistory(id uuid.UUID) ([]types.PricePeriod, error) {
+	var (
+		price     int64
+		currency  string
+		startDate time.Time
+	)
+	err := r.db.QueryRow(`
+        SELECT price_minor, currency, start_date
+        FROM subscriptions
+        WHERE sub_id = $1
+    `, id).Scan(&price, &currency, &startDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Subscription not found",
+			"subscriptionID", id,
+		)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get subscription",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	changes, err := r.loadPrices([]uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+
+	current := dates.MonthStart(time.Now())
+	history := []billing.PriceChange{{From: dates.MonthStart(startDate), Price: price}}
+	history = append(history, changes[id]...)
+
+	periods := make([]types.PricePeriod, 0, len(history))
+	for i, change := range history {
+		period := types.PricePeriod{
+			EffectiveFrom: change.From.Format(dates.MonthLayout),
+			Price:         money.New(change.Price, currency),
+			Scheduled:     change.From.After(current),
+		}
+		if i+1 < len(history) {
+			period.EffectiveTo = history[i+1].From.AddDate(0, -1, 0).Format(dates.MonthLayout)
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// loadPrices returns the price changes of the given subscriptions keyed by ID,
+// ordered by the month they take effect.
+func (r *PostgresRepository) loadPrices(ids []uuid.UUID) (map[uuid.UUID][]billing.PriceChange, error) {
+	prices := make(map[uuid.UUID][]billing.PriceChange)
+	if len(ids) == 0 {
+		return prices, nil
+	}
+
+	rows, err := r.db.Query(`
+        SELECT sub_id, effective_month, price_minor
+        FROM subscription_prices
+        WHERE sub_id = ANY($1)
+        ORDER BY effective_month
+    `, pq.Array(ids))
+	if err != nil {
+		logger.Logger.Errorw("Failed to load price changes",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to load price changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			subID  uuid.UUID
+			change billing.PriceChange
+		)
+		if err := rows.Scan(&subID, &change.From, &change.Price); err != nil {
+			logger.Logger.Errorw("Failed to scan price change",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		prices[subID] = append(prices[subID], change)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return prices, nil
+}
+
+// bumpVersion marks a change to what the subscription costs, such as its
+// prices, discounts or members: its ETag changes and the updated event is
+// recorded with tx.
+func (r *PostgresRepository) bumpVersion(tx *sql.Tx, id uuid.UUID) error {
+	_, err := tx.Exec(`UPDATE subscriptions SET version = version + 1 WHERE sub_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to update subscription version",
+			"error", err,
+			"subscriptionID", id,
+		)
+		return fmt.Errorf("failed to update subscription version: %w", err)
+	}
+	return r.recordChange(tx, types.EventSubscriptionUpdated, id)
+}
+
+func parseMonth(month string) (time.Time, error) {
+	parsed, err := dates.ParseStart(month)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+	}
+	return dates.MonthStart(parsed), nil
+}
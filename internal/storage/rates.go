@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/logger"
+)
+
+// rateScale is the number of decimal places exchange_rates.rate keeps.
+const rateScale = 6
+
+// UpsertRates stores exchange rates in one transaction, replacing rates that
+// already exist for the same currency and effective date.
+func (r *PostgresRepository) UpsertRates(rates []fx.Rate) (int, error) {
+	logger.Logger.Debugw("Loading exchange rates",
+		"count", len(rates),
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO exchange_rates (currency, effective_date, rate)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (currency, effective_date) DO UPDATE
+        SET rate = EXCLUDED.rate
+    `
+	for _, rate := range rates {
+		_, err := tx.Exec(query, rate.Currency, rate.Date, rate.Rate.FloatString(rateScale))
+		if err != nil {
+			logger.Logger.Errorw("Failed to store exchange rate",
+				"error", err,
+				"currency", rate.Currency,
+			)
+			return 0, fmt.Errorf("failed to store exchange rate: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully loaded exchange rates",
+		"count", len(rates),
+	)
+	return len(rates), nil
+}
+
+// ListRates returns the stored rates, optionally only those of one currency,
+// ordered by currency and effective date.
+func (r *PostgresRepository) ListRates(currency string) ([]fx.Rate, error) {
+	var currencies []string
+	if currency != "" {
+		currencies = []string{currency}
+	}
+	return r.loadRates(currencies)
+}
+
+// rateTable loads the rates of the given currencies for conversion.
+func (r *PostgresRepository) rateTable(currencies []string) (*fx.Table, error) {
+	if len(currencies) == 0 {
+		return fx.NewTable(nil), nil
+	}
+	rates, err := r.loadRates(currencies)
+	if err != nil {
+		return nil, err
+	}
+	return fx.NewTable(rates), nil
+}
+
+// loadRates returns the rates of the given currencies, or of all of them when
+// currencies is nil.
+func (r *PostgresRepository) loadRates(currencies []string) ([]fx.Rate, error) {
+	rows, err := r.db.Query(`
+        SELECT currency, effective_date, rate
+        FROM exchange_rates
+        WHERE $1::text[] IS NULL OR currency = ANY($1)
+        ORDER BY currency, effective_date
+    `, pq.Array(currencies))
+	if err != nil {
+		logger.Logger.Errorw("Failed to load exchange rates",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []fx.Rate
+	for rows.Next() {
+		var (
+			rate  fx.Rate
+			value string
+		)
+		if err := rows.Scan(&rate.Currency, &rate.Date, &value); err != nil {
+			logger.Logger.Errorw("Failed to scan exchange rate",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		var ok bool
+		if rate.Rate, ok = new(big.Rat).SetString(value); !ok {
+			return nil, fmt.Errorf("invalid exchange rate %q for %s", value, rate.Currency)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return rates, nil
+}
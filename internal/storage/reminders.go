@@ -0,0 +1,550 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// remindersLimit caps how many of a user's latest reminders are listed.
+const remindersLimit = 100
+
+// GetReminderPreferences returns the user's reminder settings, both kinds
+// enabled with the default window if they were never changed.
+func (r *PostgresRepository) GetReminderPreferences(userID uuid.UUID) (types.ReminderPreferences, error) {
+	if err := r.checkUser(r.db, userID); err != nil {
+		return types.ReminderPreferences{}, err
+	}
+
+	prefs := types.ReminderPreferences{Renewals: true, Expiries: true}
+	var daysBefore sql.NullInt64
+	err := r.db.QueryRow(`
+        SELECT renewals, expiries, days_before
+        FROM reminder_preferences
+        WHERE user_id = $1
+    `, userID).Scan(&prefs.Renewals, &prefs.Expiries, &daysBefore)
+	if errors.Is(err, sql.ErrNoRows) {
+		return prefs, nil
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get reminder preferences",
+			"error", err,
+			"userID", userID,
+		)
+		return prefs, fmt.Errorf("failed to get reminder preferences: %w", err)
+	}
+	prefs.DaysBefore = int(daysBefore.Int64)
+	return prefs, nil
+}
+
+func (r *PostgresRepository) SetReminderPreferences(userID uuid.UUID, prefs types.ReminderPreferences) error {
+	logger.Logger.Debugw("Setting reminder preferences",
+		"userID", userID,
+		"preferences", prefs,
+	)
+
+	var daysBefore sql.NullInt64
+	if prefs.DaysBefore > 0 {
+		daysBefore = sql.NullInt64{Int64: int64(prefs.DaysBefore), Valid: true}
+	}
+	_, err := r.db.Exec(`
+        INSERT INTO reminder_preferences (user_id, renewals, expiries, days_before)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id) DO UPDATE
+        SET renewals = EXCLUDED.renewals, expiries = EXCLUDED.expiries, days_before = EXCLUDED.days_before
+    `, userID, prefs.Renewals, prefs.Expiries, daysBefore)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		logger.Logger.Warnw("User not found",
+			"userID", userID,
+		)
+		return ErrUserNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to store reminder preferences",
+			"error", err,
+			"userID", userID,
+		)
+		return fmt.Errorf("failed to store reminder preferences: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully set reminder preferences",
+		"userID", userID,
+	)
+	return nil
+}
+
+// ListReminders returns the user's latest reminders, newest first.
+func (r *PostgresRepository) ListReminders(userID uuid.UUID) ([]types.Reminder, error) {
+	if err := r.checkUser(r.db, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+        SELECT m.reminder_id, m.user_id, m.sub_id, s.service_name, m.kind, m.due_date, m.price_minor, m.currency, m.created_at
+        FROM reminders m
+        JOIN subscriptions s ON s.sub_id = m.sub_id
+        WHERE m.user_id = $1
+        ORDER BY m.created_at DESC, m.due_date
+        LIMIT $2
+    `, userID, remindersLimit)
+	if err != nil {
+		logger.Logger.Errorw("Failed to list reminders",
+			"error", err,
+			"userID", userID,
+		)
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReminders(rows)
+}
+
+// scanReminders reads reminders selected as reminder_id, user_id, sub_id,
+// service_name, kind, due_date, price_minor, currency and created_at.
+func scanReminders(rows *sql.Rows) ([]types.Reminder, error) {
+	reminders := []types.Reminder{}
+	for rows.Next() {
+		var (
+			reminder  types.Reminder
+			id        uuid.UUID
+			subID     uuid.UUID
+			dueDate   time.Time
+			price     sql.NullInt64
+			currency  sql.NullString
+			createdAt time.Time
+		)
+		err := rows.Scan(&id, &reminder.UserID, &subID, &reminder.ServiceName, &reminder.Kind, &dueDate, &price, &currency, &createdAt)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan reminder",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+
+		reminder.ID = id.String()
+		reminder.SubID = subID.String()
+		reminder.Date = dueDate.Format(dates.DayLayout)
+		reminder.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		if price.Valid {
+			p := money.New(price.Int64, currency.String)
+			reminder.Price = &p
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return reminders, nil
+}
+
+// ClaimReminders returns the reminders whose users have not been notified yet
+// and that are not past their date, oldest first, and postpones them by
+// lease, so that other runs leave them alone while they are being sent. A
+// reminder left unmarked, for example by a crash or because no channel
+// delivered it, is returned again once its lease runs out.
+func (r *PostgresRepository) ClaimReminders(lease time.Duration, now time.Time) ([]types.Reminder, error) {
+	rows, err := r.db.Query(`
+        WITH claimed AS (
+            UPDATE reminders m
+            SET notify_after = NOW() + make_interval(secs => $1)
+            WHERE m.reminder_id IN (
+                SELECT reminder_id
+                FROM reminders
+                WHERE notified_at IS NULL AND notify_after <= NOW() AND due_date >= $2
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING m.reminder_id, m.user_id, m.sub_id, m.kind, m.due_date, m.price_minor, m.currency, m.created_at
+        )
+        SELECT c.reminder_id, c.user_id, c.sub_id, s.service_name, c.kind, c.due_date, c.price_minor, c.currency, c.created_at
+        FROM claimed c
+        JOIN subscriptions s ON s.sub_id = c.sub_id
+        ORDER BY c.created_at, c.due_date
+    `, lease.Seconds(), dates.Day(now))
+	if err != nil {
+		logger.Logger.Errorw("Failed to claim reminders",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReminders(rows)
+}
+
+// MarkReminderNotified records that the user of the reminder was notified,
+// so that it is not claimed again.
+func (r *PostgresRepository) MarkReminderNotified(reminder types.Reminder) error {
+	_, err := r.db.Exec(`UPDATE reminders SET notified_at = NOW() WHERE reminder_id = $1`, reminder.ID)
+	if err != nil {
+		logger.Logger.Errorw("Failed to mark reminder notified",
+			"error", err,
+			"reminderID", reminder.ID,
+		)
+		return fmt.Errorf("failed to mark reminder notified: %w", err)
+	}
+	return nil
+}
+
+// reminderCandidate is a subscription that may be due for a reminder along
+// with its owner's preferences.
+type reminderCandidate struct {
+	id          uuid.UUID
+	userID      uuid.UUID
+	serviceName string
+	status      string
+	currency    string
+	item        billing.Item
+	renewals    bool
+	expiries    bool
+	days        int
+}
+
+// CreateReminders records a reminder for the next paid billing date and the
+// end date of every subscription falling within the owner's window from now:
+// the number of days in their preferences, or window if they set none.
+// Reminders already recorded for the same subscription, kind and date are
+// skipped, so running it more than once a day is harmless. It returns the
+// reminders created by this run.
+func (r *PostgresRepository) CreateReminders(now time.Time, window int) ([]types.Reminder, error) {
+	today := dates.Day(now)
+	logger.Logger.Debugw("Creating reminders",
+		"today", today,
+		"window", window,
+	)
+
+	candidates, err := r.reminderCandidates(today, window)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.id
+	}
+	pauses, err := r.loadPauses(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := r.loadPrices(ids)
+	if err != nil {
+		return nil, err
+	}
+	discounts, err := r.loadDiscounts(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []types.Reminder
+	for _, candidate := range candidates {
+		item := candidate.item
+		item.Pauses = pauses[candidate.id]
+		item.PriceChanges = prices[candidate.id]
+		for _, d := range discounts[candidate.id] {
+			item.Discounts = append(item.Discounts, d.Discount)
+		}
+		until := today.AddDate(0, 0, candidate.days)
+
+		if candidate.renewals && candidate.status == types.StatusActive {
+			if charge, ok := nextRenewal(item, today, until); ok {
+				amount, err := money.Round(item.PriceOn(charge))
+				if err != nil {
+					return nil, err
+				}
+				price := money.New(amount, candidate.currency)
+				reminder, err := r.insertReminder(candidate, types.ReminderRenewal, charge, &price)
+				if err != nil {
+					return nil, err
+				}
+				if reminder != nil {
+					created = append(created, *reminder)
+				}
+			}
+		}
+
+		if candidate.expiries && item.End != nil && !item.End.Before(today) && !item.End.After(until) {
+			reminder, err := r.insertReminder(candidate, types.ReminderExpiry, dates.Day(*item.End), nil)
+			if err != nil {
+				return nil, err
+			}
+			if reminder != nil {
+				created = append(created, *reminder)
+			}
+		}
+	}
+
+	logger.Logger.Infow("Successfully created reminders",
+		"subscriptions", len(candidates),
+		"created", len(created),
+	)
+	return created, nil
+}
+
+// nextRenewal returns the first paid billing date of the item from today
+// through until; charges during a free trial are skipped.
+func nextRenewal(item billing.Item, today, until time.Time) (time.Time, bool) {
+	from := today
+	if item.TrialEnd != nil && !item.TrialEnd.Before(from) {
+		from = item.TrialEnd.AddDate(0, 0, 1)
+	}
+	return billing.NextCharge(item, from, until)
+}
+
+// NextRenewals returns the next paid billing date through until of every
+// active subscription the user owns or shares, priced like renewal reminders
+// and reduced to the user's share.
+func (r *PostgresRepository) NextRenewals(userID uuid.UUID, now, until time.Time) ([]types.Renewal, error) {
+	today := dates.Day(now)
+	logger.Logger.Debugw("Finding next renewals",
+		"userID", userID,
+		"until", until,
+	)
+
+	query, args := SubscriptionFilter{UserID: userID}.where(`
+        SELECT sub_id, user_id, service_name, price_minor, currency, billing_interval, billing_interval_count, start_date, end_date, trial_end
+        FROM subscriptions
+        WHERE status = 'active' AND (end_date IS NULL OR end_date >= $1)
+    `, []interface{}{today})
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		logger.Logger.Errorw("Failed to find renewals",
+			"error", err,
+			"userID", userID,
+		)
+		return nil, fmt.Errorf("failed to find renewals: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		ids        []uuid.UUID
+		owners     []uuid.UUID
+		names      []string
+		currencies []string
+		items      []billing.Item
+	)
+	for rows.Next() {
+		var (
+			id       uuid.UUID
+			owner    uuid.UUID
+			name     string
+			currency string
+			item     billing.Item
+			endDate  sql.NullTime
+			trialEnd sql.NullTime
+		)
+		err := rows.Scan(
+			&id,
+			&owner,
+			&name,
+			&item.Price,
+			&currency,
+			&item.Interval,
+			&item.IntervalCount,
+			&item.Start,
+			&endDate,
+			&trialEnd,
+		)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan renewal",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan renewal: %w", err)
+		}
+		if endDate.Valid {
+			item.End = &endDate.Time
+		}
+		if trialEnd.Valid {
+			item.TrialEnd = &trialEnd.Time
+		}
+		ids = append(ids, id)
+		owners = append(owners, owner)
+		names = append(names, name)
+		currencies = append(currencies, currency)
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+
+	pauses, err := r.loadPauses(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := r.loadPrices(ids)
+	if err != nil {
+		return nil, err
+	}
+	discounts, err := r.loadDiscounts(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := r.loadSharing(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var renewals []types.Renewal
+	for i, item := range items {
+		item.Pauses = pauses[ids[i]]
+		item.PriceChanges = prices[ids[i]]
+		for _, d := range discounts[ids[i]] {
+			item.Discounts = append(item.Discounts, d.Discount)
+		}
+		charge, ok := nextRenewal(item, today, until)
+		if !ok {
+			continue
+		}
+		price := item.PriceOn(charge)
+		if s, ok := shared[ids[i]]; ok {
+			price = s.split().Portion(price, s.payer(userID, owners[i]))
+		}
+		amount, err := money.Round(price)
+		if err != nil {
+			return nil, err
+		}
+		renewals = append(renewals, types.Renewal{
+			ServiceName: names[i],
+			Date:        charge.Format(dates.DayLayout),
+			Price:       money.New(amount, currencies[i]),
+		})
+	}
+
+	logger.Logger.Debugw("Successfully found next renewals",
+		"userID", userID,
+		"count", len(renewals),
+	)
+	return renewals, nil
+}
+
+// reminderCandidates returns the subscriptions that are not paused and have
+// not ended by today.
+func (r *PostgresRepository) reminderCandidates(today time.Time, window int) ([]reminderCandidate, error) {
+	rows, err := r.db.Query(`
+        SELECT s.sub_id, s.user_id, s.service_name, s.status, s.price_minor, s.currency, s.billing_interval, s.billing_interval_count,
+               s.start_date, s.end_date, s.trial_end,
+               COALESCE(p.renewals, TRUE), COALESCE(p.expiries, TRUE), COALESCE(p.days_before, $2)
+        FROM subscriptions s
+        LEFT JOIN reminder_preferences p ON p.user_id = s.user_id
+        WHERE s.status IN ('active', 'cancelled') AND (s.end_date IS NULL OR s.end_date >= $1)
+    `, today, window)
+	if err != nil {
+		logger.Logger.Errorw("Failed to find reminder candidates",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to find reminder candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []reminderCandidate
+	for rows.Next() {
+		var (
+			c        reminderCandidate
+			endDate  sql.NullTime
+			trialEnd sql.NullTime
+		)
+		err := rows.Scan(
+			&c.id,
+			&c.userID,
+			&c.serviceName,
+			&c.status,
+			&c.item.Price,
+			&c.currency,
+			&c.item.Interval,
+			&c.item.IntervalCount,
+			&c.item.Start,
+			&endDate,
+			&trialEnd,
+			&c.renewals,
+			&c.expiries,
+			&c.days,
+		)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan reminder candidate",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan reminder candidate: %w", err)
+		}
+		if endDate.Valid {
+			c.item.End = &endDate.Time
+		}
+		if trialEnd.Valid {
+			c.item.TrialEnd = &trialEnd.Time
+		}
+		candidates = append(candidates, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return candidates, nil
+}
+
+// insertReminder records a reminder and returns it, or nil if the same one
+// was recorded before.
+func (r *PostgresRepository) insertReminder(c reminderCandidate, kind string, day time.Time, price *money.Money) (*types.Reminder, error) {
+	var amount sql.NullInt64
+	var currency sql.NullString
+	if price != nil {
+		amount = sql.NullInt64{Int64: price.Amount, Valid: true}
+		currency = sql.NullString{String: price.Currency, Valid: true}
+	}
+
+	id := uuid.New()
+	var createdAt time.Time
+	err := r.db.QueryRow(`
+        INSERT INTO reminders (reminder_id, sub_id, user_id, kind, due_date, price_minor, currency)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (sub_id, kind, due_date) DO NOTHING
+        RETURNING created_at
+    `, id, c.id, c.userID, kind, day, amount, currency).Scan(&createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to store reminder",
+			"error", err,
+			"subscriptionID", c.id,
+			"kind", kind,
+		)
+		return nil, fmt.Errorf("failed to store reminder: %w", err)
+	}
+
+	logger.Logger.Infow("Reminder created",
+		"reminderID", id,
+		"subscriptionID", c.id,
+		"userID", c.userID,
+		"kind", kind,
+		"date", day.Format(dates.DayLayout),
+	)
+	return &types.Reminder{
+		ID:          id.String(),
+		UserID:      c.userID,
+		SubID:       c.id.String(),
+		ServiceName: c.serviceName,
+		Kind:        kind,
+		Date:        day.Format(dates.DayLayout),
+		Price:       price,
+		CreatedAt:   createdAt.UTC().Format(time.RFC3339),
+	}, nil
+}
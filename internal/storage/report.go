@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/dates"
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// CostQuery selects the subscriptions and the period a cost is calculated
+// for. PeriodStart and PeriodEnd are dates or MM-YYYY months, both inclusive;
+// View is one of the billing views and defaults to accrual. Prorate counts
+// partial months by day instead of in full. Costs are converted into Currency,
+// the base currency if empty, at the rate in effect at the start of each month.
+type CostQuery struct {
+	Filter      SubscriptionFilter
+	PeriodStart string
+	PeriodEnd   string
+	View        string
+	Prorate     bool
+	Currency    string
+}
+
+func (q CostQuery) currency() string {
+	if q.Currency == "" {
+		return fx.Base
+	}
+	return q.Currency
+}
+
+// CostBreakdown returns the subscriptions counted by GetTotalCost for the same
+// query together with the amount each of them contributes to the total.
+// Subscriptions that are paused for the whole period are left out. With a
+// user in the filter, shared subscriptions count only that user's share.
+func (r *PostgresRepository) CostBreakdown(q CostQuery) ([]types.CostItem, error) {
+	startTime, endTime, err := parsePeriod(q.PeriodStart, q.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT sub_id, user_id, service_name, price_minor, currency, billing_interval, billing_interval_count, start_date, end_date, trial_end, ` + categorySQL + `, tags
+        FROM subscriptions
+        WHERE 
+            start_date <= $1 AND 
+            (end_date >= $2 OR end_date IS NULL)
+    `
+	query, args := q.Filter.where(query, []interface{}{dates.MonthEnd(endTime), dates.MonthStart(startTime)})
+	query += " ORDER BY service_name, start_date"
+
+	logger.Logger.Debugw("Calculating cost breakdown",
+		"userID", q.Filter.UserID,
+		"serviceName", q.Filter.ServiceName,
+		"periodStart", q.PeriodStart,
+		"periodEnd", q.PeriodEnd,
+		"view", q.View,
+		"prorate", q.Prorate,
+	)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		logger.Logger.Errorw("Failed to calculate cost breakdown",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to calculate cost breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		items  []types.CostItem
+		billed []billing.Item
+		ids    []uuid.UUID
+	)
+	for rows.Next() {
+		var (
+			item        types.CostItem
+			dbSubID     uuid.UUID
+			dbStartDate time.Time
+			dbEndDate   sql.NullTime
+			dbTrialEnd  sql.NullTime
+			dbCategory  sql.NullString
+			dbTags      pq.StringArray
+		)
+		if err := rows.Scan(
+			&dbSubID,
+			&item.UserID,
+			&item.ServiceName,
+			&item.Price.Amount,
+			&item.Price.Currency,
+			&item.BillingInterval,
+			&item.BillingIntervalCount,
+			&dbStartDate,
+			&dbEndDate,
+			&dbTrialEnd,
+			&dbCategory,
+			&dbTags,
+		); err != nil {
+			logger.Logger.Errorw("Failed to scan cost row",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan cost row: %w", err)
+		}
+
+		bill := billing.Item{
+			Price:         item.Price.Amount,
+			Interval:      item.BillingInterval,
+			IntervalCount: item.BillingIntervalCount,
+			Start:         dbStartDate,
+		}
+		item.StartDate = dates.FormatStart(dbStartDate)
+		item.Category = dbCategory.String
+		item.Tags = dbTags
+		if dbEndDate.Valid {
+			item.EndDate = dates.FormatEnd(dbEndDate.Time)
+			bill.End = &dbEndDate.Time
+		}
+		if dbTrialEnd.Valid {
+			bill.TrialEnd = &dbTrialEnd.Time
+		}
+		items = append(items, item)
+		billed = append(billed, bill)
+		ids = append(ids, dbSubID)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+
+	pauses, err := r.loadPauses(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := r.loadPrices(ids)
+	if err != nil {
+		return nil, err
+	}
+	discounts, err := r.loadDiscounts(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := r.loadSharing(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	target := q.currency()
+	var currencies []string
+	for _, item := range items {
+		if item.Price.Currency != target && !slices.Contains(currencies, item.Price.Currency) {
+			currencies = append(currencies, item.Price.Currency)
+		}
+	}
+	if len(currencies) > 0 && target != fx.Base {
+		currencies = append(currencies, target)
+	}
+	rates, err := r.rateTable(currencies)
+	if err != nil {
+		return nil, err
+	}
+
+	charged := items[:0]
+	for i, item := range items {
+		billed[i].Pauses = pauses[ids[i]]
+		billed[i].PriceChanges = prices[ids[i]]
+		for _, d := range discounts[ids[i]] {
+			billed[i].Discounts = append(billed[i].Discounts, d.Discount)
+		}
+		opts := billing.Options{
+			View:    q.View,
+			Prorate: q.Prorate,
+			Factor: func(month time.Time) (*big.Rat, error) {
+				return rates.Factor(item.Price.Currency, target, month)
+			},
+		}
+		if s, ok := shared[ids[i]]; ok && q.Filter.UserID != uuid.Nil {
+			split, payer := s.split(), s.payer(q.Filter.UserID, item.UserID)
+			opts.Portion = func(price *big.Rat) *big.Rat {
+				return split.Portion(price, payer)
+			}
+		}
+		cost, err := billing.Calculate(billed[i], startTime, endTime, opts)
+		if errors.Is(err, fx.ErrNoRate) {
+			logger.Logger.Warnw("Exchange rate not found",
+				"error", err,
+				"subscriptionID", ids[i],
+			)
+			return nil, err
+		}
+		if err != nil {
+			logger.Logger.Errorw("Failed to calculate subscription cost",
+				"error", err,
+				"subscriptionID", ids[i],
+			)
+			return nil, fmt.Errorf("failed to calculate subscription cost: %w", err)
+		}
+		if cost.Months == 0 {
+			continue
+		}
+		item.Months = cost.Months
+		item.Charges = cost.Charges
+		item.Cost = money.New(cost.Amount, target)
+		charged = append(charged, item)
+	}
+
+	logger.Logger.Infow("Successfully calculated cost breakdown",
+		"count", len(charged),
+	)
+	return charged, nil
+}
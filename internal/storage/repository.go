@@ -1,16 +1,87 @@
 package storage
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/money"
 	"github.com/ItserX/rest/internal/types"
 )
 
 type PostRepository interface {
 	Create(sub types.Subscription) (uuid.UUID, error)
+	CreateIdempotent(sub types.Subscription, key, fingerprint string, ttl time.Duration) (uuid.UUID, bool, error)
 	Get(id uuid.UUID) (*types.Subscription, error)
-	Update(id uuid.UUID, sub types.Subscription) error
-	Delete(id uuid.UUID) error
-	List() ([]types.Subscription, error)
-	GetTotalCost(id uuid.UUID, serviceName, periodStart, periodEnd string) (int, error)
+	Update(id uuid.UUID, sub types.Subscription, ifMatch []int64) (int, error)
+	Delete(id uuid.UUID, ifMatch []int64) error
+	List(filter SubscriptionFilter) ([]types.Subscription, error)
+	Stream(ctx context.Context, filter SubscriptionFilter, fn func(types.Subscription) error) error
+	Pause(id uuid.UUID, month string) (string, error)
+	Resume(id uuid.UUID, month string) (string, error)
+	Cancel(id uuid.UUID, month string) (string, error)
+	Reactivate(id uuid.UUID) (string, error)
+	SchedulePrice(id uuid.UUID, month string, price money.Money) error
+	DeletePriceChange(id uuid.UUID, month string) error
+	PriceHistory(id uuid.UUID) ([]types.PricePeriod, error)
+	AddDiscount(id uuid.UUID, req types.DiscountRequest) (types.Discount, error)
+	DeleteDiscount(id, discountID uuid.UUID) error
+	GetSharing(id uuid.UUID) (types.Sharing, error)
+	SetSharing(id uuid.UUID, req types.Sharing) (types.Sharing, error)
+	ApplyBatch(ops []BatchOp, atomic bool) ([]BatchOpResult, error)
+	Import(subs []types.Subscription, dryRun bool) (ImportResult, error)
+	SetCalendarToken(userID uuid.UUID, tokenHash string) error
+	DeleteCalendarToken(userID uuid.UUID) error
+	CalendarEntries(tokenHash string) (uuid.UUID, []types.CalendarEntry, error)
+	GetTotalCost(q CostQuery) (money.Money, error)
+	CostBreakdown(q CostQuery) ([]types.CostItem, error)
+	ListServices(category string) ([]types.Service, error)
+	GetService(id uuid.UUID) (*types.Service, error)
+	CreateService(svc types.Service) (uuid.UUID, error)
+	UpdateService(id uuid.UUID, svc types.Service) error
+	DeleteService(id uuid.UUID) error
+	ListUsers(filter UserFilter) ([]types.User, error)
+	GetUser(id uuid.UUID) (*types.User, error)
+	CreateUser(user types.User) (uuid.UUID, error)
+	UpdateUser(id uuid.UUID, user types.User) error
+	DeleteUser(id uuid.UUID, subscriptions string, transferTo uuid.UUID) (int64, error)
+	UnknownUsers(ids []uuid.UUID) ([]uuid.UUID, error)
+	ListBudgets(userID uuid.UUID) ([]types.Budget, error)
+	GetBudget(id uuid.UUID) (*types.Budget, error)
+	CreateBudget(budget types.Budget) (uuid.UUID, error)
+	UpdateBudget(id uuid.UUID, budget types.Budget) error
+	DeleteBudget(id uuid.UUID) error
+	BudgetStatus(id uuid.UUID, now time.Time) (types.BudgetStatus, error)
+	BudgetAlerts(id uuid.UUID) ([]types.BudgetAlert, error)
+	EvaluateBudgets(now time.Time) ([]types.BudgetAlert, error)
+	ClaimBudgetAlerts(lease time.Duration) ([]types.BudgetAlert, error)
+	MarkBudgetAlertNotified(alert types.BudgetAlert) error
+	GetReminderPreferences(userID uuid.UUID) (types.ReminderPreferences, error)
+	SetReminderPreferences(userID uuid.UUID, prefs types.ReminderPreferences) error
+	ListReminders(userID uuid.UUID) ([]types.Reminder, error)
+	CreateReminders(now time.Time, window int) ([]types.Reminder, error)
+	ClaimReminders(lease time.Duration, now time.Time) ([]types.Reminder, error)
+	MarkReminderNotified(reminder types.Reminder) error
+	NextRenewals(userID uuid.UUID, now, until time.Time) ([]types.Renewal, error)
+	RecordDelivery(delivery types.Delivery) error
+	EventDelivered(eventID uuid.UUID, channel string) (bool, error)
+	ListDeliveries(filter DeliveryFilter) ([]types.Delivery, error)
+	ListWebhooks() ([]types.WebhookEndpoint, error)
+	GetWebhook(id uuid.UUID) (*types.WebhookEndpoint, error)
+	CreateWebhook(endpoint types.WebhookEndpoint) (uuid.UUID, error)
+	UpdateWebhook(id uuid.UUID, endpoint types.WebhookEndpoint) error
+	DeleteWebhook(id uuid.UUID) error
+	EnqueueWebhookEvent(event types.SubscriptionEvent) (int, error)
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]WebhookJob, error)
+	RecordWebhookAttempt(id uuid.UUID, attempt WebhookAttempt) error
+	ListWebhookDeliveries(filter WebhookDeliveryFilter) ([]types.WebhookDelivery, error)
+	RedeliverWebhook(id uuid.UUID) error
+	RecordExpiredSubscriptions(now time.Time) (int, error)
+	ClaimOutboxEvents(limit int, lease time.Duration) ([]OutboxEvent, error)
+	RecordOutboxAttempt(id uuid.UUID, attempt OutboxAttempt) error
+	PurgeOutbox(before time.Time) (int64, error)
+	UpsertRates(rates []fx.Rate) (int, error)
+	ListRates(currency string) ([]fx.Rate, error)
 }
@@ -0,0 +1,522 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/money"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var (
+	ErrServiceNotFound = errors.New("service not found")
+	ErrServiceConflict = errors.New("service name or alias already used")
+)
+
+// uniqueViolation is the PostgreSQL error code for a duplicate key.
+const uniqueViolation = "23505"
+
+// serviceKeySQL normalizes a service_name column the same way serviceKey does.
+const serviceKeySQL = `lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g'))`
+
+// serviceKey is the form service names and aliases are matched in: lower case
+// with runs of whitespace collapsed.
+func serviceKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ListServices lists the catalog, narrowed down to a category if it is not
+// empty. The category is matched in the form categories are stored in.
+func (r *PostgresRepository) ListServices(category string) ([]types.Service, error) {
+	query := `
+        SELECT service_id, name, category, vendor, website, default_price_minor, default_currency
+        FROM services
+        WHERE TRUE
+    `
+	query, args := servicesWhere(query, category)
+	query += " ORDER BY name"
+
+	logger.Logger.Debugw("Listing services",
+		"category", category,
+	)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		logger.Logger.Errorw("Failed to list services",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		services []types.Service
+		ids      []uuid.UUID
+	)
+	for rows.Next() {
+		svc, id, err := scanService(rows)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan service row",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan service row: %w", err)
+		}
+		services = append(services, svc)
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+
+	aliases, err := r.loadAliases(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range services {
+		services[i].Aliases = aliases[ids[i]]
+	}
+
+	logger.Logger.Infow("Successfully listed services",
+		"count", len(services),
+	)
+	return services, nil
+}
+
+// servicesWhere appends the category condition to a query over services that
+// already has a WHERE clause.
+func servicesWhere(query, category string) (string, []interface{}) {
+	if category = normalizeLabel(category); category == "" {
+		return query, nil
+	}
+	return query + " AND category = $1", []interface{}{category}
+}
+
+func (r *PostgresRepository) GetService(id uuid.UUID) (*types.Service, error) {
+	query := `
+        SELECT service_id, name, category, vendor, website, default_price_minor, default_currency
+        FROM services
+        WHERE service_id = $1
+    `
+
+	logger.Logger.Debugw("Getting service",
+		"serviceID", id,
+	)
+
+	svc, _, err := scanService(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Service not found",
+			"serviceID", id,
+		)
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get service",
+			"error", err,
+			"serviceID", id,
+		)
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	aliases, err := r.loadAliases([]uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	svc.Aliases = aliases[id]
+
+	return &svc, nil
+}
+
+// CreateService adds a service to the catalog and links the subscriptions
+// whose free-text name matches its name or one of its aliases.
+func (r *PostgresRepository) CreateService(svc types.Service) (uuid.UUID, error) {
+	id := uuid.New()
+	logger.Logger.Debugw("Creating service",
+		"serviceID", id,
+		"name", svc.Name,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	price, currency := defaultPrice(svc)
+	_, err = tx.Exec(`
+        INSERT INTO services (service_id, name, category, vendor, website, default_price_minor, default_currency)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `, id, svc.Name, nullString(normalizeLabel(svc.Category)), nullString(svc.Vendor), nullString(svc.Website), price, currency)
+	if err != nil {
+		logger.Logger.Errorw("Failed to create service",
+			"error", err,
+			"serviceID", id,
+		)
+		return uuid.Nil, fmt.Errorf("failed to create service: %w", err)
+	}
+
+	if err := r.saveAliases(tx, id, svc); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"serviceID", id,
+		)
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully created service",
+		"serviceID", id,
+	)
+	return id, nil
+}
+
+// UpdateService replaces a catalog entry. Linked subscriptions follow a new
+// canonical name, and unlinked ones matching the new aliases are linked.
+func (r *PostgresRepository) UpdateService(id uuid.UUID, svc types.Service) error {
+	logger.Logger.Debugw("Updating service",
+		"serviceID", id,
+		"name", svc.Name,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	price, currency := defaultPrice(svc)
+	result, err := tx.Exec(`
+        UPDATE services
+        SET
+            name = $1,
+            category = $2,
+            vendor = $3,
+            website = $4,
+            default_price_minor = $5,
+            default_currency = $6
+        WHERE service_id = $7
+    `, svc.Name, nullString(normalizeLabel(svc.Category)), nullString(svc.Vendor), nullString(svc.Website), price, currency, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to update service",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("Service not found",
+			"serviceID", id,
+		)
+		return ErrServiceNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM service_aliases WHERE service_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete service aliases",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to delete service aliases: %w", err)
+	}
+
+	if err := r.saveAliases(tx, id, svc); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully updated service",
+		"serviceID", id,
+	)
+	return nil
+}
+
+// DeleteService removes a service from the catalog. Its subscriptions keep
+// their names and are no longer linked.
+func (r *PostgresRepository) DeleteService(id uuid.UUID) error {
+	logger.Logger.Debugw("Deleting service",
+		"serviceID", id,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Unlinking here rather than through ON DELETE SET NULL bumps the versions
+	// and records the change of every linked subscription.
+	unlinked, err := subscriptionIDs(tx, `
+        UPDATE subscriptions SET service_id = NULL, version = version + 1
+        WHERE service_id = $1
+        RETURNING sub_id
+    `, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM services WHERE service_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete service",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to check rows affected",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("Service not found",
+			"serviceID", id,
+		)
+		return ErrServiceNotFound
+	}
+
+	if err := r.recordChanges(tx, unlinked); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully deleted service",
+		"serviceID", id,
+		"unlinked", len(unlinked),
+	)
+	return nil
+}
+
+// saveAliases stores the name and aliases of a service as lookup keys and
+// brings the names of matching subscriptions in line with the catalog.
+func (r *PostgresRepository) saveAliases(tx *sql.Tx, id uuid.UUID, svc types.Service) error {
+	keys := []string{serviceKey(svc.Name)}
+	aliases := []string{svc.Name}
+	for _, alias := range svc.Aliases {
+		key := serviceKey(alias)
+		if key == "" || slices.Contains(keys, key) {
+			continue
+		}
+		keys = append(keys, key)
+		aliases = append(aliases, alias)
+	}
+
+	for i, key := range keys {
+		_, err := tx.Exec(`
+            INSERT INTO service_aliases (alias_key, service_id, alias)
+            VALUES ($1, $2, $3)
+        `, key, id, aliases[i])
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			logger.Logger.Warnw("Service alias already used",
+				"serviceID", id,
+				"alias", aliases[i],
+			)
+			return fmt.Errorf("%w: %q", ErrServiceConflict, aliases[i])
+		}
+		if err != nil {
+			logger.Logger.Errorw("Failed to store service alias",
+				"error", err,
+				"serviceID", id,
+			)
+			return fmt.Errorf("failed to store service alias: %w", err)
+		}
+	}
+
+	linked, err := subscriptionIDs(tx, `
+        UPDATE subscriptions
+        SET service_id = $1, service_name = $2, version = version + 1
+        WHERE (service_id = $1 AND service_name <> $2)
+            OR (service_id IS NULL AND `+serviceKeySQL+` = ANY($3))
+        RETURNING sub_id
+    `, id, svc.Name, pq.Array(keys))
+	if err != nil {
+		logger.Logger.Errorw("Failed to link subscriptions to service",
+			"error", err,
+			"serviceID", id,
+		)
+		return fmt.Errorf("failed to link subscriptions to service: %w", err)
+	}
+
+	logger.Logger.Debugw("Linked subscriptions to service",
+		"serviceID", id,
+		"count", len(linked),
+	)
+	return r.recordChanges(tx, linked)
+}
+
+// resolveService links sub to the catalog: by service_id when given, or else
+// by matching its name against service names and aliases. A matched
+// subscription takes the canonical name; an unknown name is kept as is.
+func (r *PostgresRepository) resolveService(q dbtx, sub *types.Subscription) error {
+	var (
+		id   uuid.UUID
+		name string
+		err  error
+	)
+	if sub.ServiceID != nil {
+		err = q.QueryRow(`SELECT service_id, name FROM services WHERE service_id = $1`, *sub.ServiceID).Scan(&id, &name)
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Logger.Warnw("Service not found",
+				"serviceID", *sub.ServiceID,
+			)
+			return ErrServiceNotFound
+		}
+	} else {
+		err = q.QueryRow(`
+            SELECT s.service_id, s.name
+            FROM service_aliases a
+            JOIN services s ON s.service_id = a.service_id
+            WHERE a.alias_key = $1
+        `, serviceKey(sub.ServiceName)).Scan(&id, &name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to resolve service",
+			"error", err,
+			"serviceName", sub.ServiceName,
+		)
+		return fmt.Errorf("failed to resolve service: %w", err)
+	}
+
+	sub.ServiceID = &id
+	sub.ServiceName = name
+	return nil
+}
+
+// loadAliases returns the aliases of the given services keyed by ID, without
+// the canonical names.
+func (r *PostgresRepository) loadAliases(ids []uuid.UUID) (map[uuid.UUID][]string, error) {
+	aliases := make(map[uuid.UUID][]string)
+	if len(ids) == 0 {
+		return aliases, nil
+	}
+
+	rows, err := r.db.Query(`
+        SELECT a.service_id, a.alias
+        FROM service_aliases a
+        JOIN services s ON s.service_id = a.service_id
+        WHERE a.service_id = ANY($1) AND a.alias <> s.name
+        ORDER BY a.alias
+    `, pq.Array(ids))
+	if err != nil {
+		logger.Logger.Errorw("Failed to load service aliases",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to load service aliases: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id    uuid.UUID
+			alias string
+		)
+		if err := rows.Scan(&id, &alias); err != nil {
+			logger.Logger.Errorw("Failed to scan service alias",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan service alias: %w", err)
+		}
+		aliases[id] = append(aliases[id], alias)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return aliases, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanService(row rowScanner) (types.Service, uuid.UUID, error) {
+	var (
+		svc      types.Service
+		id       uuid.UUID
+		category sql.NullString
+		vendor   sql.NullString
+		website  sql.NullString
+		price    sql.NullInt64
+		currency sql.NullString
+	)
+	if err := row.Scan(&id, &svc.Name, &category, &vendor, &website, &price, &currency); err != nil {
+		return svc, id, err
+	}
+
+	svc.ID = id.String()
+	svc.Category = category.String
+	svc.Vendor = vendor.String
+	svc.Website = website.String
+	if price.Valid {
+		p := money.New(price.Int64, currency.String)
+		svc.DefaultPrice = &p
+	}
+	return svc, id, nil
+}
+
+func defaultPrice(svc types.Service) (sql.NullInt64, sql.NullString) {
+	if svc.DefaultPrice == nil {
+		return sql.NullInt64{}, sql.NullString{}
+	}
+	return sql.NullInt64{Int64: svc.DefaultPrice.Amount, Valid: true},
+		sql.NullString{String: svc.DefaultPrice.Currency, Valid: true}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
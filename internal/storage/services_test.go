@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServicesWhere(t *testing.T) {
+	const query = "SELECT name FROM services WHERE TRUE"
+	tests := []struct {
+		category  string
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{category: "", wantQuery: query},
+		{category: "   ", wantQuery: query},
+		{category: "streaming", wantQuery: query + " AND category = $1", wantArgs: []interface{}{"streaming"}},
+		{category: "Streaming", wantQuery: query + " AND category = $1", wantArgs: []interface{}{"streaming"}},
+		{category: " streaming ", wantQuery: query + " AND category = $1", wantArgs: []interface{}{"streaming"}},
+		{category: "Video  Streaming", wantQuery: query + " AND category = $1", wantArgs: []interface{}{"video streaming"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			gotQuery, gotArgs := servicesWhere(query, tt.category)
+			if gotQuery != tt.wantQuery || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("servicesWhere(%q) = %q, %v; want %q, %v", tt.category, gotQuery, gotArgs, tt.wantQuery, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestServicesWhereMatchesStoredCategories(t *testing.T) {
+	for _, stored := range []string{"Streaming", "  STREAMING", "streaming"} {
+		_, args := servicesWhere("", " Streaming ")
+		if want := normalizeLabel(stored); args[0] != want {
+			t.Errorf("filter %v does not match category %q stored as %q", args, stored, want)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+// What DeleteUser does with the subscriptions the user owns. Memberships in
+// other users' subscriptions and calendar tokens are always removed.
+const (
+	RestrictUserSubscriptions = "restrict"
+	DeleteUserSubscriptions   = "delete"
+	TransferUserSubscriptions = "transfer"
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrUserConflict         = errors.New("user already exists")
+	ErrUserHasSubscriptions = errors.New("user owns subscriptions")
+	ErrInvalidUserDelete    = errors.New("invalid user delete options")
+)
+
+// foreignKeyViolation is the PostgreSQL error code for a reference to a
+// missing row.
+const foreignKeyViolation = "23503"
+
+// UserFilter looks users up by email, matched without regard to case, or by
+// their ID in an external system. Empty fields match every user.
+type UserFilter struct {
+	Email      string
+	ExternalID string
+}
+
+func (r *PostgresRepository) ListUsers(filter UserFilter) ([]types.User, error) {
+	query := `
+        SELECT user_id, name, email, external_id, language
+        FROM users
+        WHERE ($1 = '' OR email = $1) AND ($2 = '' OR external_id = $2)
+        ORDER BY name, user_id
+    `
+
+	logger.Logger.Debugw("Listing users",
+		"email", filter.Email,
+		"externalID", filter.ExternalID,
+	)
+
+	rows, err := r.db.Query(query, normalizeEmail(filter.Email), filter.ExternalID)
+	if err != nil {
+		logger.Logger.Errorw("Failed to list users",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []types.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan user row",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully listed users",
+		"count", len(users),
+	)
+	return users, nil
+}
+
+func (r *PostgresRepository) GetUser(id uuid.UUID) (*types.User, error) {
+	logger.Logger.Debugw("Getting user",
+		"userID", id,
+	)
+
+	user, err := scanUser(r.db.QueryRow(`SELECT user_id, name, email, external_id, language FROM users WHERE user_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("User not found",
+			"userID", id,
+		)
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get user",
+			"error", err,
+			"userID", id,
+		)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// CreateUser registers a user under user.ID, or a new ID if it is empty, so
+// that owners of existing subscriptions can be registered as they are.
+func (r *PostgresRepository) CreateUser(user types.User) (uuid.UUID, error) {
+	id := uuid.New()
+	if user.ID != "" {
+		parsed, err := uuid.Parse(user.ID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid user id: %w", err)
+		}
+		id = parsed
+	}
+
+	logger.Logger.Debugw("Creating user",
+		"userID", id,
+		"name", user.Name,
+	)
+
+	_, err := r.db.Exec(`
+        INSERT INTO users (user_id, name, email, external_id, language)
+        VALUES ($1, $2, $3, $4, $5)
+    `, id, user.Name, nullString(normalizeEmail(user.Email)), nullString(user.ExternalID), userLanguage(user))
+	if err := userWriteError(err, id); err != nil {
+		return uuid.Nil, err
+	}
+
+	logger.Logger.Infow("Successfully created user",
+		"userID", id,
+	)
+	return id, nil
+}
+
+func (r *PostgresRepository) UpdateUser(id uuid.UUID, user types.User) error {
+	logger.Logger.Debugw("Updating user",
+		"userID", id,
+		"name", user.Name,
+	)
+
+	result, err := r.db.Exec(`
+        UPDATE users
+        SET name = $1, email = $2, external_id = $3, language = $4
+        WHERE user_id = $5
+    `, user.Name, nullString(normalizeEmail(user.Email)), nullString(user.ExternalID), userLanguage(user), id)
+	if err := userWriteError(err, id); err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"userID", id,
+		)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("User not found",
+			"userID", id,
+		)
+		return ErrUserNotFound
+	}
+
+	logger.Logger.Infow("Successfully updated user",
+		"userID", id,
+	)
+	return nil
+}
+
+// DeleteUser removes a user. With RestrictUserSubscriptions the user may not own
+// any subscriptions; DeleteUserSubscriptions deletes them along with the user
+// and TransferUserSubscriptions hands them over to transferTo. It returns the
+// number of subscriptions deleted or transferred.
+func (r *PostgresRepository) DeleteUser(id uuid.UUID, subscriptions string, transferTo uuid.UUID) (int64, error) {
+	logger.Logger.Debugw("Deleting user",
+		"userID", id,
+		"subscriptions", subscriptions,
+		"transferTo", transferTo,
+	)
+
+	switch {
+	case subscriptions == TransferUserSubscriptions && (transferTo == uuid.Nil || transferTo == id):
+		return 0, fmt.Errorf("%w: transfer needs another user", ErrInvalidUserDelete)
+	case subscriptions != TransferUserSubscriptions && transferTo != uuid.Nil:
+		return 0, fmt.Errorf("%w: transfer_to is only used with transfer", ErrInvalidUserDelete)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT user_id FROM users WHERE user_id = $1 FOR UPDATE`, id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("User not found",
+			"userID", id,
+		)
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get user",
+			"error", err,
+			"userID", id,
+		)
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Every subscription the deletion changes gets its event: those the user
+	// is a member of lose a member and those the user owns are deleted or
+	// transferred.
+	shared, err := subscriptionIDs(tx, `SELECT sub_id FROM subscription_members WHERE user_id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	owned, err := subscriptionIDs(tx, `SELECT sub_id FROM subscriptions WHERE user_id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	deleted := make(map[uuid.UUID]*types.Subscription)
+	if subscriptions == DeleteUserSubscriptions {
+		for _, subID := range owned {
+			if deleted[subID], err = r.getSubscription(tx, subID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// Subscriptions the user shares lose a member, which changes what the
+	// others pay.
+	_, err = tx.Exec(`
+        UPDATE subscriptions SET version = version + 1
+        WHERE sub_id IN (SELECT sub_id FROM subscription_members WHERE user_id = $1)
+    `, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to update shared subscriptions",
+			"error", err,
+			"userID", id,
+		)
+		return 0, fmt.Errorf("failed to update shared subscriptions: %w", err)
+	}
+
+	var result sql.Result
+	switch subscriptions {
+	case RestrictUserSubscriptions:
+		var owns bool
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM subscriptions WHERE user_id = $1)`, id).Scan(&owns)
+		if err == nil && owns {
+			logger.Logger.Warnw("User still owns subscriptions",
+				"userID", id,
+			)
+			return 0, ErrUserHasSubscriptions
+		}
+	case DeleteUserSubscriptions:
+		result, err = tx.Exec(`DELETE FROM subscriptions WHERE user_id = $1`, id)
+	case TransferUserSubscriptions:
+		if err := r.checkUser(tx, transferTo); err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrInvalidUserDelete, err)
+		}
+		// The new owner no longer pays as a member of what they now own.
+		_, err = tx.Exec(`
+            DELETE FROM subscription_members
+            WHERE user_id = $2 AND sub_id IN (SELECT sub_id FROM subscriptions WHERE user_id = $1)
+        `, id, transferTo)
+		if err == nil {
+			result, err = tx.Exec(`UPDATE subscriptions SET user_id = $2, version = version + 1 WHERE user_id = $1`, id, transferTo)
+		}
+	default:
+		return 0, fmt.Errorf("%w: unknown subscriptions option %q", ErrInvalidUserDelete, subscriptions)
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to handle user subscriptions",
+			"error", err,
+			"userID", id,
+			"subscriptions", subscriptions,
+		)
+		return 0, fmt.Errorf("failed to handle user subscriptions: %w", err)
+	}
+
+	var affected int64
+	if result != nil {
+		affected, err = result.RowsAffected()
+		if err != nil {
+			logger.Logger.Errorw("Failed to get rows affected",
+				"error", err,
+				"userID", id,
+			)
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM users WHERE user_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete user",
+			"error", err,
+			"userID", id,
+		)
+		return 0, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	for _, subID := range owned {
+		if sub, ok := deleted[subID]; ok {
+			_, err = recordEvent(tx, types.EventSubscriptionDeleted, subID, sub, "")
+		} else if subscriptions == TransferUserSubscriptions {
+			err = r.recordChange(tx, types.EventSubscriptionUpdated, subID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, subID := range shared {
+		if _, ok := deleted[subID]; ok {
+			continue
+		}
+		if err := r.recordChange(tx, types.EventSubscriptionUpdated, subID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"userID", id,
+		)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully deleted user",
+		"userID", id,
+		"subscriptions", subscriptions,
+		"affected", affected,
+	)
+	return affected, nil
+}
+
+// subscriptionIDs returns the subscription IDs selected by query.
+func subscriptionIDs(q dbtx, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		logger.Logger.Errorw("Failed to find subscriptions",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to find subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return ids, nil
+}
+
+// UnknownUsers returns those of ids that are not registered users.
+func (r *PostgresRepository) UnknownUsers(ids []uuid.UUID) ([]uuid.UUID, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(`
+        SELECT DISTINCT id
+        FROM unnest($1::uuid[]) AS id
+        WHERE NOT EXISTS (SELECT 1 FROM users WHERE user_id = id)
+    `, pq.Array(ids))
+	if err != nil {
+		logger.Logger.Errorw("Failed to check users",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to check users: %w", err)
+	}
+	defer rows.Close()
+
+	var unknown []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			logger.Logger.Errorw("Failed to scan user ID",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		unknown = append(unknown, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return unknown, nil
+}
+
+// checkUser returns ErrUserNotFound unless id is a registered user.
+func (r *PostgresRepository) checkUser(q dbtx, id uuid.UUID) error {
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE user_id = $1)`, id).Scan(&exists)
+	if err != nil {
+		logger.Logger.Errorw("Failed to check user",
+			"error", err,
+			"userID", id,
+		)
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		logger.Logger.Warnw("User not found",
+			"userID", id,
+		)
+		return fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// userWriteError maps a failed insert or update of a user to ErrUserConflict
+// when the ID, email or external ID is already taken.
+func userWriteError(err error, id uuid.UUID) error {
+	if err == nil {
+		return nil
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		logger.Logger.Warnw("User already exists",
+			"userID", id,
+			"constraint", pqErr.Constraint,
+		)
+		return fmt.Errorf("%w: %s", ErrUserConflict, pqErr.Constraint)
+	}
+	logger.Logger.Errorw("Failed to save user",
+		"error", err,
+		"userID", id,
+	)
+	return fmt.Errorf("failed to save user: %w", err)
+}
+
+func scanUser(row rowScanner) (types.User, error) {
+	var (
+		user       types.User
+		id         uuid.UUID
+		email      sql.NullString
+		externalID sql.NullString
+	)
+	if err := row.Scan(&id, &user.Name, &email, &externalID, &user.Language); err != nil {
+		return user, err
+	}
+
+	user.ID = id.String()
+	user.Email = email.String
+	user.ExternalID = externalID.String
+	return user, nil
+}
+
+// userLanguage is the language user gets notifications in, Russian unless
+// another one is chosen.
+func userLanguage(user types.User) string {
+	if user.Language == "" {
+		return types.LanguageRussian
+	}
+	return user.Language
+}
+
+// normalizeEmail is the form emails are stored and looked up in.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
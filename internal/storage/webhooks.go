@@ -0,0 +1,556 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/types"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// webhookDeliveriesLimit caps how many of the latest deliveries are listed.
+const webhookDeliveriesLimit = 100
+
+// WebhookDeliveryFilter narrows deliveries to an endpoint or a status. Empty
+// fields match every delivery.
+type WebhookDeliveryFilter struct {
+	EndpointID uuid.UUID
+	Status     string
+}
+
+// WebhookJob is a delivery claimed for sending along with what is needed to
+// send it.
+type WebhookJob struct {
+	DeliveryID uuid.UUID
+	Attempts   int
+	URL        string
+	Secret     string
+	EventID    uuid.UUID
+	EventType  string
+	Payload    []byte
+}
+
+// WebhookAttempt is the outcome of sending a delivery once.
+type WebhookAttempt struct {
+	StatusCode int
+	Error      string
+	Delivered  bool
+	// RetryIn is the wait before the next attempt of a failed delivery; zero
+	// gives up and moves the delivery to the dead letters.
+	RetryIn time.Duration
+}
+
+func (r *PostgresRepository) ListWebhooks() ([]types.WebhookEndpoint, error) {
+	rows, err := r.db.Query(`
+        SELECT endpoint_id, url, events, disabled, created_at
+        FROM webhook_endpoints
+        ORDER BY created_at, endpoint_id
+    `)
+	if err != nil {
+		logger.Logger.Errorw("Failed to list webhooks",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []types.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanWebhook(rows)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan webhook",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, endpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (r *PostgresRepository) GetWebhook(id uuid.UUID) (*types.WebhookEndpoint, error) {
+	endpoint, err := scanWebhook(r.db.QueryRow(`
+        SELECT endpoint_id, url, events, disabled, created_at
+        FROM webhook_endpoints
+        WHERE endpoint_id = $1
+    `, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Logger.Warnw("Webhook not found",
+			"endpointID", id,
+		)
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorw("Failed to get webhook",
+			"error", err,
+			"endpointID", id,
+		)
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// CreateWebhook registers an endpoint that signs its events with
+// endpoint.Secret.
+func (r *PostgresRepository) CreateWebhook(endpoint types.WebhookEndpoint) (uuid.UUID, error) {
+	id := uuid.New()
+	logger.Logger.Debugw("Creating webhook",
+		"endpointID", id,
+		"url", endpoint.URL,
+		"events", endpoint.Events,
+	)
+
+	_, err := r.db.Exec(`
+        INSERT INTO webhook_endpoints (endpoint_id, url, secret, events, disabled)
+        VALUES ($1, $2, $3, $4, $5)
+    `, id, endpoint.URL, endpoint.Secret, pq.Array(webhookEvents(endpoint)), endpoint.Disabled)
+	if err != nil {
+		logger.Logger.Errorw("Failed to create webhook",
+			"error", err,
+			"url", endpoint.URL,
+		)
+		return uuid.Nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	logger.Logger.Infow("Successfully created webhook",
+		"endpointID", id,
+	)
+	return id, nil
+}
+
+// UpdateWebhook replaces the URL, events and state of the endpoint. Its
+// secret stays the same.
+func (r *PostgresRepository) UpdateWebhook(id uuid.UUID, endpoint types.WebhookEndpoint) error {
+	logger.Logger.Debugw("Updating webhook",
+		"endpointID", id,
+		"url", endpoint.URL,
+	)
+
+	result, err := r.db.Exec(`
+        UPDATE webhook_endpoints
+        SET url = $1, events = $2, disabled = $3
+        WHERE endpoint_id = $4
+    `, endpoint.URL, pq.Array(webhookEvents(endpoint)), endpoint.Disabled, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to update webhook",
+			"error", err,
+			"endpointID", id,
+		)
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return r.webhookAffected(result, id)
+}
+
+// DeleteWebhook removes the endpoint along with its deliveries.
+func (r *PostgresRepository) DeleteWebhook(id uuid.UUID) error {
+	logger.Logger.Debugw("Deleting webhook",
+		"endpointID", id,
+	)
+
+	result, err := r.db.Exec(`DELETE FROM webhook_endpoints WHERE endpoint_id = $1`, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to delete webhook",
+			"error", err,
+			"endpointID", id,
+		)
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return r.webhookAffected(result, id)
+}
+
+func (r *PostgresRepository) webhookAffected(result sql.Result, id uuid.UUID) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"endpointID", id,
+		)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("Webhook not found",
+			"endpointID", id,
+		)
+		return ErrWebhookNotFound
+	}
+
+	logger.Logger.Infow("Successfully changed webhook",
+		"endpointID", id,
+	)
+	return nil
+}
+
+// EnqueueWebhookEvent records event and a pending delivery of it to every
+// enabled endpoint subscribed to its type. An event already recorded is not
+// queued again. It returns the number of deliveries queued.
+func (r *PostgresRepository) EnqueueWebhookEvent(event types.SubscriptionEvent) (int, error) {
+	eventID, err := uuid.Parse(event.ID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event ID %q: %w", event.ID, err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logger.Logger.Errorw("Failed to begin transaction",
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	endpoints, err := subscribedEndpoints(tx, event.Type)
+	if err != nil {
+		return 0, err
+	}
+	if len(endpoints) == 0 {
+		return 0, nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	result, err := tx.Exec(`
+        INSERT INTO webhook_events (event_id, event_type, sub_id, payload)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (event_id) DO NOTHING
+    `, eventID, event.Type, event.SubID, payload)
+	if err != nil {
+		logger.Logger.Errorw("Failed to store webhook event",
+			"error", err,
+			"event", event.Type,
+			"subscriptionID", event.SubID,
+		)
+		return 0, fmt.Errorf("failed to store webhook event: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"eventID", eventID,
+		)
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Debugw("Webhook event already recorded",
+			"eventID", eventID,
+		)
+		return 0, nil
+	}
+
+	for _, endpointID := range endpoints {
+		_, err := tx.Exec(`
+            INSERT INTO webhook_deliveries (delivery_id, endpoint_id, event_id)
+            VALUES ($1, $2, $3)
+        `, uuid.New(), endpointID, eventID)
+		if err != nil {
+			logger.Logger.Errorw("Failed to queue webhook delivery",
+				"error", err,
+				"endpointID", endpointID,
+				"eventID", eventID,
+			)
+			return 0, fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Logger.Errorw("Failed to commit transaction",
+			"error", err,
+			"eventID", eventID,
+		)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Logger.Infow("Webhook event queued",
+		"eventID", eventID,
+		"event", event.Type,
+		"subscriptionID", event.SubID,
+		"deliveries", len(endpoints),
+	)
+	return len(endpoints), nil
+}
+
+// subscribedEndpoints returns the enabled endpoints that receive events of
+// eventType.
+func subscribedEndpoints(tx *sql.Tx, eventType string) ([]uuid.UUID, error) {
+	rows, err := tx.Query(`
+        SELECT endpoint_id
+        FROM webhook_endpoints
+        WHERE NOT disabled AND (cardinality(events) = 0 OR $1 = ANY(events))
+    `, eventType)
+	if err != nil {
+		logger.Logger.Errorw("Failed to find webhook endpoints",
+			"error", err,
+			"event", eventType,
+		)
+		return nil, fmt.Errorf("failed to find webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		endpoints = append(endpoints, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return endpoints, nil
+}
+
+// ClaimWebhookDeliveries returns up to limit deliveries that are due and
+// postpones them by lease, so that other workers leave them alone while they
+// are being sent.
+func (r *PostgresRepository) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]WebhookJob, error) {
+	rows, err := r.db.Query(`
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = NOW() + make_interval(secs => $2)
+        FROM webhook_endpoints e, webhook_events v
+        WHERE d.delivery_id IN (
+            SELECT w.delivery_id
+            FROM webhook_deliveries w
+            JOIN webhook_endpoints we ON we.endpoint_id = w.endpoint_id
+            WHERE w.status = 'pending' AND w.next_attempt_at <= NOW() AND NOT we.disabled
+            ORDER BY w.next_attempt_at
+            LIMIT $1
+            FOR UPDATE OF w SKIP LOCKED
+        ) AND e.endpoint_id = d.endpoint_id AND v.event_id = d.event_id
+        RETURNING d.delivery_id, d.attempts, e.url, e.secret, v.event_id, v.event_type, v.payload
+    `, limit, lease.Seconds())
+	if err != nil {
+		logger.Logger.Errorw("Failed to claim webhook deliveries",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []WebhookJob
+	for rows.Next() {
+		var job WebhookJob
+		err := rows.Scan(&job.DeliveryID, &job.Attempts, &job.URL, &job.Secret, &job.EventID, &job.EventType, &job.Payload)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan webhook delivery",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return jobs, nil
+}
+
+// RecordWebhookAttempt stores the outcome of sending a delivery: delivered,
+// scheduled for another attempt or given up on.
+func (r *PostgresRepository) RecordWebhookAttempt(id uuid.UUID, attempt WebhookAttempt) error {
+	status := types.WebhookPending
+	switch {
+	case attempt.Delivered:
+		status = types.WebhookDelivered
+	case attempt.RetryIn <= 0:
+		status = types.WebhookDead
+	}
+
+	var statusCode sql.NullInt64
+	if attempt.StatusCode != 0 {
+		statusCode = sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: true}
+	}
+	_, err := r.db.Exec(`
+        UPDATE webhook_deliveries
+        SET attempts = attempts + 1,
+            status = $2,
+            last_status_code = $3,
+            last_error = $4,
+            next_attempt_at = NOW() + make_interval(secs => $5),
+            delivered_at = CASE WHEN $6 THEN NOW() END
+        WHERE delivery_id = $1
+    `, id, status, statusCode, nullString(attempt.Error), attempt.RetryIn.Seconds(), attempt.Delivered)
+	if err != nil {
+		logger.Logger.Errorw("Failed to record webhook attempt",
+			"error", err,
+			"deliveryID", id,
+		)
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	if status == types.WebhookDead {
+		logger.Logger.Warnw("Webhook delivery moved to dead letters",
+			"deliveryID", id,
+			"statusCode", attempt.StatusCode,
+			"error", attempt.Error,
+		)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the latest deliveries matching filter, newest
+// first. Filtering by an unknown endpoint fails with ErrWebhookNotFound.
+func (r *PostgresRepository) ListWebhookDeliveries(filter WebhookDeliveryFilter) ([]types.WebhookDelivery, error) {
+	if filter.EndpointID != uuid.Nil {
+		if _, err := r.GetWebhook(filter.EndpointID); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := r.db.Query(`
+        SELECT d.delivery_id, d.endpoint_id, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error,
+               d.created_at, d.delivered_at, v.payload
+        FROM webhook_deliveries d
+        JOIN webhook_events v ON v.event_id = d.event_id
+        WHERE ($1::uuid IS NULL OR d.endpoint_id = $1) AND ($2 = '' OR d.status = $2)
+        ORDER BY d.created_at DESC, d.delivery_id
+        LIMIT $3
+    `, uuid.NullUUID{UUID: filter.EndpointID, Valid: filter.EndpointID != uuid.Nil}, filter.Status, webhookDeliveriesLimit)
+	if err != nil {
+		logger.Logger.Errorw("Failed to list webhook deliveries",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		var (
+			delivery    types.WebhookDelivery
+			id          uuid.UUID
+			endpointID  uuid.UUID
+			nextAttempt time.Time
+			statusCode  sql.NullInt64
+			lastError   sql.NullString
+			createdAt   time.Time
+			deliveredAt sql.NullTime
+			payload     []byte
+		)
+		err := rows.Scan(&id, &endpointID, &delivery.Status, &delivery.Attempts, &nextAttempt, &statusCode, &lastError,
+			&createdAt, &deliveredAt, &payload)
+		if err != nil {
+			logger.Logger.Errorw("Failed to scan webhook delivery",
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		if err := json.Unmarshal(payload, &delivery.Event); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook event: %w", err)
+		}
+
+		delivery.ID = id.String()
+		delivery.EndpointID = endpointID.String()
+		if delivery.Status == types.WebhookPending {
+			delivery.NextAttemptAt = nextAttempt.UTC().Format(time.RFC3339)
+		}
+		delivery.LastStatusCode = int(statusCode.Int64)
+		delivery.LastError = lastError.String
+		delivery.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = deliveredAt.Time.UTC().Format(time.RFC3339)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Logger.Errorw("Error after scanning rows",
+			"error", err,
+		)
+		return nil, fmt.Errorf("error after scanning rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook queues the delivery to be sent again right away with a
+// fresh set of attempts, whatever its state.
+func (r *PostgresRepository) RedeliverWebhook(id uuid.UUID) error {
+	logger.Logger.Debugw("Redelivering webhook event",
+		"deliveryID", id,
+	)
+
+	result, err := r.db.Exec(`
+        UPDATE webhook_deliveries
+        SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+        WHERE delivery_id = $1
+    `, id)
+	if err != nil {
+		logger.Logger.Errorw("Failed to redeliver webhook event",
+			"error", err,
+			"deliveryID", id,
+		)
+		return fmt.Errorf("failed to redeliver webhook event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Logger.Errorw("Failed to get rows affected",
+			"error", err,
+			"deliveryID", id,
+		)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Logger.Warnw("Webhook delivery not found",
+			"deliveryID", id,
+		)
+		return ErrWebhookDeliveryNotFound
+	}
+
+	logger.Logger.Infow("Webhook event queued for redelivery",
+		"deliveryID", id,
+	)
+	return nil
+}
+
+// webhookEvents is how the events of endpoint are stored, an empty list
+// meaning every event.
+func webhookEvents(endpoint types.WebhookEndpoint) []string {
+	if endpoint.Events == nil {
+		return []string{}
+	}
+	return endpoint.Events
+}
+
+func scanWebhook(row rowScanner) (types.WebhookEndpoint, error) {
+	var (
+		endpoint  types.WebhookEndpoint
+		id        uuid.UUID
+		events    []string
+		createdAt time.Time
+	)
+	if err := row.Scan(&id, &endpoint.URL, pq.Array(&events), &endpoint.Disabled, &createdAt); err != nil {
+		return endpoint, err
+	}
+
+	endpoint.ID = id.String()
+	if len(events) > 0 {
+		endpoint.Events = events
+	}
+	endpoint.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return endpoint, nil
+}
@@ -1,19 +1,559 @@
 package types
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ItserX/rest/internal/billing"
+	"github.com/ItserX/rest/internal/fx"
+	"github.com/ItserX/rest/internal/money"
+)
 
 // @Description Информация о подписке
 type Subscription struct {
-	// Название сервиса
-	ServiceName string `json:"service_name" binding:"required"`
-	// Месячная стоимость в рублях
-	Price int `json:"price" binding:"required,min=0"`
+	// Название сервиса. Название или псевдоним из каталога заменяется каноническим названием
+	ServiceName string `json:"service_name" binding:"required_without=ServiceID,max=255"`
+	// ID сервиса из каталога. Если указан, название берется из каталога
+	ServiceID *uuid.UUID `json:"service_id,omitempty" example:"4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d"`
+	// Стоимость за один расчетный период в основных единицах валюты, больше нуля. В ответе — цена текущего месяца
+	Price json.Number `json:"price" binding:"required,price" swaggertype:"number" example:"399.9"`
+	// Код валюты ISO 4217 (по умолчанию RUB)
+	Currency string `json:"currency,omitempty" binding:"omitempty,iso4217" example:"RUB"`
+	// Единица расчетного периода: week, month, quarter или year (по умолчанию month)
+	BillingInterval string `json:"billing_interval,omitempty" binding:"omitempty,oneof=week month quarter year" example:"month"`
+	// Количество единиц в расчетном периоде (по умолчанию 1)
+	BillingIntervalCount int `json:"billing_interval_count,omitempty" binding:"omitempty,min=1" example:"1"`
 	// ID пользователя-владельца подписки
 	UserID uuid.UUID `json:"user_id" binding:"required,uuid4"`
-	// Дата начала подписки в формате ММ-ГГГГ
-	StartDate string `json:"start_date" binding:"required"`
-	// Опциональная дата окончания подписки в формате ММ-ГГГГ
-	EndDate string `json:"end_date,omitempty"`
+	// Дата начала подписки в формате ГГГГ-ММ-ДД или ММ-ГГГГ (первое число месяца)
+	StartDate string `json:"start_date" binding:"required,date"`
+	// Опциональная дата окончания подписки включительно в формате ГГГГ-ММ-ДД или ММ-ГГГГ (последнее число месяца)
+	EndDate string `json:"end_date,omitempty" binding:"omitempty,date"`
+	// Собственная категория подписки, например music, video, cloud или dev tools
+	Category string `json:"category,omitempty" binding:"max=255" example:"music"`
+	// Действующая категория: собственная или, если она не задана, категория сервиса из каталога. Только для чтения
+	EffectiveCategory string `json:"effective_category,omitempty" readonly:"true" example:"music"`
+	// Произвольные теги подписки, хранятся в нижнем регистре
+	Tags []string `json:"tags,omitempty" binding:"dive,max=64" example:"family,work"`
+	// Опциональный последний день бесплатного пробного периода в формате ГГГГ-ММ-ДД или ММ-ГГГГ (последнее число месяца)
+	TrialEnd string `json:"trial_end,omitempty" binding:"omitempty,date" example:"09-2025"`
+	// Статус подписки: active, paused, cancelled или expired. Только для чтения, меняется отдельными действиями
+	Status string `json:"status,omitempty" readonly:"true" example:"active"`
+	// История приостановок подписки
+	Pauses []Pause `json:"pauses,omitempty" readonly:"true"`
+	// Скидки подписки, меняются отдельными действиями
+	Discounts []Discount `json:"discounts,omitempty" readonly:"true"`
+	// Участники совместной подписки и правило разделения стоимости, меняются отдельным действием
+	Sharing *Sharing `json:"sharing,omitempty" readonly:"true"`
+	// Версия записи, передается в заголовке ETag
+	Version int `json:"-"`
+}
+
+// Money returns the price in minor units of the subscription's currency,
+// RUB if it has none.
+func (s Subscription) Money() (money.Money, error) {
+	currency := s.Currency
+	if currency == "" {
+		currency = fx.Base
+	}
+	return money.Parse(s.Price.String(), currency)
+}
+
+// SetMoney sets the price and the currency from an amount of money.
+func (s *Subscription) SetMoney(price money.Money) {
+	s.Price = json.Number(price.Decimal())
+	s.Currency = price.Currency
+}
+
+const (
+	StatusActive    = "active"
+	StatusPaused    = "paused"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// @Description Период приостановки подписки, месяцы включительно
+type Pause struct {
+	// Первый приостановленный месяц в формате ММ-ГГГГ
+	From string `json:"from" example:"11-2025"`
+	// Последний приостановленный месяц в формате ММ-ГГГГ, пусто для текущей приостановки
+	To string `json:"to,omitempty" example:"02-2026"`
+}
+
+// @Description Скидка на подписку: процент или фиксированная сумма за расчетный период
+type Discount struct {
+	ID string `json:"id" example:"0b8f3a52-4c1e-4a8e-9d6f-2f7b5c1e9a30"`
+	// Скидка в процентах от цены
+	Percent int `json:"percent,omitempty" example:"50"`
+	// Фиксированная скидка за расчетный период в валюте подписки
+	Amount *money.Money `json:"amount,omitempty"`
+	// Первый день действия скидки
+	StartDate string `json:"start_date" example:"07-2025"`
+	// Последний день действия скидки включительно, пусто для бессрочной скидки
+	EndDate string `json:"end_date,omitempty" example:"06-2026"`
+}
+
+// @Description Новая скидка на подписку. Указывается либо percent, либо amount; срок задается months или end_date, без них скидка действует до окончания подписки
+type DiscountRequest struct {
+	// Скидка в процентах от цены, от 1 до 100
+	Percent int `json:"percent,omitempty" binding:"omitempty,min=1,max=100" example:"50"`
+	// Фиксированная скидка за расчетный период в валюте подписки
+	Amount *money.Money `json:"amount,omitempty"`
+	// Первый день действия скидки в формате ГГГГ-ММ-ДД или ММ-ГГГГ, по умолчанию дата начала подписки
+	StartDate string `json:"start_date,omitempty" example:"07-2025"`
+	// Срок действия скидки в месяцах
+	Months int `json:"months,omitempty" binding:"omitempty,min=1" example:"12"`
+	// Последний день действия скидки включительно в формате ГГГГ-ММ-ДД или ММ-ГГГГ
+	EndDate string `json:"end_date,omitempty" example:"06-2026"`
+}
+
+// @Description Участник совместной подписки, кроме владельца
+type Member struct {
+	// ID пользователя-участника
+	UserID uuid.UUID `json:"user_id" binding:"required" example:"2c1e4a6b-8d0f-4a2c-9e1b-3d5f7a9c1e2b"`
+	// Доля участника в процентах для правила percent
+	Percent int `json:"percent,omitempty" binding:"omitempty,min=1,max=100" example:"25"`
+	// Фиксированная сумма участника за расчетный период для правила fixed, в валюте подписки
+	Amount *money.Money `json:"amount,omitempty"`
+}
+
+// @Description Разделение стоимости подписки между владельцем и участниками. Владелец платит то, что остается после долей участников
+type Sharing struct {
+	// Правило разделения: equal — поровну, percent — в процентах, fixed — фиксированными суммами
+	SplitRule string `json:"split_rule" binding:"required,oneof=equal percent fixed" example:"equal"`
+	// Участники подписки без владельца; пустой список отменяет совместное использование
+	Members []Member `json:"members" binding:"dive"`
+}
+
+// @Description Параметры смены статуса подписки
+type LifecycleRequest struct {
+	// Дата вступления в силу в формате ГГГГ-ММ-ДД или ММ-ГГГГ, по умолчанию текущий месяц. Приостановки учитываются по месяцам
+	Date string `json:"date,omitempty" example:"03-2026"`
+}
+
+// @Description Запланированное изменение цены подписки
+type PriceChangeRequest struct {
+	// Новая цена за расчетный период в валюте подписки, больше нуля
+	Price money.Money `json:"price"`
+	// Первый месяц действия новой цены в формате ММ-ГГГГ или ГГГГ-ММ-ДД (учитывается месяц)
+	EffectiveFrom string `json:"effective_from" binding:"required" example:"01-2026"`
+}
+
+// @Description Цена подписки, действовавшая или действующая в диапазоне месяцев
+type PricePeriod struct {
+	// Первый месяц действия цены в формате ММ-ГГГГ
+	EffectiveFrom string `json:"effective_from" example:"07-2025"`
+	// Последний месяц действия цены в формате ММ-ГГГГ, пусто для последней цены
+	EffectiveTo string `json:"effective_to,omitempty" example:"12-2025"`
+	// Цена за расчетный период
+	Price money.Money `json:"price"`
+	// Цена еще не вступила в силу
+	Scheduled bool `json:"scheduled" example:"false"`
+}
+
+type PriceHistoryResponse struct {
+	ID     string        `json:"id" example:"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"`
+	Prices []PricePeriod `json:"prices"`
+}
+
+type StatusResponse struct {
+	ID     string `json:"id" example:"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"`
+	Status string `json:"status" example:"paused"`
+}
+
+// @Description Частичное обновление подписки (JSON Merge Patch): отсутствующие поля не меняются, null очищает поле
+type SubscriptionMergePatch struct {
+	// Название сервиса. Чтобы сменить сервис по названию, передайте также service_id: null
+	ServiceName *string `json:"service_name,omitempty" example:"Yandex Plus"`
+	// ID сервиса из каталога, null отвязывает подписку от каталога
+	ServiceID *string `json:"service_id,omitempty" example:"4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d"`
+	// Стоимость за один расчетный период в основных единицах валюты
+	Price *json.Number `json:"price,omitempty" swaggertype:"number" example:"399.9"`
+	// Код валюты ISO 4217
+	Currency *string `json:"currency,omitempty" example:"RUB"`
+	// Единица расчетного периода: week, month, quarter или year
+	BillingInterval *string `json:"billing_interval,omitempty" example:"month"`
+	// Количество единиц в расчетном периоде
+	BillingIntervalCount *int `json:"billing_interval_count,omitempty" example:"1"`
+	// Дата начала подписки в формате ГГГГ-ММ-ДД или ММ-ГГГГ
+	StartDate *string `json:"start_date,omitempty" example:"07-2025"`
+	// Дата окончания подписки в формате ГГГГ-ММ-ДД или ММ-ГГГГ, null снимает дату окончания
+	EndDate *string `json:"end_date,omitempty" example:"12-2025"`
+	// Последний день пробного периода в формате ГГГГ-ММ-ДД или ММ-ГГГГ, null снимает пробный период
+	TrialEnd *string `json:"trial_end,omitempty" example:"09-2025"`
+	// Категория подписки, null возвращает категорию сервиса из каталога
+	Category *string `json:"category,omitempty" example:"music"`
+	// Теги подписки, заменяют текущие целиком
+	Tags []string `json:"tags,omitempty" example:"family,work"`
+}
+
+// @Description Операция пакетной обработки подписок
+type BatchOperation struct {
+	// Тип операции: create, update или delete
+	Op string `json:"op" example:"create"`
+	// ID подписки, обязателен для update и delete
+	ID string `json:"id,omitempty" example:"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"`
+	// Опциональный ETag версии для update и delete
+	IfMatch string `json:"if_match,omitempty" example:"\"3\""`
+	// Данные подписки, обязательны для create и update
+	Subscription *Subscription `json:"subscription,omitempty"`
+}
+
+// @Description Пакет операций над подписками
+type BatchRequest struct {
+	// Выполнить все операции в одной транзакции: при первой ошибке изменения откатываются
+	Atomic bool `json:"atomic" example:"true"`
+	// Операции в порядке выполнения
+	Operations []BatchOperation `json:"operations" binding:"required,min=1"`
+}
+
+type BatchResult struct {
+	Index  int    `json:"index" example:"0"`
+	Op     string `json:"op" example:"create"`
+	ID     string `json:"id,omitempty" example:"d79c4c83-b0e4-4cc7-a6b1-3f2c5b8c9b76"`
+	Status int    `json:"status" example:"201"`
+	Error  string `json:"error,omitempty" example:"Subscription not found"`
+}
+
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded" example:"1"`
+	Failed    int           `json:"failed" example:"0"`
+}
+
+type ImportRowError struct {
+	// Номер строки файла (для CSV с учетом заголовка) или позиция в JSON-массиве
+	Row   int    `json:"row" example:"3"`
+	Field string `json:"field,omitempty" example:"price"`
+	Error string `json:"error" example:"price must be a positive amount in major units, e.g. 399.90"`
+}
+
+type ImportResponse struct {
+	DryRun  bool             `json:"dry_run" example:"true"`
+	Total   int              `json:"total" example:"120"`
+	Created int              `json:"created" example:"100"`
+	Updated int              `json:"updated" example:"20"`
+	Errors  []ImportRowError `json:"errors,omitempty"`
+}
+
+// @Description Сервис из каталога
+type Service struct {
+	ID string `json:"id" readonly:"true" example:"4a6b8c0d-2e4f-4a6b-8c0d-2e4f6a8b0c1d"`
+	// Каноническое название сервиса
+	Name string `json:"name" binding:"required,max=255" example:"Yandex Plus"`
+	// Другие написания названия, по которым подписки связываются с сервисом без учета регистра
+	Aliases []string `json:"aliases,omitempty" binding:"dive,required,max=255" example:"yandex plus,Яндекс Плюс"`
+	// Категория сервиса, используется для подписок без своей категории
+	Category string `json:"category,omitempty" binding:"max=255" example:"music"`
+	// Компания-поставщик
+	Vendor string `json:"vendor,omitempty" binding:"max=255" example:"Яндекс"`
+	// Сайт сервиса
+	Website string `json:"website,omitempty" binding:"omitempty,url,max=2048" example:"https://plus.yandex.ru"`
+	// Цена по умолчанию за расчетный период
+	DefaultPrice *money.Money `json:"default_price,omitempty"`
+}
+
+type ListServicesResponse struct {
+	Services []Service `json:"services"`
+	Count    int       `json:"count" example:"1"`
+}
+
+// @Description Пользователь
+type User struct {
+	// ID пользователя; при создании можно указать user_id уже существующих подписок
+	ID string `json:"id,omitempty" binding:"omitempty,uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	// Отображаемое имя
+	Name string `json:"name" binding:"required,max=255" example:"Иван Петров"`
+	// Адрес электронной почты, уникален без учета регистра
+	Email string `json:"email,omitempty" binding:"omitempty,email,max=255" example:"ivan@example.com"`
+	// Идентификатор пользователя во внешней системе, уникален
+	ExternalID string `json:"external_id,omitempty" binding:"max=255" example:"crm-10452"`
+	// Язык уведомлений: ru (по умолчанию) или en
+	Language string `json:"language,omitempty" binding:"omitempty,oneof=ru en" example:"ru"`
+}
+
+const (
+	LanguageRussian = "ru"
+	LanguageEnglish = "en"
+)
+
+type ListUsersResponse struct {
+	Users []User `json:"users"`
+	Count int    `json:"count" example:"1"`
+}
+
+type DeleteUserResponse struct {
+	ID string `json:"id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	// Количество удаленных или переданных подписок пользователя
+	Subscriptions int64 `json:"subscriptions" example:"3"`
+}
+
+// @Description Сводка расходов пользователя. Суммы учитывают только долю пользователя в совместных подписках
+type UserSummary struct {
+	UserID   string `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Currency string `json:"currency" example:"RUB"`
+	// Количество действующих подписок, включая совместные
+	ActiveSubscriptions int `json:"active_subscriptions" example:"4"`
+	// Расходы за текущий месяц
+	MonthlySpend money.Money `json:"monthly_spend"`
+	// Расходы за предыдущий месяц
+	PreviousMonthSpend money.Money `json:"previous_month_spend"`
+	// Изменение расходов текущего месяца по сравнению с предыдущим
+	MonthOverMonthChange money.Money `json:"month_over_month_change"`
+	// Изменение в процентах, отсутствует, если в предыдущем месяце расходов не было
+	MonthOverMonthPercent *float64 `json:"month_over_month_percent,omitempty" example:"12.5"`
+	// Расходы с начала года по текущий месяц включительно
+	YearToDateSpend money.Money `json:"year_to_date_spend"`
+	// Ближайшие списания по действующим подпискам в течение года
+	NextRenewals []Renewal `json:"next_renewals"`
+	// Самые дорогие сервисы по расходам текущего месяца
+	TopServices []ServiceCost `json:"top_services"`
+}
+
+type Renewal struct {
+	ServiceName string `json:"service_name" example:"Yandex Plus"`
+	// Дата списания в формате ГГГГ-ММ-ДД
+	Date string `json:"date" example:"2026-03-15"`
+	// Сумма списания с учетом пробного периода, скидок и доли пользователя
+	Price money.Money `json:"price"`
+}
+
+type ServiceCost struct {
+	ServiceName string      `json:"service_name" example:"Yandex Plus"`
+	Cost        money.Money `json:"cost"`
+}
+
+// @Description Бюджет на подписки пользователя или команды: общий, по категории или по сервису
+type Budget struct {
+	ID string `json:"id" readonly:"true" example:"7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20"`
+	// Название бюджета
+	Name string `json:"name" binding:"required,max=255" example:"Развлечения семьи"`
+	// Пользователи, расходы которых учитываются; несколько пользователей образуют командный бюджет
+	UserIDs []uuid.UUID `json:"user_ids" binding:"required,min=1,dive,required"`
+	// Период бюджета: month или year
+	Period string `json:"period" binding:"required,oneof=month year" example:"month"`
+	// Лимит расходов за период
+	Amount money.Money `json:"amount"`
+	// Категория подписок, пусто для всех категорий
+	Category string `json:"category,omitempty" binding:"max=255" example:"music"`
+	// Название сервиса, пусто для всех сервисов. Указывается не вместе с category
+	ServiceName string `json:"service_name,omitempty" binding:"max=255" example:"Yandex Plus"`
+}
+
+type ListBudgetsResponse struct {
+	Budgets []Budget `json:"budgets"`
+	Count   int      `json:"count" example:"1"`
+}
+
+const (
+	BudgetOK       = "ok"
+	BudgetWarning  = "warning"
+	BudgetExceeded = "exceeded"
+)
+
+// @Description Исполнение бюджета в текущем периоде
+type BudgetStatus struct {
+	Budget Budget `json:"budget"`
+	// Первый месяц текущего периода в формате ММ-ГГГГ
+	PeriodStart string `json:"period_start" example:"03-2026"`
+	// Последний месяц текущего периода в формате ММ-ГГГГ
+	PeriodEnd string `json:"period_end" example:"03-2026"`
+	// Расходы с начала периода по текущий месяц включительно
+	Spent money.Money `json:"spent"`
+	// Остаток лимита, отрицательный при перерасходе
+	Remaining money.Money `json:"remaining"`
+	// Израсходованная доля лимита в процентах
+	Percent float64 `json:"percent" example:"84.5"`
+	// Состояние: ok, warning (от 80%) или exceeded (от 100%)
+	State string `json:"state" example:"warning"`
+	// Оповещения текущего периода
+	Alerts []BudgetAlert `json:"alerts"`
+}
+
+// @Description Оповещение о достижении порога бюджета, создается один раз за период для каждого порога
+type BudgetAlert struct {
+	BudgetID string `json:"budget_id" example:"7e2d4f6a-8b0c-4d2e-9f1a-3b5c7d9e1f20"`
+	// Первый месяц периода в формате ММ-ГГГГ
+	PeriodStart string `json:"period_start" example:"03-2026"`
+	// Достигнутый порог в процентах: 80 или 100
+	Threshold int `json:"threshold" example:"80"`
+	// Расходы на момент достижения порога
+	Spent money.Money `json:"spent"`
+	// Время создания оповещения в формате RFC 3339
+	CreatedAt string `json:"created_at" example:"2026-03-14T09:30:00Z"`
+}
+
+type BudgetAlertsResponse struct {
+	Alerts []BudgetAlert `json:"alerts"`
+	Count  int           `json:"count" example:"1"`
+}
+
+const (
+	ReminderRenewal = "renewal"
+	ReminderExpiry  = "expiry"
+)
+
+// @Description Напоминание о предстоящем продлении или окончании подписки
+type Reminder struct {
+	ID     string    `json:"id" example:"3f5a7c9e-1b3d-4f5a-8c9e-1b3d5f7a9c0e"`
+	UserID uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	SubID  string    `json:"sub_id" example:"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"`
+	// Название сервиса
+	ServiceName string `json:"service_name" example:"Yandex Plus"`
+	// Тип напоминания: renewal — продление, expiry — окончание
+	Kind string `json:"kind" example:"renewal"`
+	// Дата списания или последний день подписки в формате ГГГГ-ММ-ДД
+	Date string `json:"date" example:"2026-03-15"`
+	// Сумма списания для напоминания о продлении
+	Price *money.Money `json:"price,omitempty"`
+	// Время создания напоминания в формате RFC 3339
+	CreatedAt string `json:"created_at" example:"2026-03-12T06:00:00Z"`
+}
+
+type ListRemindersResponse struct {
+	Reminders []Reminder `json:"reminders"`
+	Count     int        `json:"count" example:"1"`
+}
+
+// @Description Настройки напоминаний пользователя
+type ReminderPreferences struct {
+	// Напоминать о продлении подписок
+	Renewals bool `json:"renewals" example:"true"`
+	// Напоминать об окончании подписок
+	Expiries bool `json:"expiries" example:"true"`
+	// За сколько дней напоминать, 0 — значение по умолчанию сервиса
+	DaysBefore int `json:"days_before" binding:"min=0,max=60" example:"3"`
+}
+
+const (
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliverySkipped = "skipped"
+)
+
+// @Description Запись журнала доставки уведомления
+type Delivery struct {
+	ID int64 `json:"id" example:"42"`
+	// Канал доставки: email или webhook
+	Channel string `json:"channel" example:"email"`
+	// Событие: reminder.renewal, reminder.expiry, budget.alert, subscription.created, subscription.updated, subscription.deleted
+	Event string `json:"event" example:"reminder.renewal"`
+	// ID события подписки, о котором уведомление, для событий subscription.*
+	EventID string    `json:"event_id,omitempty" example:"5d7f9b1c-3e5a-4c7e-9f1b-3d5f7a9c1e3a"`
+	UserID  uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	// Адрес получателя в канале
+	Recipient string `json:"recipient,omitempty" example:"ivan@example.com"`
+	// Тема уведомления
+	Subject string `json:"subject" example:"Скоро продление подписки Yandex Plus"`
+	// Результат: sent — доставлено, failed — не доставлено после всех попыток, skipped — у пользователя нет адреса в канале
+	Status string `json:"status" example:"sent"`
+	// Число попыток отправки
+	Attempts int `json:"attempts" example:"1"`
+	// Ошибка последней попытки
+	Error string `json:"error,omitempty" example:""`
+	// Время записи в формате RFC 3339
+	CreatedAt string `json:"created_at" example:"2026-03-12T09:00:00Z"`
+}
+
+type ListDeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+	Count      int        `json:"count" example:"1"`
+}
+
+// Subscription events, recorded with every change and delivered to webhooks
+// and the other event sinks.
+const (
+	EventSubscriptionCreated = "subscription.created"
+	EventSubscriptionUpdated = "subscription.updated"
+	EventSubscriptionDeleted = "subscription.deleted"
+	EventSubscriptionExpired = "subscription.expired"
+)
+
+// @Description Адрес, на который отправляются события об изменении подписок
+type WebhookEndpoint struct {
+	ID string `json:"id" readonly:"true" example:"2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f"`
+	// HTTP(S) URL, принимающий POST-запросы с событиями
+	URL string `json:"url" binding:"required,http_url,max=2048" example:"https://crm.example.com/hooks/subscriptions"`
+	// Типы событий: subscription.created, subscription.updated, subscription.deleted, subscription.expired. Пусто — все события
+	Events []string `json:"events,omitempty" binding:"dive,oneof=subscription.created subscription.updated subscription.deleted subscription.expired" example:"subscription.created,subscription.deleted"`
+	// Временно не отправлять события
+	Disabled bool `json:"disabled,omitempty" example:"false"`
+	// Секрет для проверки подписи HMAC-SHA256, возвращается только при регистрации
+	Secret string `json:"secret,omitempty" readonly:"true" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// Время регистрации в формате RFC 3339
+	CreatedAt string `json:"created_at" readonly:"true" example:"2026-03-01T10:00:00Z"`
+}
+
+type ListWebhooksResponse struct {
+	Webhooks []WebhookEndpoint `json:"webhooks"`
+	Count    int               `json:"count" example:"1"`
+}
+
+// @Description Событие об изменении подписки, тело запроса к вебхуку и сообщения в остальные получатели событий
+type SubscriptionEvent struct {
+	ID string `json:"id" example:"5d7f9b1c-3e5a-4c7e-9f1b-3d5f7a9c1e3a"`
+	// Тип события
+	Type  string `json:"type" example:"subscription.created"`
+	SubID string `json:"sub_id" example:"8d05c8f6-8a7e-4e07-8dc6-07e1b7bafef0"`
+	// Время события в формате RFC 3339
+	CreatedAt string `json:"created_at" example:"2026-03-01T10:00:00Z"`
+	// Подписка после изменения; для удаления — до него
+	Data *Subscription `json:"data,omitempty"`
+}
+
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead"
+)
+
+// @Description Доставка события на вебхук
+type WebhookDelivery struct {
+	ID         string `json:"id" example:"7a9c1e3f-5b7d-4f9a-8c1e-3f5b7d9f1a3c"`
+	EndpointID string `json:"endpoint_id" example:"2c4e6a8b-0d2f-4e6a-8b0d-2f4e6a8b0d2f"`
+	// Состояние: pending — ожидает отправки, delivered — доставлено, dead — не доставлено после всех попыток
+	Status string `json:"status" example:"dead"`
+	// Число выполненных попыток
+	Attempts int `json:"attempts" example:"8"`
+	// Время следующей попытки для ожидающих доставок в формате RFC 3339
+	NextAttemptAt string `json:"next_attempt_at,omitempty" example:"2026-03-01T10:05:00Z"`
+	// HTTP-статус последнего ответа
+	LastStatusCode int `json:"last_status_code,omitempty" example:"503"`
+	// Ошибка последней попытки
+	LastError string `json:"last_error,omitempty" example:"webhook responded with status 503"`
+	// Время создания в формате RFC 3339
+	CreatedAt string `json:"created_at" example:"2026-03-01T10:00:00Z"`
+	// Время доставки в формате RFC 3339
+	DeliveredAt string `json:"delivered_at,omitempty" example:""`
+	// Отправляемое событие
+	Event SubscriptionEvent `json:"event"`
+}
+
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Count      int               `json:"count" example:"1"`
+}
+
+// CalendarEntry is a subscription as it appears in a user's iCalendar feed.
+type CalendarEntry struct {
+	SubID                uuid.UUID
+	ServiceName          string
+	Price                money.Money
+	BillingInterval      string
+	BillingIntervalCount int
+	StartDate            time.Time
+	EndDate              *time.Time
+	// Pauses are the paused months; no renewals fall in them.
+	Pauses []billing.Range
+}
+
+type CalendarTokenResponse struct {
+	// Секретный токен ленты, показывается только при выпуске
+	Token string `json:"token" example:"3f1c0d9e5b7a4c2e8f6a1b3d5c7e9f0a2b4c6d8e0f1a3b5c7d9e1f3a5b7c9d1e"`
+	// Адрес ленты для подписки в календаре
+	URL string `json:"url" example:"http://localhost:8080/api/calendar/3f1c0d9e5b7a4c2e8f6a1b3d5c7e9f0a2b4c6d8e0f1a3b5c7d9e1f3a5b7c9d1e.ics"`
 }
 
 type ErrorResponse struct {
@@ -29,7 +569,57 @@ type CreatedResponse struct {
 }
 
 type TotalCostResponse struct {
-	TotalCost int `json:"total_cost" example:"2997"`
+	// Общая стоимость в основных единицах валюты
+	TotalCost json.Number `json:"total_cost" swaggertype:"number" example:"2997.5"`
+	// Валюта расчета, код ISO 4217
+	Currency string `json:"currency" example:"RUB"`
+	// Стоимость по категориям, если указан group_by=category
+	ByCategory []CategoryCost `json:"by_category,omitempty"`
+}
+
+// @Description Стоимость подписок одной категории и ее доля в общей стоимости
+type CategoryCost struct {
+	// Категория, пусто для подписок без категории
+	Category string `json:"category" example:"dev tools"`
+	// Стоимость в валюте расчета
+	Cost json.Number `json:"cost" swaggertype:"number" example:"1263.5"`
+	// Доля в общей стоимости от 0 до 1
+	Share float64 `json:"share" example:"0.4215"`
+}
+
+// @Description Вклад подписки в общую стоимость за период
+type CostItem struct {
+	ServiceName          string      `json:"service_name" example:"Yandex Plus"`
+	Category             string      `json:"category,omitempty" example:"music"`
+	Tags                 []string    `json:"tags,omitempty" example:"family"`
+	UserID               uuid.UUID   `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate            string      `json:"start_date" example:"07-2025"`
+	EndDate              string      `json:"end_date,omitempty" example:"12-2025"`
+	Price                money.Money `json:"price"`
+	BillingInterval      string      `json:"billing_interval" example:"month"`
+	BillingIntervalCount int         `json:"billing_interval_count" example:"1"`
+	Months               int         `json:"months" example:"6"`
+	Charges              int         `json:"charges" example:"6"`
+	Cost                 money.Money `json:"cost"`
+}
+
+// @Description Курс валюты к рублю, действующий с указанной даты до следующего курса
+type ExchangeRate struct {
+	// Код валюты ISO 4217
+	Currency string `json:"currency" example:"USD"`
+	// Дата начала действия курса в формате ГГГГ-ММ-ДД или ММ-ГГГГ
+	Date string `json:"date" example:"2025-07-01"`
+	// Стоимость единицы валюты в рублях
+	Rate json.Number `json:"rate" swaggertype:"number" example:"78.45"`
+}
+
+type ExchangeRatesResponse struct {
+	Rates []ExchangeRate `json:"rates"`
+	Count int            `json:"count" example:"1"`
+}
+
+type LoadRatesResponse struct {
+	Loaded int `json:"loaded" example:"12"`
 }
 
 type ListSubscriptionsResponse struct {
@@ -45,6 +635,22 @@ type NotFoundErrorResponse struct {
 	Error string `json:"error" example:"Subscription not found"`
 }
 
+type PreconditionFailedErrorResponse struct {
+	Error string `json:"error" example:"Subscription was modified by another request"`
+}
+
+type InvalidPatchErrorResponse struct {
+	Error string `json:"error" example:"Invalid patch document"`
+}
+
+type PatchTestFailedErrorResponse struct {
+	Error string `json:"error" example:"Patch test operation failed"`
+}
+
+type UnsupportedPatchTypeErrorResponse struct {
+	Error string `json:"error" example:"Unsupported patch content type"`
+}
+
 type InvalidRequestBodyErrorResponse struct {
 	Error string `json:"error" example:"Invalid request body"`
 }
@@ -57,6 +663,142 @@ type PeriodStartRequiredErrorResponse struct {
 	Error string `json:"error" example:"period_start are required"`
 }
 
+type InvalidIdempotencyKeyErrorResponse struct {
+	Error string `json:"error" example:"Invalid Idempotency-Key header"`
+}
+
+type IdempotencyConflictErrorResponse struct {
+	Error string `json:"error" example:"Idempotency-Key already used with a different request"`
+}
+
+type CurrencyChangeErrorResponse struct {
+	Error string `json:"error" example:"Currency of a subscription with billing history cannot change"`
+}
+
+type CancelledEndDateErrorResponse struct {
+	Error string `json:"error" example:"Cancelled subscription must keep an end date, reactivate it instead"`
+}
+
+type BatchTooLargeErrorResponse struct {
+	Error string `json:"error" example:"Batch exceeds maximum size of 100 operations"`
+}
+
+type InvalidImportFileErrorResponse struct {
+	Error string `json:"error" example:"Invalid import file"`
+}
+
+type InvalidColumnMappingErrorResponse struct {
+	Error string `json:"error" example:"Invalid column mapping"`
+}
+
+type UnsupportedFormatErrorResponse struct {
+	Error string `json:"error" example:"Unsupported format"`
+}
+
+type CalendarNotFoundErrorResponse struct {
+	Error string `json:"error" example:"Calendar not found"`
+}
+
+type InvalidTransitionErrorResponse struct {
+	Error string `json:"error" example:"Transition not allowed from status cancelled"`
+}
+
+type InvalidDateErrorResponse struct {
+	Error string `json:"error" example:"Invalid date"`
+}
+
+type InvalidGroupByErrorResponse struct {
+	Error string `json:"error" example:"Invalid group_by"`
+}
+
+type InvalidViewErrorResponse struct {
+	Error string `json:"error" example:"Invalid view"`
+}
+
+type CurrencyMismatchErrorResponse struct {
+	Error string `json:"error" example:"Price currency must match subscription currency"`
+}
+
+type PriceChangeNotFoundErrorResponse struct {
+	Error string `json:"error" example:"Price change not found"`
+}
+
+type UnknownServiceErrorResponse struct {
+	Error string `json:"error" example:"Unknown service_id"`
+}
+
+type UnknownUserErrorResponse struct {
+	Error string `json:"error" example:"Unknown user_id"`
+}
+
+type InvalidDeliveryStatusErrorResponse struct {
+	Error string `json:"error" example:"Invalid status"`
+}
+
+type WebhookNotFoundErrorResponse struct {
+	Error string `json:"error" example:"Webhook not found"`
+}
+
+type WebhookDeliveryNotFoundErrorResponse struct {
+	Error string `json:"error" example:"Webhook delivery not found"`
+}
+
+type UserNotFoundErrorResponse struct {
+	Error string `json:"error" example:"User not found"`
+}
+
+type UserConflictErrorResponse struct {
+	Error string `json:"error" example:"User ID, email or external_id is already used by another user"`
+}
+
+type UserHasSubscriptionsErrorResponse struct {
+	Error string `json:"error" example:"User still owns subscriptions"`
+}
+
+type InvalidUserDeleteErrorResponse struct {
+	Error string `json:"error" example:"Invalid subscriptions or transfer_to parameter"`
+}
+
+type BudgetNotFoundErrorResponse struct {
+	Error string `json:"error" example:"Budget not found"`
+}
+
+type InvalidBudgetErrorResponse struct {
+	Error string `json:"error" example:"Invalid budget"`
+}
+
+type ServiceNotFoundErrorResponse struct {
+	Error string `json:"error" example:"Service not found"`
+}
+
+type ServiceConflictErrorResponse struct {
+	Error string `json:"error" example:"Service name or alias is already used by another service"`
+}
+
+type InvalidSharingErrorResponse struct {
+	Error string `json:"error" example:"Invalid sharing"`
+}
+
+type InvalidDiscountErrorResponse struct {
+	Error string `json:"error" example:"Invalid discount"`
+}
+
+type DiscountNotFoundErrorResponse struct {
+	Error string `json:"error" example:"Discount not found"`
+}
+
+type InvalidCurrencyErrorResponse struct {
+	Error string `json:"error" example:"Invalid currency"`
+}
+
+type MissingExchangeRateErrorResponse struct {
+	Error string `json:"error" example:"Exchange rate not found"`
+}
+
+type InvalidRatesFileErrorResponse struct {
+	Error string `json:"error" example:"Invalid exchange rates"`
+}
+
 type InvalidUserIDErrorResponse struct {
 	Error string `json:"error" example:"Invalid user_id format"`
 }
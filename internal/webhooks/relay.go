@@ -0,0 +1,203 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Headers sent with every event. The signature is "sha256=" followed by the
+// hex HMAC-SHA256 of the timestamp, a dot and the body, keyed with the
+// endpoint's secret.
+const (
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// maxErrorBody caps how much of a failed response is kept as its error.
+const maxErrorBody = 256
+
+// Store is what the relay needs from the repository: the queued deliveries.
+type Store interface {
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]storage.WebhookJob, error)
+	RecordWebhookAttempt(id uuid.UUID, attempt storage.WebhookAttempt) error
+}
+
+// Relay sends queued webhook deliveries. Failed deliveries are retried with
+// exponential backoff and given up on after MaxAttempts, which leaves them
+// in the dead letters until they are redelivered.
+type Relay struct {
+	Repo   Store
+	Client *http.Client
+	// Interval is how often due deliveries are looked for.
+	Interval  time.Duration
+	BatchSize int
+	// MaxAttempts is how many times a delivery is tried before it is dead.
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles with every
+	// retry after that, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Run sends deliveries until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	logger.Logger.Infow("Webhook relay started",
+		"interval", r.Interval,
+		"maxAttempts", r.MaxAttempts,
+	)
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("Webhook relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the deliveries that are due, a batch at a time, until none
+// are left.
+func (r *Relay) RunOnce(ctx context.Context) {
+	for ctx.Err() == nil {
+		// Sending stops before the lease runs out, so that no other worker
+		// claims a delivery while it is still being sent here.
+		lease := r.lease()
+		deadline := time.Now().Add(lease * 9 / 10)
+		jobs, err := r.Repo.ClaimWebhookDeliveries(r.BatchSize, lease)
+		if err != nil {
+			logger.Logger.Errorw("Failed to claim webhook deliveries",
+				"error", err,
+			)
+			return
+		}
+
+		batch, cancel := context.WithDeadline(ctx, deadline)
+		for i, job := range jobs {
+			if batch.Err() != nil {
+				logger.Logger.Warnw("Webhook lease ran out before the batch was sent",
+					"left", len(jobs)-i,
+					"lease", lease,
+				)
+				break
+			}
+			r.deliver(batch, job)
+		}
+		cancel()
+		if len(jobs) < r.BatchSize {
+			return
+		}
+	}
+}
+
+// lease is how long a claimed batch is hidden from other workers: long enough
+// for a request to time out. A batch is sent within nine tenths of it; the
+// deliveries it leaves are sent once the lease runs out.
+func (r *Relay) lease() time.Duration {
+	if r.Client != nil && r.Client.Timeout > 0 {
+		return 2 * r.Client.Timeout
+	}
+	return time.Minute
+}
+
+func (r *Relay) deliver(ctx context.Context, job storage.WebhookJob) {
+	attempt := r.send(ctx, job)
+	if !attempt.Delivered && job.Attempts+1 < r.MaxAttempts {
+		attempt.RetryIn = r.backoff(job.Attempts)
+	}
+
+	if attempt.Delivered {
+		logger.Logger.Infow("Webhook delivered",
+			"deliveryID", job.DeliveryID,
+			"event", job.EventType,
+			"statusCode", attempt.StatusCode,
+		)
+	} else {
+		logger.Logger.Warnw("Webhook delivery attempt failed",
+			"deliveryID", job.DeliveryID,
+			"event", job.EventType,
+			"attempt", job.Attempts+1,
+			"statusCode", attempt.StatusCode,
+			"error", attempt.Error,
+			"retryIn", attempt.RetryIn,
+		)
+	}
+
+	if err := r.Repo.RecordWebhookAttempt(job.DeliveryID, attempt); err != nil {
+		logger.Logger.Errorw("Failed to record webhook attempt",
+			"error", err,
+			"deliveryID", job.DeliveryID,
+		)
+	}
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.Backoff
+	for i := 0; i < attempts && wait < r.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, r.MaxBackoff)
+}
+
+func (r *Relay) send(ctx context.Context, job storage.WebhookJob) storage.WebhookAttempt {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(job.Payload))
+	if err != nil {
+		return storage.WebhookAttempt{Error: fmt.Sprintf("invalid webhook request: %v", err)}
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, job.EventID.String())
+	req.Header.Set(HeaderEvent, job.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(job.Secret, timestamp, job.Payload))
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return storage.WebhookAttempt{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	attempt := storage.WebhookAttempt{StatusCode: resp.StatusCode}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		attempt.Delivered = true
+		return attempt
+	}
+	attempt.Error = fmt.Sprintf("webhook responded with status %d", resp.StatusCode)
+	if len(body) > 0 {
+		attempt.Error += ": " + string(body)
+	}
+	return attempt
+}
+
+// Sign returns the signature header value for body sent at timestamp, in
+// seconds since the Unix epoch.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,175 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/ItserX/rest/internal/logger"
+	"github.com/ItserX/rest/internal/storage"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop().Sugar()
+	m.Run()
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"subscription.created"}`)
+
+	// HMAC-SHA256 of "1767225600.<body>" keyed with "s3cr3t".
+	want := "sha256=addd0e141cedd9eac799a88145f146423f5654806e3cecc3f1835225cafac8de"
+	if got := Sign("s3cr3t", 1767225600, body); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+
+	if Sign("s3cr3t", 1767225601, body) == want {
+		t.Error("Sign() ignores the timestamp")
+	}
+	if Sign("other", 1767225600, body) == want {
+		t.Error("Sign() ignores the secret")
+	}
+	if Sign("s3cr3t", 1767225600, []byte(`{"type":"subscription.deleted"}`)) == want {
+		t.Error("Sign() ignores the body")
+	}
+}
+
+type recordedAttempt struct {
+	id      uuid.UUID
+	attempt storage.WebhookAttempt
+}
+
+// fakeStore hands out its queued deliveries in order, a batch per claim.
+type fakeStore struct {
+	queued   []storage.WebhookJob
+	attempts []recordedAttempt
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(limit int, _ time.Duration) ([]storage.WebhookJob, error) {
+	n := min(limit, len(s.queued))
+	jobs := s.queued[:n]
+	s.queued = s.queued[n:]
+	return jobs, nil
+}
+
+func (s *fakeStore) RecordWebhookAttempt(id uuid.UUID, attempt storage.WebhookAttempt) error {
+	s.attempts = append(s.attempts, recordedAttempt{id: id, attempt: attempt})
+	return nil
+}
+
+func testJobs(url string, n, attempts int) []storage.WebhookJob {
+	jobs := make([]storage.WebhookJob, n)
+	for i := range jobs {
+		jobs[i] = storage.WebhookJob{
+			DeliveryID: uuid.New(),
+			Attempts:   attempts,
+			URL:        url,
+			Secret:     "s3cr3t",
+			EventID:    uuid.New(),
+			EventType:  "subscription.created",
+			Payload:    []byte(`{"type":"subscription.created"}`),
+		}
+	}
+	return jobs
+}
+
+func TestRelayDelivers(t *testing.T) {
+	var signed int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if r.Header.Get(HeaderSignature) == Sign("s3cr3t", timestamp, body) {
+			signed++
+		}
+	}))
+	defer server.Close()
+
+	store := &fakeStore{queued: testJobs(server.URL, 3, 0)}
+	r := &Relay{Repo: store, Client: server.Client(), BatchSize: 2, MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute}
+
+	r.RunOnce(context.Background())
+
+	if signed != 3 {
+		t.Errorf("server got %d signed requests, want 3", signed)
+	}
+	if len(store.attempts) != 3 {
+		t.Fatalf("recorded %d attempts, want 3", len(store.attempts))
+	}
+	for _, recorded := range store.attempts {
+		if !recorded.attempt.Delivered || recorded.attempt.StatusCode != http.StatusOK {
+			t.Errorf("attempt = %+v, want delivered", recorded.attempt)
+		}
+	}
+}
+
+func TestRelayRetriesUntilMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		attempts  int
+		wantRetry time.Duration
+	}{
+		{name: "first failure", attempts: 0, wantRetry: time.Second},
+		{name: "later failure", attempts: 2, wantRetry: 4 * time.Second},
+		// The last attempt gets no retry, which leaves it in the dead letters.
+		{name: "last attempt", attempts: 7, wantRetry: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{queued: testJobs(server.URL, 1, tt.attempts)}
+			r := &Relay{Repo: store, Client: server.Client(), BatchSize: 10, MaxAttempts: 8, Backoff: time.Second, MaxBackoff: time.Minute}
+
+			r.RunOnce(context.Background())
+
+			if len(store.attempts) != 1 {
+				t.Fatalf("recorded %d attempts, want 1", len(store.attempts))
+			}
+			attempt := store.attempts[0].attempt
+			if attempt.Delivered || attempt.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("attempt = %+v, want a failed one with status 503", attempt)
+			}
+			if attempt.RetryIn != tt.wantRetry {
+				t.Errorf("retry in %v, want %v", attempt.RetryIn, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestRelayStopsBeforeLeaseRunsOut(t *testing.T) {
+	// Every request hangs until the client gives up on it.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := server.Client()
+	client.Timeout = 200 * time.Millisecond
+	store := &fakeStore{queued: testJobs(server.URL, 5, 0)}
+	r := &Relay{Repo: store, Client: client, BatchSize: 10, MaxAttempts: 8, Backoff: time.Second, MaxBackoff: time.Minute}
+
+	start := time.Now()
+	r.RunOnce(context.Background())
+
+	// The first request times out after 200ms and the second is cut off at
+	// nine tenths of the 400ms lease; the rest keep their claim.
+	if len(store.attempts) != 2 {
+		t.Errorf("recorded %d attempts, want 2", len(store.attempts))
+	}
+	if elapsed := time.Since(start); elapsed >= 2*client.Timeout {
+		t.Errorf("RunOnce() took %v, want less than the %v lease", elapsed, 2*client.Timeout)
+	}
+}
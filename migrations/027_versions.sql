@@ -0,0 +1,2 @@
+-- Record versions behind ETag and If-Match. Existing subscriptions start at 1.
+ALTER TABLE subscriptions ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+-- Imports upsert subscriptions by user, service name and start date.
+CREATE INDEX subscriptions_natural_key_idx ON subscriptions (user_id, service_name, start_date);
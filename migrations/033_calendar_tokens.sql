@@ -0,0 +1,6 @@
+-- Hashes of the tokens behind the per-user calendar feeds.
+CREATE TABLE calendar_tokens (
+    user_id UUID PRIMARY KEY,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
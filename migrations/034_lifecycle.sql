@@ -0,0 +1,17 @@
+-- Lifecycle status and pauses. Existing subscriptions are active; expired
+-- ones are told apart by their end date.
+BEGIN;
+
+ALTER TABLE subscriptions
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled'));
+
+CREATE TABLE subscription_pauses (
+    pause_id BIGSERIAL PRIMARY KEY,
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    start_month TIMESTAMP NOT NULL,
+    end_month TIMESTAMP
+);
+
+CREATE INDEX subscription_pauses_sub_id_idx ON subscription_pauses (sub_id);
+
+COMMIT;
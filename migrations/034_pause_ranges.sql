@@ -0,0 +1,5 @@
+-- Pauses closed before the month they started never took effect.
+DELETE FROM subscription_pauses WHERE end_month < start_month;
+
+ALTER TABLE subscription_pauses
+    ADD CONSTRAINT subscription_pauses_end_month_check CHECK (end_month >= start_month);
@@ -0,0 +1,4 @@
+-- Billing cycles. Existing subscriptions are billed every month.
+ALTER TABLE subscriptions
+    ADD COLUMN billing_interval VARCHAR(16) NOT NULL DEFAULT 'month' CHECK (billing_interval IN ('week', 'month', 'quarter', 'year')),
+    ADD COLUMN billing_interval_count INTEGER NOT NULL DEFAULT 1 CHECK (billing_interval_count > 0);
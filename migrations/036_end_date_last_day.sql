@@ -0,0 +1,5 @@
+-- End dates were stored as the first day of the last month. They are the last
+-- day of the subscription now, so a legacy "03-2025" becomes 2025-03-31.
+UPDATE subscriptions
+SET end_date = end_date + interval '1 month' - interval '1 day'
+WHERE end_date IS NOT NULL AND end_date = date_trunc('month', end_date);
@@ -0,0 +1,13 @@
+-- Currencies and exchange rates. Existing prices are in rubles.
+BEGIN;
+
+ALTER TABLE subscriptions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+CREATE TABLE exchange_rates (
+    currency CHAR(3) NOT NULL,
+    effective_date DATE NOT NULL,
+    rate NUMERIC(18, 6) NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (currency, effective_date)
+);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Prices were whole major units. They are kept in minor units now, so they
+-- are scaled by the number of minor units in a major unit of their currency,
+-- as listed in internal/money.
+BEGIN;
+
+ALTER TABLE subscriptions RENAME COLUMN price TO price_minor;
+ALTER TABLE subscriptions ALTER COLUMN price_minor TYPE BIGINT;
+
+UPDATE subscriptions
+SET price_minor = price_minor * CASE
+    WHEN currency IN ('CLP', 'ISK', 'JPY', 'KRW', 'VND') THEN 1
+    WHEN currency IN ('BHD', 'JOD', 'KWD', 'OMR', 'TND') THEN 1000
+    ELSE 100
+END;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_price_minor_check CHECK (price_minor >= 0);
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Price changes. Without one a subscription keeps its initial price.
+CREATE TABLE subscription_prices (
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    effective_month TIMESTAMP NOT NULL,
+    price_minor BIGINT NOT NULL CHECK (price_minor >= 0),
+    PRIMARY KEY (sub_id, effective_month)
+);
@@ -0,0 +1,18 @@
+-- Free trials and discount periods.
+BEGIN;
+
+ALTER TABLE subscriptions ADD COLUMN trial_end TIMESTAMP;
+
+CREATE TABLE subscription_discounts (
+    discount_id UUID PRIMARY KEY,
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    percent SMALLINT CHECK (percent BETWEEN 1 AND 100),
+    amount_minor BIGINT CHECK (amount_minor > 0),
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP,
+    CHECK ((percent IS NULL) <> (amount_minor IS NULL))
+);
+
+CREATE INDEX subscription_discounts_sub_id_idx ON subscription_discounts (sub_id);
+
+COMMIT;
@@ -0,0 +1,26 @@
+-- Service catalog. Existing subscriptions keep their free-form service names
+-- and are not linked to catalog entries.
+BEGIN;
+
+CREATE TABLE services (
+    service_id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(255),
+    vendor VARCHAR(255),
+    website VARCHAR(2048),
+    default_price_minor BIGINT CHECK (default_price_minor >= 0),
+    default_currency CHAR(3),
+    CHECK ((default_price_minor IS NULL) = (default_currency IS NULL))
+);
+
+CREATE TABLE service_aliases (
+    alias_key VARCHAR(255) PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services (service_id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX service_aliases_service_id_idx ON service_aliases (service_id);
+
+ALTER TABLE subscriptions ADD COLUMN service_id UUID REFERENCES services (service_id) ON DELETE SET NULL;
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Categories and tags.
+BEGIN;
+
+ALTER TABLE subscriptions
+    ADD COLUMN category VARCHAR(255),
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX subscriptions_tags_idx ON subscriptions USING GIN (tags);
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Shared subscriptions. Existing subscriptions have no members and are paid
+-- by their owners alone.
+BEGIN;
+
+ALTER TABLE subscriptions
+    ADD COLUMN split_rule VARCHAR(16) NOT NULL DEFAULT 'equal' CHECK (split_rule IN ('equal', 'percent', 'fixed'));
+
+CREATE TABLE subscription_members (
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    share BIGINT NOT NULL DEFAULT 0 CHECK (share >= 0),
+    PRIMARY KEY (sub_id, user_id)
+);
+
+CREATE INDEX subscription_members_user_id_idx ON subscription_members (user_id);
+
+COMMIT;
@@ -0,0 +1,33 @@
+-- Subscriptions, members and calendar tokens reference users now. Every user
+-- ID they already mention becomes a user named after the ID, so the foreign
+-- keys can be added; names and emails can be set through /api/users later.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS users (
+    user_id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE,
+    external_id VARCHAR(255) UNIQUE
+);
+
+INSERT INTO users (user_id, name)
+SELECT DISTINCT user_id, user_id::text FROM subscriptions
+UNION
+SELECT DISTINCT user_id, user_id::text FROM subscription_members
+UNION
+SELECT DISTINCT user_id, user_id::text FROM calendar_tokens
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS subscriptions_user_id_fkey,
+    ADD CONSTRAINT subscriptions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id);
+
+ALTER TABLE subscription_members
+    DROP CONSTRAINT IF EXISTS subscription_members_user_id_fkey,
+    ADD CONSTRAINT subscription_members_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE calendar_tokens
+    DROP CONSTRAINT IF EXISTS calendar_tokens_user_id_fkey,
+    ADD CONSTRAINT calendar_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+COMMIT;
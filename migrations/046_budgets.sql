@@ -0,0 +1,34 @@
+-- Budgets and the threshold alerts raised for them.
+BEGIN;
+
+CREATE TABLE budgets (
+    budget_id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    period VARCHAR(16) NOT NULL CHECK (period IN ('month', 'year')),
+    amount_minor BIGINT NOT NULL CHECK (amount_minor > 0),
+    currency CHAR(3) NOT NULL,
+    category VARCHAR(255),
+    service_name VARCHAR(255),
+    CHECK (category IS NULL OR service_name IS NULL)
+);
+
+CREATE TABLE budget_users (
+    budget_id UUID NOT NULL REFERENCES budgets (budget_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    PRIMARY KEY (budget_id, user_id)
+);
+
+CREATE INDEX budget_users_user_id_idx ON budget_users (user_id);
+
+CREATE TABLE budget_alerts (
+    alert_id BIGSERIAL PRIMARY KEY,
+    budget_id UUID NOT NULL REFERENCES budgets (budget_id) ON DELETE CASCADE,
+    period_start TIMESTAMP NOT NULL,
+    threshold SMALLINT NOT NULL,
+    spent_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (budget_id, period_start, threshold)
+);
+
+COMMIT;
@@ -0,0 +1,26 @@
+-- Reminder preferences and the reminders already sent, so that a reminder is
+-- not sent twice. Users without preferences get every reminder.
+BEGIN;
+
+CREATE TABLE reminder_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    renewals BOOLEAN NOT NULL DEFAULT TRUE,
+    expiries BOOLEAN NOT NULL DEFAULT TRUE,
+    days_before SMALLINT CHECK (days_before > 0)
+);
+
+CREATE TABLE reminders (
+    reminder_id UUID PRIMARY KEY,
+    sub_id UUID NOT NULL REFERENCES subscriptions (sub_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('renewal', 'expiry')),
+    due_date TIMESTAMP NOT NULL,
+    price_minor BIGINT,
+    currency CHAR(3),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (sub_id, kind, due_date)
+);
+
+CREATE INDEX reminders_user_id_idx ON reminders (user_id, created_at);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Budget alerts remember whether their users were notified, so that an alert
+-- is sent again if the service stops before sending it. Alerts raised before
+-- count as sent.
+BEGIN;
+
+ALTER TABLE budget_alerts
+    ADD COLUMN notify_after TIMESTAMP NOT NULL DEFAULT NOW(),
+    ADD COLUMN notified_at TIMESTAMP;
+
+UPDATE budget_alerts SET notified_at = created_at;
+
+CREATE INDEX budget_alerts_pending_idx ON budget_alerts (notify_after) WHERE notified_at IS NULL;
+
+COMMIT;
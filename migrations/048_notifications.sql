@@ -0,0 +1,23 @@
+-- Notification languages and the delivery log. Existing users get Russian
+-- notifications.
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN language VARCHAR(2) NOT NULL DEFAULT 'ru' CHECK (language IN ('ru', 'en'));
+
+CREATE TABLE notification_deliveries (
+    delivery_id BIGSERIAL PRIMARY KEY,
+    channel VARCHAR(32) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    user_id UUID,
+    recipient TEXT,
+    subject TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('sent', 'failed', 'skipped')),
+    attempts INTEGER NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX notification_deliveries_user_id_idx ON notification_deliveries (user_id, created_at);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Reminders remember whether their users were notified, so that a reminder is
+-- sent again if the service stops before sending it or no channel delivers
+-- it. Reminders created before count as sent.
+BEGIN;
+
+ALTER TABLE reminders
+    ADD COLUMN notify_after TIMESTAMP NOT NULL DEFAULT NOW(),
+    ADD COLUMN notified_at TIMESTAMP;
+
+UPDATE reminders SET notified_at = created_at;
+
+CREATE INDEX reminders_pending_idx ON reminders (notify_after) WHERE notified_at IS NULL;
+
+COMMIT;
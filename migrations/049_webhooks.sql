@@ -0,0 +1,38 @@
+-- Webhook endpoints, the events sent to them and their deliveries.
+BEGIN;
+
+CREATE TABLE webhook_endpoints (
+    endpoint_id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_events (
+    event_id UUID PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    sub_id UUID NOT NULL,
+    dedup_key TEXT UNIQUE,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_deliveries (
+    delivery_id UUID PRIMARY KEY,
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints (endpoint_id) ON DELETE CASCADE,
+    event_id UUID NOT NULL REFERENCES webhook_events (event_id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at);
+
+COMMIT;
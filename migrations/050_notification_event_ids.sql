@@ -0,0 +1,5 @@
+-- Notifications about subscription events remember the event, so that an
+-- event published again by the outbox is not sent twice through a channel.
+ALTER TABLE notification_deliveries ADD COLUMN event_id UUID;
+
+CREATE INDEX notification_deliveries_event_id_idx ON notification_deliveries (event_id, channel) WHERE event_id IS NOT NULL;
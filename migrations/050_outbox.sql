@@ -0,0 +1,30 @@
+-- Subscription events go through the outbox now, which also remembers the
+-- expired events already recorded. Expired events recorded for webhooks are
+-- carried over as published, so they are not sent again.
+BEGIN;
+
+CREATE TABLE outbox_events (
+    event_id UUID PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    sub_id UUID NOT NULL,
+    dedup_key TEXT UNIQUE,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT clock_timestamp(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX outbox_events_pending_idx ON outbox_events (created_at) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_pending_sub_id_idx ON outbox_events (sub_id, created_at) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_published_at_idx ON outbox_events (published_at) WHERE published_at IS NOT NULL;
+
+INSERT INTO outbox_events (event_id, event_type, sub_id, dedup_key, payload, created_at, published_at)
+SELECT event_id, event_type, sub_id, dedup_key, payload, created_at, created_at
+FROM webhook_events
+WHERE dedup_key IS NOT NULL;
+
+ALTER TABLE webhook_events DROP COLUMN dedup_key;
+
+COMMIT;